@@ -15,6 +15,7 @@ import (
 )
 
 var isoDateRE = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+var isoMonthRE = regexp.MustCompile(`^\d{4}-\d{2}$`)
 
 type apiErr struct {
 	Status  int
@@ -35,6 +36,53 @@ func serverError(msg string, err error) *apiErr {
 	return &apiErr{Status: 500, Message: msg}
 }
 
+// fieldErrors collects validation messages by JSON field so a form can show
+// them all at once. The first message found becomes the error message.
+type fieldErrors struct {
+	first  string
+	fields map[string]string
+}
+
+// add records msg for field unless the field already has a message.
+func (f *fieldErrors) add(field, msg string) {
+	if f.fields == nil {
+		f.fields = make(map[string]string)
+	}
+	if _, ok := f.fields[field]; ok {
+		return
+	}
+	if f.first == "" {
+		f.first = msg
+	}
+	f.fields[field] = msg
+}
+
+// check records a client error under field and passes server errors back.
+func (f *fieldErrors) check(field string, e *apiErr) *apiErr {
+	if e == nil || e.Status >= 500 {
+		return e
+	}
+	f.add(field, e.Message)
+	return nil
+}
+
+func (f *fieldErrors) has(fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := f.fields[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// err returns the collected messages as a 400 with details.fields, or nil.
+func (f *fieldErrors) err() *apiErr {
+	if f.first == "" {
+		return nil
+	}
+	return badRequest(f.first, map[string]any{"fields": f.fields})
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -54,15 +102,53 @@ func writeErr(w http.ResponseWriter, err *apiErr) {
 }
 
 func readJSON(r *http.Request, dst any) *apiErr {
+	_, e := readJSONFields(r, dst)
+	return e
+}
+
+// readJSONFields is readJSON that also reports which top-level keys the body
+// set, telling a field left out from one sent as null.
+func readJSONFields(r *http.Request, dst any) (map[string]bool, *apiErr) {
 	b, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
 	if err != nil {
-		return badRequest("could not read body", nil)
+		return nil, badRequest("could not read body", nil)
 	}
 	if len(b) == 0 {
 		b = []byte(`{}`)
 	}
 	if err := json.Unmarshal(b, dst); err != nil {
-		return badRequest("invalid JSON", map[string]any{"error": err.Error()})
+		return nil, badRequest("invalid JSON", map[string]any{"error": err.Error()})
+	}
+	var raw map[string]json.RawMessage
+	_ = json.Unmarshal(b, &raw)
+	present := make(map[string]bool, len(raw))
+	for k := range raw {
+		present[k] = true
+	}
+	return present, nil
+}
+
+// keepOmitted fills the fields a PUT body left out with the row's stored
+// values, so saving from a form that does not know a column keeps it. cols
+// maps each column, named as its JSON key, to the field to fill. A missing
+// row is left for the update to report.
+func (s *server) keepOmitted(table string, ledgerID, id int64, present map[string]bool, cols map[string]any) *apiErr {
+	var names []string
+	var dests []any
+	for col, dest := range cols {
+		if !present[col] {
+			names = append(names, col)
+			dests = append(dests, dest)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	err := s.db.QueryRow(
+		"SELECT "+strings.Join(names, ", ")+" FROM "+table+" WHERE id = ? AND ledger_id = ?", id, ledgerID,
+	).Scan(dests...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return serverError("failed to read "+table, err)
 	}
 	return nil
 }
@@ -77,6 +163,18 @@ func requireDate(v string, field string) (string, *apiErr) {
 	return v, nil
 }
 
+// requireMonth validates a calendar month (YYYY-MM) and returns its first and last day.
+func requireMonth(v string, field string) (string, string, *apiErr) {
+	if !isoMonthRE.MatchString(v) {
+		return "", "", badRequest(fmt.Sprintf("%s must be an ISO month YYYY-MM", field), nil)
+	}
+	start, err := time.Parse("2006-01-02", v+"-01")
+	if err != nil {
+		return "", "", badRequest(fmt.Sprintf("%s is not a valid calendar month", field), nil)
+	}
+	return start.Format("2006-01-02"), start.AddDate(0, 1, -1).Format("2006-01-02"), nil
+}
+
 func optionalDate(v *string, field string) (*string, *apiErr) {
 	if v == nil {
 		return nil, nil
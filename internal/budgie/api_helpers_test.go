@@ -43,3 +43,16 @@ func TestParseIDFromPath(t *testing.T) {
 		t.Fatalf("expected non-numeric id to fail")
 	}
 }
+
+func TestRequireMonth(t *testing.T) {
+	start, end, err := requireMonth("2024-02", "month")
+	if err != nil || start != "2024-02-01" || end != "2024-02-29" {
+		t.Fatalf("expected February 2024 bounds, got %v, %v, %v", start, end, err)
+	}
+	if _, _, err := requireMonth("2024-13", "month"); err == nil {
+		t.Fatalf("expected error for invalid month")
+	}
+	if _, _, err := requireMonth("2024-2", "month"); err == nil {
+		t.Fatalf("expected error for malformed month")
+	}
+}
@@ -0,0 +1,389 @@
+package budgie
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const defaultAttachmentMaxBytes = 10 << 20
+
+// attachmentTypes lists the sniffed media types accepted for upload. Anything
+// a browser could execute (HTML, SVG, scripts) is rejected outright.
+var attachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+// inlineAttachmentTypes may be served with an inline disposition so the UI can
+// show previews; everything else is always a download.
+var inlineAttachmentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// envAttachmentDir returns the directory attachments are written to. When it
+// is empty, attachments are stored as blobs in the database.
+func envAttachmentDir() string {
+	return strings.TrimSpace(os.Getenv("BUDGIE_ATTACHMENTS_DIR"))
+}
+
+// envAttachmentMaxBytes returns the upload size limit.
+func envAttachmentMaxBytes() int64 {
+	if v := strings.TrimSpace(os.Getenv("BUDGIE_ATTACHMENT_MAX_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultAttachmentMaxBytes
+}
+
+// attachmentOwner reads the entry_id or account_id query parameter; exactly
+// one must be given.
+func (s *server) attachmentOwner(r *http.Request, ledgerID int64) (string, int64, *apiErr) {
+	q := r.URL.Query()
+	var col, raw string
+	for _, c := range []string{"entry_id", "account_id"} {
+		if v := strings.TrimSpace(q.Get(c)); v != "" {
+			if col != "" {
+				return "", 0, badRequest("give either entry_id or account_id, not both", nil)
+			}
+			col, raw = c, v
+		}
+	}
+	if col == "" {
+		return "", 0, badRequest("entry_id or account_id is required", nil)
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, badRequest(col+" must be a positive integer", nil)
+	}
+	table := strings.TrimSuffix(col, "_id")
+	if e := s.ledgerOwnsRow(ledgerID, table, &id); e != nil {
+		return "", 0, e
+	}
+	return col, id, nil
+}
+
+// sanitizeFilename reduces a client-supplied name to a bare file name without
+// path separators or control characters.
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name
+}
+
+// sniffAttachmentType detects the content type from the data itself; the
+// client's declared type is ignored.
+func sniffAttachmentType(data []byte) (string, *apiErr) {
+	ct := http.DetectContentType(data)
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil || !attachmentTypes[mt] {
+		return "", badRequest("unsupported attachment type", map[string]any{"detected": ct})
+	}
+	return ct, nil
+}
+
+// attachmentDisposition builds a Content-Disposition value. The filename is
+// quoted (or RFC 2231 encoded) by mime.FormatMediaType, so it cannot inject
+// header parameters.
+func attachmentDisposition(inline bool, filename string) string {
+	kind := "attachment"
+	if inline {
+		kind = "inline"
+	}
+	if v := mime.FormatMediaType(kind, map[string]string{"filename": filename}); v != "" {
+		return v
+	}
+	return kind
+}
+
+// writeAttachmentFile stores data under a content-addressed path below dir and
+// returns that path relative to dir. Identical uploads share one file.
+func writeAttachmentFile(dir, sum string, data []byte) (string, error) {
+	rel := filepath.Join(sum[:2], sum)
+	full := filepath.Join(dir, rel)
+	if _, err := os.Stat(full); err == nil {
+		return rel, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return rel, os.Rename(tmp.Name(), full)
+}
+
+// readAttachmentUpload reads the "file" part of a multipart request, stopping
+// once the size limit is exceeded.
+func readAttachmentUpload(w http.ResponseWriter, r *http.Request, limit int64) (string, []byte, *apiErr) {
+	tooLarge := &apiErr{Status: http.StatusRequestEntityTooLarge, Message: "attachment is too large", Details: map[string]any{"max_bytes": limit}}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit+(64<<10))
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", nil, badRequest("expected a multipart/form-data body", nil)
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return "", nil, badRequest("missing file field", nil)
+		}
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				return "", nil, tooLarge
+			}
+			return "", nil, badRequest("invalid multipart body", nil)
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, limit+1))
+		part.Close()
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				return "", nil, tooLarge
+			}
+			return "", nil, badRequest("could not read file", nil)
+		}
+		if int64(len(data)) > limit {
+			return "", nil, tooLarge
+		}
+		if len(data) == 0 {
+			return "", nil, badRequest("file is empty", nil)
+		}
+		return part.FileName(), data, nil
+	}
+}
+
+// attachments serves GET/POST /api/attachments. Both take entry_id or
+// account_id; POST expects multipart/form-data with a "file" field.
+func (s *server) attachments(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		col, ownerID, e := s.attachmentOwner(r, ledgerID)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		rows, err := s.db.Query(`
+			SELECT id, entry_id, account_id, filename, mime_type, size_bytes, sha256, storage, created_at
+			FROM attachment
+			WHERE ledger_id = ? AND `+col+` = ?
+			ORDER BY created_at, id
+		`, ledgerID, ownerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query attachments", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read attachments", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		col, ownerID, e := s.attachmentOwner(r, ledgerID)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		name, data, e := readAttachmentUpload(w, r, envAttachmentMaxBytes())
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		mimeType, e := sniffAttachmentType(data)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		digest := sha256.Sum256(data)
+		sum := hex.EncodeToString(digest[:])
+
+		storage := "db"
+		var blob []byte
+		var path *string
+		if dir := envAttachmentDir(); dir != "" {
+			rel, err := writeAttachmentFile(dir, sum, data)
+			if err != nil {
+				writeErr(w, serverError("failed to store attachment", err))
+				return
+			}
+			storage, path = "dir", &rel
+		} else {
+			blob = data
+		}
+
+		var id int64
+		err := s.db.QueryRow(`
+			INSERT INTO attachment (ledger_id, `+col+`, filename, mime_type, size_bytes, sha256, storage, data, path)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, ledgerID, ownerID, sanitizeFilename(name), mimeType, len(data), sum, storage, blob, path).Scan(&id)
+		if err != nil {
+			writeErr(w, serverError("failed to save attachment", err))
+			return
+		}
+		created, apiE := s.attachmentMeta(ledgerID, id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) attachmentMeta(ledgerID, id int64) (map[string]any, *apiErr) {
+	rows, err := s.db.Query(`
+		SELECT id, entry_id, account_id, filename, mime_type, size_bytes, sha256, storage, created_at
+		FROM attachment
+		WHERE id = ? AND ledger_id = ?
+	`, id, ledgerID)
+	if err != nil {
+		return nil, serverError("failed to query attachment", err)
+	}
+	defer rows.Close()
+	data, err := rowsToMaps(rows)
+	if err != nil {
+		return nil, serverError("failed to read attachment", err)
+	}
+	if len(data) == 0 {
+		return nil, notFound("attachment not found")
+	}
+	return data[0], nil
+}
+
+// attachmentByID serves GET (download) and DELETE /api/attachments/{id}.
+// Downloads are sent as attachments; ?inline=1 is honoured for images only.
+func (s *server) attachmentByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/attachments/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		var (
+			filename, mimeType, storage string
+			data                        []byte
+			path                        sql.NullString
+		)
+		err := s.db.QueryRow(
+			"SELECT filename, mime_type, storage, data, path FROM attachment WHERE id = ? AND ledger_id = ?",
+			id, ledgerID,
+		).Scan(&filename, &mimeType, &storage, &data, &path)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErr(w, notFound("attachment not found"))
+			return
+		}
+		if err != nil {
+			writeErr(w, serverError("failed to read attachment", err))
+			return
+		}
+		if storage == "dir" {
+			dir := envAttachmentDir()
+			if dir == "" {
+				writeErr(w, serverError("failed to read attachment", errors.New("BUDGIE_ATTACHMENTS_DIR is not set")))
+				return
+			}
+			data, err = os.ReadFile(filepath.Join(dir, path.String))
+			if err != nil {
+				writeErr(w, serverError("failed to read attachment", err))
+				return
+			}
+		}
+		mt, _, _ := mime.ParseMediaType(mimeType)
+		inline := r.URL.Query().Get("inline") == "1" && inlineAttachmentTypes[mt]
+
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Content-Disposition", attachmentDisposition(inline, filename))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		var storage string
+		var path sql.NullString
+		err := s.db.QueryRow(
+			"DELETE FROM attachment WHERE id = ? AND ledger_id = ? RETURNING storage, path",
+			id, ledgerID,
+		).Scan(&storage, &path)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErr(w, notFound("attachment not found"))
+			return
+		}
+		if err != nil {
+			writeErr(w, badRequest("could not delete attachment", nil))
+			return
+		}
+		if storage == "dir" {
+			s.removeUnusedAttachmentFile(path.String)
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// removeUnusedAttachmentFile deletes a stored file once no attachment row in
+// any ledger refers to it any more.
+func (s *server) removeUnusedAttachmentFile(rel string) {
+	dir := envAttachmentDir()
+	if dir == "" || rel == "" {
+		return
+	}
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM attachment WHERE storage = 'dir' AND path = ?", rel).Scan(&n); err != nil || n > 0 {
+		return
+	}
+	_ = os.Remove(filepath.Join(dir, rel))
+}
@@ -0,0 +1,218 @@
+package budgie
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func uploadAttachment(t *testing.T, url, filename string, data []byte) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart: %v", err)
+	}
+	resp, err := http.Post(url, mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	return resp
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"receipt.pdf":             "receipt.pdf",
+		"../../etc/passwd":        "passwd",
+		`C:\Users\me\scan.png`:    "scan.png",
+		"bad\"name\r\n.txt":       "badname.txt",
+		"   ":                     "attachment",
+		"reçu été.pdf":            "reçu été.pdf",
+		"x/y/":                    "y",
+		"attachment\x00hidden.js": "attachmenthidden.js",
+	}
+	for in, want := range cases {
+		if got := sanitizeFilename(in); got != want {
+			t.Fatalf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAttachmentsBlobStorage(t *testing.T) {
+	t.Setenv("BUDGIE_ATTACHMENTS_DIR", "")
+	t.Setenv("BUDGIE_ATTACHMENT_MAX_BYTES", "64")
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+	res, err = db.Exec(
+		"INSERT INTO entry (entry_date, name, amount_cents, src_account_id) VALUES (?, ?, ?, ?)",
+		"2026-01-05", "Hardware store", int64(4200), acctID,
+	)
+	if err != nil {
+		t.Fatalf("insert entry: %v", err)
+	}
+	entryID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+	base := api.URL + "/api/attachments?entry_id=" + fmtInt64(entryID)
+
+	resp := uploadAttachment(t, base, `..\receipt "final".png`, testPNG)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload: status %d", resp.StatusCode)
+	}
+	meta := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if meta["mime_type"] != "image/png" || meta["storage"] != "db" || meta["filename"] != "receipt final.png" {
+		t.Fatalf("unexpected metadata: %v", meta)
+	}
+	if mustInt64(t, meta["size_bytes"]) != int64(len(testPNG)) || len(meta["sha256"].(string)) != 64 {
+		t.Fatalf("unexpected size/hash: %v", meta)
+	}
+	if _, ok := meta["data"]; ok {
+		t.Fatalf("metadata must not include the content")
+	}
+	attID := mustInt64(t, meta["id"])
+
+	resp = uploadAttachment(t, base, "page.html", []byte("<html><script>alert(1)</script></html>"))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for html upload, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = uploadAttachment(t, base, "big.txt", bytes.Repeat([]byte("a"), 65))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for oversized upload, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = uploadAttachment(t, api.URL+"/api/attachments?entry_id=999", "x.png", testPNG)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown entry, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, base, nil)
+	if got := len(mustList(t, decodeAPIResponse(t, resp).Data)); got != 1 {
+		t.Fatalf("expected 1 attachment, got %d", got)
+	}
+
+	resp, err = http.Get(api.URL + "/api/attachments/" + fmtInt64(attID))
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(body, testPNG) {
+		t.Fatalf("downloaded content differs")
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="receipt final.png"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "image/png" {
+		t.Fatalf("unexpected Content-Type %q", got)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Security-Policy"), "default-src 'self'") || resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("security headers missing on download")
+	}
+
+	resp, err = http.Get(api.URL + "/api/attachments/" + fmtInt64(attID) + "?inline=1")
+	if err != nil {
+		t.Fatalf("download inline: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(got, "inline;") {
+		t.Fatalf("expected inline disposition for image, got %q", got)
+	}
+
+	if _, err := db.Exec("DELETE FROM entry WHERE id = ?", entryID); err != nil {
+		t.Fatalf("delete entry: %v", err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM attachment").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected attachments to cascade with the entry, got %d (%v)", n, err)
+	}
+}
+
+func TestAttachmentsDirectoryStorage(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BUDGIE_ATTACHMENTS_DIR", dir)
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Card", "2026-01-01", int64(0),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+	base := api.URL + "/api/attachments?account_id=" + fmtInt64(acctID)
+	statement := []byte("%PDF-1.4\n% statement for January\n")
+
+	var ids []int64
+	for _, name := range []string{"jan.pdf", "jan-copy.pdf"} {
+		resp := uploadAttachment(t, base, name, statement)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("upload %s: status %d", name, resp.StatusCode)
+		}
+		meta := mustMap(t, decodeAPIResponse(t, resp).Data)
+		if meta["storage"] != "dir" || meta["mime_type"] != "application/pdf" {
+			t.Fatalf("unexpected metadata: %v", meta)
+		}
+		ids = append(ids, mustInt64(t, meta["id"]))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(files) != 1 {
+		t.Fatalf("expected identical uploads to share one file, got %v", files)
+	}
+
+	resp, err := http.Get(api.URL + "/api/attachments/" + fmtInt64(ids[1]))
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(body, statement) {
+		t.Fatalf("downloaded content differs")
+	}
+
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/attachments/"+fmtInt64(ids[0]), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	if _, err := os.Stat(files[0]); err != nil {
+		t.Fatalf("file still referenced by another attachment was removed: %v", err)
+	}
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/attachments/"+fmtInt64(ids[1]), nil)
+	resp.Body.Close()
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Fatalf("expected unreferenced file to be removed, got %v", err)
+	}
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/attachments/"+fmtInt64(ids[1]), nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
@@ -167,6 +167,7 @@ type sessionInfo struct {
 	UserID    int64
 	CSRFToken string
 	ExpiresAt int64
+	LedgerID  *int64
 }
 
 type userInfo struct {
@@ -312,6 +313,7 @@ func (a *AuthService) sessionFromRequest(r *http.Request) (*sessionInfo, *userIn
 		expiresAt   int64
 		hasPassword int
 		oidcLinked  int
+		ledgerID    sql.NullInt64
 	)
 	err = a.db.QueryRow(`
 		SELECT
@@ -320,6 +322,7 @@ func (a *AuthService) sessionFromRequest(r *http.Request) (*sessionInfo, *userIn
 			u.display_name,
 			s.csrf_token,
 			s.expires_at,
+			s.ledger_id,
 			EXISTS(SELECT 1 FROM user_password p WHERE p.user_id = u.id),
 			EXISTS(SELECT 1 FROM user_oidc_identity o WHERE o.user_id = u.id)
 		FROM auth_session s
@@ -327,7 +330,7 @@ func (a *AuthService) sessionFromRequest(r *http.Request) (*sessionInfo, *userIn
 		WHERE s.id = ?
 		  AND s.expires_at > strftime('%s','now')
 		  AND u.disabled_at IS NULL
-	`, hash).Scan(&userID, &email, &displayName, &csrfToken, &expiresAt, &hasPassword, &oidcLinked)
+	`, hash).Scan(&userID, &email, &displayName, &csrfToken, &expiresAt, &ledgerID, &hasPassword, &oidcLinked)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil, errNoSession
@@ -340,7 +343,12 @@ func (a *AuthService) sessionFromRequest(r *http.Request) (*sessionInfo, *userIn
 	if strings.TrimSpace(name) == "" {
 		name = email
 	}
-	return &sessionInfo{ID: hash, UserID: userID, CSRFToken: csrfToken, ExpiresAt: expiresAt}, &userInfo{
+	sess := &sessionInfo{ID: hash, UserID: userID, CSRFToken: csrfToken, ExpiresAt: expiresAt}
+	if ledgerID.Valid {
+		v := ledgerID.Int64
+		sess.LedgerID = &v
+	}
+	return sess, &userInfo{
 		ID:          userID,
 		Email:       email,
 		DisplayName: name,
@@ -15,6 +15,7 @@ type ctxKey int
 const (
 	ctxUserKey ctxKey = iota
 	ctxSessionKey
+	ctxLedgerKey
 )
 
 func userFromContext(ctx context.Context) *userInfo {
@@ -61,8 +62,14 @@ func (s *server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 				return
 			}
 		}
+		ledgerID, err := s.resolveLedger(sess, user)
+		if err != nil {
+			writeErr(w, serverError("failed to resolve ledger", err))
+			return
+		}
 		ctx := context.WithValue(r.Context(), ctxUserKey, user)
 		ctx = context.WithValue(ctx, ctxSessionKey, sess)
+		ctx = context.WithValue(ctx, ctxLedgerKey, ledgerID)
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -100,8 +107,14 @@ func (s *server) session(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 	if sess, user, err := s.auth.sessionFromRequest(r); err == nil {
+		ledgerID, err := s.resolveLedger(sess, user)
+		if err != nil {
+			writeErr(w, serverError("failed to resolve ledger", err))
+			return
+		}
 		payload["user"] = user
 		payload["csrf_token"] = sess.CSRFToken
+		payload["ledger_id"] = ledgerID
 	} else if !errors.Is(err, errNoSession) {
 		writeErr(w, serverError("failed to read session", err))
 		return
@@ -45,7 +45,7 @@ func TestActualBalancesAsOf(t *testing.T) {
 	}
 
 	srv := &server{db: db}
-	pts, err := srv.actualBalancesAsOf("2026-01-10")
+	pts, err := srv.actualBalancesAsOf(defaultLedgerID, "2026-01-10")
 	if err != nil {
 		t.Fatalf("actualBalancesAsOf: %v", err)
 	}
@@ -84,7 +84,7 @@ func TestProjectedBalancesAsOf(t *testing.T) {
 	}
 
 	srv := &server{db: db}
-	pts, err := srv.projectedBalancesAsOf("2026-01-01", "2026-02-01")
+	pts, err := srv.projectedBalancesAsOf(defaultLedgerID, "2026-01-01", "2026-02-01")
 	if err != nil {
 		t.Fatalf("projectedBalancesAsOf: %v", err)
 	}
@@ -120,7 +120,7 @@ func TestYearlyScheduleLeapYearClamping(t *testing.T) {
 
 	// Query occurrences from 2024-02-28 to 2027-03-01 to capture multiple years.
 	q := occurrenceQuery()
-	rows, err := db.Query(q, "2027-03-01", "2024-02-28", "2027-03-01")
+	rows, err := db.Query(q, occurrenceParams(0, defaultLedgerID, "2024-02-28", "2027-03-01", "[]")...)
 	if err != nil {
 		t.Fatalf("query occurrences: %v", err)
 	}
@@ -132,8 +132,13 @@ func TestYearlyScheduleLeapYearClamping(t *testing.T) {
 		var occDate, kind, name string
 		var amountCents int64
 		var srcID, destID *int64
-		var desc *string
-		if err := rows.Scan(&schedID, &occDate, &kind, &name, &amountCents, &srcID, &destID, &desc); err != nil {
+		var desc, categoryPath *string
+		var categoryID, destAmountCents *int64
+		var originalDate string
+		var modified bool
+		var exceptionID *int64
+		var exceptionKind *string
+		if err := rows.Scan(&schedID, &occDate, &kind, &name, &amountCents, &srcID, &destID, &desc, &categoryID, &categoryPath, &destAmountCents, &originalDate, &modified, &exceptionID, &exceptionKind); err != nil {
 			t.Fatalf("scan: %v", err)
 		}
 		dates = append(dates, occDate)
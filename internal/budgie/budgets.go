@@ -0,0 +1,444 @@
+package budgie
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type budgetBody struct {
+	CategoryID   int64   `json:"category_id"`
+	Month        string  `json:"month"`
+	PlannedCents int64   `json:"planned_cents"`
+	Rollover     string  `json:"rollover"`
+	Description  *string `json:"description"`
+}
+
+func (s *server) validateBudget(ledgerID int64, b *budgetBody) *apiErr {
+	if b.CategoryID == 0 {
+		return badRequest("category_id is required", nil)
+	}
+	if e := s.ledgerOwnsCategory(ledgerID, &b.CategoryID); e != nil {
+		return e
+	}
+	if _, _, e := requireMonth(b.Month, "month"); e != nil {
+		return e
+	}
+	if b.PlannedCents < 0 {
+		return badRequest("planned_cents must be >= 0", nil)
+	}
+	if strings.TrimSpace(b.Rollover) == "" {
+		b.Rollover = "none"
+	}
+	if b.Rollover != "none" && b.Rollover != "surplus" && b.Rollover != "both" {
+		return badRequest("rollover must be one of none, surplus, both", nil)
+	}
+	return nil
+}
+
+func (s *server) budgets(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		month := r.URL.Query().Get("month")
+		q := `
+			WITH RECURSIVE ` + categoryPathCTEDefs() + `
+			SELECT b.*, cp.path AS category_path
+			FROM budget b
+			LEFT JOIN category_path cp ON cp.id = b.category_id
+			WHERE b.ledger_id = ?`
+		args := []any{ledgerID}
+		if month != "" {
+			if _, _, e := requireMonth(month, "month"); e != nil {
+				writeErr(w, e)
+				return
+			}
+			q += " AND b.month = ?"
+			args = append(args, month)
+		}
+		rows, err := s.db.Query(q+" ORDER BY b.month DESC, cp.path", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query budgets", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read budgets", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body budgetBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateBudget(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO budget (ledger_id, category_id, month, planned_cents, rollover, description) VALUES (?, ?, ?, ?, ?, ?)",
+			ledgerID, body.CategoryID, body.Month, body.PlannedCents, body.Rollover, body.Description,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create budget (one already exists for this category and month?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "budget", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) budgetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/budgets/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body budgetBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateBudget(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"UPDATE budget SET category_id=?, month=?, planned_cents=?, rollover=?, description=? WHERE id=? AND ledger_id=?",
+			body.CategoryID, body.Month, body.PlannedCents, body.Rollover, body.Description, id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not update budget", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("budget not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "budget", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM budget WHERE id = ? AND ledger_id = ?", id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete budget", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("budget not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type budgetLine struct {
+	BudgetID                int64  `json:"budget_id"`
+	CategoryID              int64  `json:"category_id"`
+	CategoryPath            string `json:"category_path"`
+	Rollover                string `json:"rollover"`
+	PlannedCents            int64  `json:"planned_cents"`
+	CarriedInCents          int64  `json:"carried_in_cents"`
+	AvailableCents          int64  `json:"available_cents"`
+	ActualCents             int64  `json:"actual_cents"`
+	ScheduledCents          int64  `json:"scheduled_cents"`
+	RemainingCents          int64  `json:"remaining_cents"`
+	ProjectedRemainingCents int64  `json:"projected_remaining_cents"`
+	OverspentCents          int64  `json:"overspent_cents"`
+	CarryForwardCents       int64  `json:"carry_forward_cents"`
+}
+
+type budgetRow struct {
+	ID       int64
+	Planned  int64
+	Rollover string
+}
+
+// carryForward applies a rollover policy to what is left of a month's budget.
+func carryForward(policy string, left int64) int64 {
+	switch policy {
+	case "surplus":
+		if left > 0 {
+			return left
+		}
+		return 0
+	case "both":
+		return left
+	default:
+		return 0
+	}
+}
+
+func addMonths(month string, n int) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	return t.AddDate(0, n, 0).Format("2006-01")
+}
+
+// budgetReport compares a month's budgets against actual spending and the
+// scheduled occurrences still to come in that month.
+//
+// Spending is the net outflow of categorized entries and split lines
+// (expenses add, refunds/income subtract, transfers net to zero), rolled up
+// from subcategories. Carried-in amounts come from replaying each category's
+// budgets month by month from its first budgeted month.
+//
+//	GET /api/budgets/report?month=YYYY-MM
+func (s *server) budgetReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	month := r.URL.Query().Get("month")
+	_, monthEnd, e := requireMonth(month, "month")
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	// Budgets up to and including the report month, per category.
+	rows, err := s.db.Query(`
+		SELECT id, category_id, month, planned_cents, rollover
+		FROM budget
+		WHERE ledger_id = ? AND month <= ?
+		ORDER BY month
+	`, ledgerID, month)
+	if err != nil {
+		writeErr(w, serverError("failed to query budgets", err))
+		return
+	}
+	byCategory := make(map[int64]map[string]budgetRow)
+	firstMonth := make(map[int64]string)
+	earliest := month
+	for rows.Next() {
+		var (
+			b          budgetRow
+			categoryID int64
+			m          string
+		)
+		if err := rows.Scan(&b.ID, &categoryID, &m, &b.Planned, &b.Rollover); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read budgets", err))
+			return
+		}
+		if byCategory[categoryID] == nil {
+			byCategory[categoryID] = make(map[string]budgetRow)
+			firstMonth[categoryID] = m
+		}
+		byCategory[categoryID][m] = b
+		if m < earliest {
+			earliest = m
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read budgets", err))
+		return
+	}
+
+	parents, paths, err := s.categoryTree(ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to read categories", err))
+		return
+	}
+
+	from := earliest + "-01"
+	actual, err := s.categoryNetOutflow(ledgerID, from, monthEnd, parents)
+	if err != nil {
+		writeErr(w, serverError("failed to compute category spending", err))
+		return
+	}
+	scheduledFrom := time.Now().Format("2006-01-02")
+	if scheduledFrom < from {
+		scheduledFrom = from
+	}
+	scheduled := map[string]map[int64]int64{}
+	if scheduledFrom <= monthEnd {
+		scheduled, err = s.categoryScheduledOutflow(ledgerID, scheduledFrom, monthEnd, parents)
+		if err != nil {
+			writeErr(w, serverError("failed to compute scheduled spending", err))
+			return
+		}
+	}
+
+	var out []budgetLine
+	var totals budgetLine
+	for categoryID, months := range byCategory {
+		if _, ok := months[month]; !ok {
+			continue
+		}
+		var carry int64
+		var line budgetLine
+		for m := firstMonth[categoryID]; m <= month; m = addMonths(m, 1) {
+			b := months[m]
+			line = budgetLine{
+				BudgetID:       b.ID,
+				CategoryID:     categoryID,
+				CategoryPath:   paths[categoryID],
+				Rollover:       b.Rollover,
+				PlannedCents:   b.Planned,
+				CarriedInCents: carry,
+				ActualCents:    actual[m][categoryID],
+				ScheduledCents: scheduled[m][categoryID],
+			}
+			line.AvailableCents = line.PlannedCents + line.CarriedInCents
+			line.RemainingCents = line.AvailableCents - line.ActualCents
+			line.ProjectedRemainingCents = line.RemainingCents - line.ScheduledCents
+			if line.ActualCents > line.AvailableCents {
+				line.OverspentCents = line.ActualCents - line.AvailableCents
+			}
+			line.CarryForwardCents = carryForward(b.Rollover, line.ProjectedRemainingCents)
+			carry = line.CarryForwardCents
+		}
+		out = append(out, line)
+
+		totals.PlannedCents += line.PlannedCents
+		totals.CarriedInCents += line.CarriedInCents
+		totals.AvailableCents += line.AvailableCents
+		totals.ActualCents += line.ActualCents
+		totals.ScheduledCents += line.ScheduledCents
+		totals.RemainingCents += line.RemainingCents
+		totals.ProjectedRemainingCents += line.ProjectedRemainingCents
+		totals.OverspentCents += line.OverspentCents
+		totals.CarryForwardCents += line.CarryForwardCents
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CategoryPath < out[j].CategoryPath })
+
+	writeOK(w, map[string]any{
+		"month":      month,
+		"categories": out,
+		"totals": map[string]any{
+			"planned_cents":             totals.PlannedCents,
+			"carried_in_cents":          totals.CarriedInCents,
+			"available_cents":           totals.AvailableCents,
+			"actual_cents":              totals.ActualCents,
+			"scheduled_cents":           totals.ScheduledCents,
+			"remaining_cents":           totals.RemainingCents,
+			"projected_remaining_cents": totals.ProjectedRemainingCents,
+			"overspent_cents":           totals.OverspentCents,
+			"carry_forward_cents":       totals.CarryForwardCents,
+		},
+	})
+}
+
+// categoryTree returns each category's parent and full path for a ledger.
+func (s *server) categoryTree(ledgerID int64) (map[int64]int64, map[int64]string, error) {
+	rows, err := s.db.Query(`
+		WITH RECURSIVE `+categoryPathCTEDefs()+`
+		SELECT c.id, c.parent_id, cp.path
+		FROM category c
+		JOIN category_path cp ON cp.id = c.id
+		WHERE c.ledger_id = ?
+	`, ledgerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	parents := make(map[int64]int64)
+	paths := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var parent sql.NullInt64
+		var path string
+		if err := rows.Scan(&id, &parent, &path); err != nil {
+			return nil, nil, err
+		}
+		if parent.Valid {
+			parents[id] = parent.Int64
+		}
+		paths[id] = path
+	}
+	return parents, paths, rows.Err()
+}
+
+// rollUpByMonth scans (category_id, month, cents) rows and adds each amount to
+// the category and all of its ancestors.
+func rollUpByMonth(rows *sql.Rows, parents map[int64]int64) (map[string]map[int64]int64, error) {
+	defer rows.Close()
+	out := make(map[string]map[int64]int64)
+	for rows.Next() {
+		var (
+			categoryID int64
+			month      string
+			cents      int64
+		)
+		if err := rows.Scan(&categoryID, &month, &cents); err != nil {
+			return nil, err
+		}
+		if out[month] == nil {
+			out[month] = make(map[int64]int64)
+		}
+		for id, ok := categoryID, true; ok; id, ok = parents[id] {
+			out[month][id] += cents
+		}
+	}
+	return out, rows.Err()
+}
+
+// categoryNetOutflow returns net outflow per month and category (rolled up)
+// from categorized entries between from and to.
+func (s *server) categoryNetOutflow(ledgerID int64, from, to string, parents map[int64]int64) (map[string]map[int64]int64, error) {
+	rows, err := s.db.Query(`
+		SELECT category_id, substr(entry_date, 1, 7) AS month,
+		       SUM(CASE
+		             WHEN dest_account_id IS NULL THEN amount_cents
+		             WHEN src_account_id IS NULL THEN -amount_cents
+		             ELSE 0
+		           END)
+		FROM v_entry_category_line
+		WHERE ledger_id = ?
+		  AND entry_date BETWEEN ? AND ?
+		  AND category_id IS NOT NULL
+		GROUP BY category_id, month
+	`, ledgerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return rollUpByMonth(rows, parents)
+}
+
+// categoryScheduledOutflow returns net outflow per month and category (rolled
+// up) from schedule occurrences between from and to that are not yet posted.
+func (s *server) categoryScheduledOutflow(ledgerID int64, from, to string, parents map[int64]int64) (map[string]map[int64]int64, error) {
+	args, err := occurrenceArgs(s.db, ledgerID, 0, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT o.category_id, substr(o.occ_date, 1, 7) AS month,
+		       SUM(CASE o.kind WHEN 'E' THEN o.amount_cents WHEN 'I' THEN -o.amount_cents ELSE 0 END)
+		FROM (`+occurrenceQuery()+`) o
+		WHERE o.category_id IS NOT NULL
+		GROUP BY o.category_id, month
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rollUpByMonth(rows, parents)
+}
@@ -0,0 +1,136 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBudgetReportRolloverAndRollup(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+	res, err = db.Exec("INSERT INTO category (name) VALUES ('Food')")
+	if err != nil {
+		t.Fatalf("insert category: %v", err)
+	}
+	food, _ := res.LastInsertId()
+	res, err = db.Exec("INSERT INTO category (name, parent_id) VALUES ('Groceries', ?)", food)
+	if err != nil {
+		t.Fatalf("insert subcategory: %v", err)
+	}
+	groceries, _ := res.LastInsertId()
+
+	server := newTestAPIServer(t, db)
+
+	for _, b := range []map[string]any{
+		{"category_id": food, "month": "2026-01", "planned_cents": 10000, "rollover": "surplus"},
+		{"category_id": food, "month": "2026-02", "planned_cents": 5000},
+	} {
+		resp := doJSON(t, http.MethodPost, server.URL+"/api/budgets", b)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create budget %v: status %d", b["month"], resp.StatusCode)
+		}
+	}
+	resp := doJSON(t, http.MethodPost, server.URL+"/api/budgets", map[string]any{"category_id": food, "month": "2026-02", "planned_cents": 1})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for duplicate budget, got %d", resp.StatusCode)
+	}
+
+	for _, e := range []struct {
+		date   string
+		amount int64
+	}{{"2026-01-10", 4000}, {"2026-02-03", 12000}} {
+		resp := doJSON(t, http.MethodPost, server.URL+"/api/entries", map[string]any{
+			"entry_date":     e.date,
+			"name":           "Market",
+			"amount_cents":   e.amount,
+			"src_account_id": acctID,
+			"category_id":    groceries,
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create entry %s: status %d", e.date, resp.StatusCode)
+		}
+	}
+
+	reportResp, err := http.Get(server.URL + "/api/budgets/report?month=2026-02")
+	if err != nil {
+		t.Fatalf("get report: %v", err)
+	}
+	report := mustMap(t, decodeAPIResponse(t, reportResp).Data)
+	lines := mustList(t, report["categories"])
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 budget line, got %d", len(lines))
+	}
+	line := mustMap(t, lines[0])
+	// January left 6000 unspent under a surplus rollover.
+	for key, want := range map[string]int64{
+		"carried_in_cents": 6000,
+		"available_cents":  11000,
+		"actual_cents":     12000,
+		"remaining_cents":  -1000,
+		"overspent_cents":  1000,
+	} {
+		if got := mustInt64(t, line[key]); got != want {
+			t.Fatalf("expected %s=%d, got %d", key, want, got)
+		}
+	}
+
+	badResp, err := http.Get(server.URL + "/api/budgets/report?month=2026-2")
+	if err != nil {
+		t.Fatalf("get bad report: %v", err)
+	}
+	if badResp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed month, got %d", badResp.StatusCode)
+	}
+}
+
+func TestBudgetReportScheduledAfterEarlyPosting(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	next := time.Now().AddDate(0, 2, 0)
+	month := next.Format("2006-01")
+	first := time.Date(next.Year(), next.Month(), 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 7).Format("2006-01-02")
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	acctID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/categories", map[string]any{"name": "Food"})
+	food := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/budgets", map[string]any{"category_id": food, "month": month, "planned_cents": 5000})
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Market", "kind": "E", "amount_cents": 1000, "src_account_id": acctID, "category_id": food,
+		"start_date": first.Format("2006-01-02"), "end_date": second, "freq": "W", "interval": 1,
+	})
+	scheduleID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// The first week is posted late, on the date of the second, which is
+	// still to come.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": scheduleID, "occurrence_date": first.Format("2006-01-02"), "entry_date": second,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post occurrence: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/budgets/report?month="+month, nil)
+	lines := mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["categories"])
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 budget line, got %d", len(lines))
+	}
+	if got := mustInt64(t, mustMap(t, lines[0])["scheduled_cents"]); got != 1000 {
+		t.Fatalf("scheduled_cents = %d, want 1000", got)
+	}
+}
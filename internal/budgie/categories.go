@@ -0,0 +1,419 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (s *server) categories(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query(`
+			WITH RECURSIVE `+categoryPathCTEDefs()+`
+			SELECT c.*, cp.path, cp.depth
+			FROM category c
+			JOIN category_path cp ON cp.id = c.id
+			WHERE c.ledger_id = ?
+			ORDER BY cp.path
+		`, ledgerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query categories", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read categories", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body struct {
+			Name        string  `json:"name"`
+			ParentID    *int64  `json:"parent_id"`
+			Description *string `json:"description"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if strings.TrimSpace(body.Name) == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, body.ParentID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO category (ledger_id, parent_id, name, description) VALUES (?, ?, ?, ?)",
+			ledgerID, body.ParentID, strings.TrimSpace(body.Name), body.Description,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create category (name already used under this parent?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "category", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// categoryByID serves PUT/DELETE /api/categories/{id} and
+// POST /api/categories/{id}/merge.
+func (s *server) categoryByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/categories/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "merge") {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+	parentID, e := s.categoryParent(ledgerID, id)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			IntoID int64 `json:"into_id"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if body.IntoID == 0 || body.IntoID == id {
+			writeErr(w, badRequest("into_id must reference a different category", nil))
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, &body.IntoID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if desc, e := s.isCategoryDescendant(id, body.IntoID); e != nil {
+			writeErr(w, e)
+			return
+		} else if desc {
+			writeErr(w, badRequest("cannot merge a category into one of its descendants", nil))
+			return
+		}
+		if e := s.removeCategory(id, &body.IntoID, &body.IntoID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		writeOK(w, map[string]any{"merged": id, "into_id": body.IntoID})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Name        string  `json:"name"`
+			ParentID    *int64  `json:"parent_id"`
+			Description *string `json:"description"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if strings.TrimSpace(body.Name) == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		if body.ParentID != nil {
+			if *body.ParentID == id {
+				writeErr(w, badRequest("a category cannot be its own parent", nil))
+				return
+			}
+			if e := s.ledgerOwnsCategory(ledgerID, body.ParentID); e != nil {
+				writeErr(w, e)
+				return
+			}
+			if desc, e := s.isCategoryDescendant(id, *body.ParentID); e != nil {
+				writeErr(w, e)
+				return
+			} else if desc {
+				writeErr(w, badRequest("parent_id cannot be a descendant of the category", nil))
+				return
+			}
+		}
+		if _, err := s.db.Exec(
+			"UPDATE category SET name=?, parent_id=?, description=? WHERE id=? AND ledger_id=?",
+			strings.TrimSpace(body.Name), body.ParentID, body.Description, id, ledgerID,
+		); err != nil {
+			writeErr(w, badRequest("could not update category (name already used under this parent?)", nil))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "category", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		// Children and transactions move up to the deleted category's parent.
+		if e := s.removeCategory(id, parentID, parentID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// categoryParent returns the parent of a ledger's category (nil for top level).
+func (s *server) categoryParent(ledgerID, id int64) (*int64, *apiErr) {
+	var parent sql.NullInt64
+	err := s.db.QueryRow("SELECT parent_id FROM category WHERE id = ? AND ledger_id = ?", id, ledgerID).Scan(&parent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound("category not found")
+		}
+		return nil, serverError("failed to read category", err)
+	}
+	if !parent.Valid {
+		return nil, nil
+	}
+	v := parent.Int64
+	return &v, nil
+}
+
+// isCategoryDescendant reports whether candidate sits anywhere below id.
+func (s *server) isCategoryDescendant(id, candidate int64) (bool, *apiErr) {
+	var n int
+	err := s.db.QueryRow(`
+		WITH RECURSIVE sub(id) AS (
+			SELECT id FROM category WHERE parent_id = ?
+			UNION ALL
+			SELECT c.id FROM category c JOIN sub ON c.parent_id = sub.id
+		)
+		SELECT COUNT(*) FROM sub WHERE id = ?
+	`, id, candidate).Scan(&n)
+	if err != nil {
+		return false, serverError("failed to walk categories", err)
+	}
+	return n > 0, nil
+}
+
+// removeCategory deletes a category after moving its children under
+// childParent and its entries, splits and schedules to txnCategory, all in one transaction.
+func (s *server) removeCategory(id int64, childParent *int64, txnCategory *int64) *apiErr {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return serverError("failed to open transaction", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE category SET parent_id = ? WHERE parent_id = ?", childParent, id); err != nil {
+		return badRequest("could not move child categories (name conflict?)", nil)
+	}
+	if _, err := tx.Exec("UPDATE entry SET category_id = ? WHERE category_id = ?", txnCategory, id); err != nil {
+		return serverError("failed to reassign entries", err)
+	}
+	if _, err := tx.Exec("UPDATE schedule SET category_id = ? WHERE category_id = ?", txnCategory, id); err != nil {
+		return serverError("failed to reassign schedules", err)
+	}
+	if _, err := tx.Exec("UPDATE entry_split SET category_id = ? WHERE category_id = ?", txnCategory, id); err != nil {
+		return serverError("failed to reassign entry splits", err)
+	}
+	if _, err := tx.Exec("UPDATE payee SET default_category_id = ? WHERE default_category_id = ?", txnCategory, id); err != nil {
+		return serverError("failed to reassign payee defaults", err)
+	}
+	if txnCategory != nil {
+		// Budgets follow their transactions unless the target already has one that month.
+		if _, err := tx.Exec("UPDATE OR IGNORE budget SET category_id = ? WHERE category_id = ?", txnCategory, id); err != nil {
+			return serverError("failed to reassign budgets", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM category WHERE id = ?", id); err != nil {
+		return badRequest("could not delete category", nil)
+	}
+	if err := tx.Commit(); err != nil {
+		return serverError("failed to remove category", err)
+	}
+	return nil
+}
+
+type categoryRollup struct {
+	ID           *int64 `json:"id"`
+	ParentID     *int64 `json:"parent_id"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Depth        int64  `json:"depth"`
+	OwnInflow    int64  `json:"own_inflow_cents"`
+	OwnOutflow   int64  `json:"own_outflow_cents"`
+	InflowCents  int64  `json:"inflow_cents"`
+	OutflowCents int64  `json:"outflow_cents"`
+}
+
+// categoryRollups returns income/expense totals per category for a window.
+// Each category's totals include all of its descendants; uncategorized
+// movements are reported as a final row with a nil id. Transfers are ignored.
+//
+//	GET /api/categories/rollup?from_date=&to_date=&mode=actual|projected
+func (s *server) categoryRollups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	from := r.URL.Query().Get("from_date")
+	to := r.URL.Query().Get("to_date")
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "actual"
+	}
+	if _, e := requireDate(from, "from_date"); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if _, e := requireDate(to, "to_date"); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if mode != "actual" && mode != "projected" {
+		writeErr(w, badRequest("mode must be 'actual' or 'projected'", nil))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	rows, err := s.db.Query(`
+		WITH RECURSIVE `+categoryPathCTEDefs()+`
+		SELECT c.id, c.parent_id, c.name, cp.path, cp.depth
+		FROM category c
+		JOIN category_path cp ON cp.id = c.id
+		WHERE c.ledger_id = ?
+		ORDER BY cp.path
+	`, ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to query categories", err))
+		return
+	}
+	var out []*categoryRollup
+	byID := make(map[int64]*categoryRollup)
+	for rows.Next() {
+		var (
+			id     int64
+			parent sql.NullInt64
+			c      categoryRollup
+		)
+		if err := rows.Scan(&id, &parent, &c.Name, &c.Path, &c.Depth); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read categories", err))
+			return
+		}
+		c.ID = &id
+		if parent.Valid {
+			p := parent.Int64
+			c.ParentID = &p
+		}
+		out = append(out, &c)
+		byID[id] = &c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read categories", err))
+		return
+	}
+	uncategorized := &categoryRollup{Name: "Uncategorized", Path: "Uncategorized"}
+
+	add := func(categoryID sql.NullInt64, inflow, outflow int64) {
+		c := uncategorized
+		if categoryID.Valid {
+			if found, ok := byID[categoryID.Int64]; ok {
+				c = found
+			}
+		}
+		c.OwnInflow += inflow
+		c.OwnOutflow += outflow
+	}
+
+	if mode == "actual" {
+		rows, err = s.db.Query(`
+			SELECT category_id,
+			       SUM(CASE WHEN src_account_id IS NULL THEN amount_cents ELSE 0 END),
+			       SUM(CASE WHEN dest_account_id IS NULL THEN amount_cents ELSE 0 END)
+			FROM v_entry_category_line
+			WHERE ledger_id = ?
+			  AND entry_date BETWEEN ? AND ?
+			  AND (src_account_id IS NULL OR dest_account_id IS NULL)
+			GROUP BY category_id
+		`, ledgerID, from, to)
+	} else {
+		var args []any
+		if args, err = occurrenceArgs(s.db, ledgerID, 0, from, to); err != nil {
+			writeErr(w, serverError("failed to compute category totals", err))
+			return
+		}
+		rows, err = s.db.Query(`
+			SELECT category_id,
+			       SUM(CASE WHEN kind = 'I' THEN amount_cents ELSE 0 END),
+			       SUM(CASE WHEN kind = 'E' THEN amount_cents ELSE 0 END)
+			FROM (`+occurrenceQuery()+`)
+			WHERE kind IN ('I', 'E')
+			GROUP BY category_id
+		`, args...)
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to compute category totals", err))
+		return
+	}
+	for rows.Next() {
+		var (
+			categoryID      sql.NullInt64
+			inflow, outflow int64
+		)
+		if err := rows.Scan(&categoryID, &inflow, &outflow); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read category totals", err))
+			return
+		}
+		add(categoryID, inflow, outflow)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read category totals", err))
+		return
+	}
+
+	// Roll each category's own totals into itself and every ancestor.
+	for _, c := range out {
+		for cur := c; cur != nil; {
+			cur.InflowCents += c.OwnInflow
+			cur.OutflowCents += c.OwnOutflow
+			if cur.ParentID == nil {
+				break
+			}
+			cur = byID[*cur.ParentID]
+		}
+	}
+	uncategorized.InflowCents = uncategorized.OwnInflow
+	uncategorized.OutflowCents = uncategorized.OwnOutflow
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	out = append(out, uncategorized)
+
+	writeOK(w, map[string]any{
+		"mode":       mode,
+		"from_date":  from,
+		"to_date":    to,
+		"categories": out,
+	})
+}
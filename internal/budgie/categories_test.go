@@ -0,0 +1,124 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCategoriesPathRollupAndDelete(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	server := newTestAPIServer(t, db)
+
+	create := func(name string, parent any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, server.URL+"/api/categories", map[string]any{"name": name, "parent_id": parent})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create category %s: status %d", name, resp.StatusCode)
+		}
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	food := create("Food", nil)
+	groceries := create("Groceries", food)
+
+	resp := doJSON(t, http.MethodPost, server.URL+"/api/entries", map[string]any{
+		"entry_date":     "2026-01-05",
+		"name":           "Market",
+		"amount_cents":   2500,
+		"src_account_id": acctID,
+		"category_id":    groceries,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create entry: status %d", resp.StatusCode)
+	}
+	marketID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// Edits that leave the category out, like the editors', keep it.
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/entries/"+fmtInt64(marketID), map[string]any{
+		"entry_date": "2026-01-05", "name": "Market", "amount_cents": 2500, "src_account_id": acctID, "description": "Weekly",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["category_id"] == nil || mustInt64(t, updated["category_id"]) != groceries {
+		t.Fatalf("expected an entry edit without category_id to keep it: %v", updated)
+	}
+	schedule := map[string]any{
+		"name": "Market run", "kind": "E", "amount_cents": 2500, "src_account_id": acctID,
+		"start_date": "2026-02-05", "freq": "M", "interval": 1, "category_id": groceries,
+	}
+	resp = doJSON(t, http.MethodPost, server.URL+"/api/schedules", schedule)
+	scheduleID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	delete(schedule, "category_id")
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/schedules/"+fmtInt64(scheduleID), schedule)
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["category_id"] == nil || mustInt64(t, updated["category_id"]) != groceries {
+		t.Fatalf("expected a schedule edit without category_id to keep it: %v", updated)
+	}
+	resp = doJSON(t, http.MethodPost, server.URL+"/api/entries", map[string]any{
+		"entry_date":     "2026-01-06",
+		"name":           "Diner",
+		"amount_cents":   1500,
+		"src_account_id": acctID,
+		"category_id":    food,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create entry 2: status %d", resp.StatusCode)
+	}
+
+	entriesResp, err := http.Get(server.URL + "/api/entries")
+	if err != nil {
+		t.Fatalf("get entries: %v", err)
+	}
+	paths := map[string]any{}
+	for _, item := range mustList(t, decodeAPIResponse(t, entriesResp).Data) {
+		row := mustMap(t, item)
+		paths[row["name"].(string)] = row["category_path"]
+	}
+	if paths["Market"] != "Food > Groceries" {
+		t.Fatalf("expected Market path 'Food > Groceries', got %v", paths["Market"])
+	}
+
+	rollResp, err := http.Get(server.URL + "/api/categories/rollup?from_date=2026-01-01&to_date=2026-01-31")
+	if err != nil {
+		t.Fatalf("get rollup: %v", err)
+	}
+	roll := mustMap(t, decodeAPIResponse(t, rollResp).Data)
+	totals := map[string]int64{}
+	for _, item := range mustList(t, roll["categories"]) {
+		row := mustMap(t, item)
+		totals[row["path"].(string)] = mustInt64(t, row["outflow_cents"])
+	}
+	if totals["Food"] != 4000 || totals["Food > Groceries"] != 2500 {
+		t.Fatalf("unexpected rollup totals: %v", totals)
+	}
+
+	// Deleting the child moves its entries up to the parent.
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/categories/"+fmtInt64(groceries), nil)
+	delResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete category: %v", err)
+	}
+	if delResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 deleting category, got %d", delResp.StatusCode)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entry WHERE category_id = ?", food).Scan(&n); err != nil {
+		t.Fatalf("count entries: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected both entries under Food after delete, got %d", n)
+	}
+
+	// A category cannot become its own descendant's child.
+	sub := create("Takeout", food)
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/categories/"+fmtInt64(food), map[string]any{"name": "Food", "parent_id": sub})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for cyclic parent, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,449 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Credit cards are liability accounts with card_closing_day set. Each cycle
+// closes on that day of the month (clamped to the month's end); the statement
+// is due card_due_offset_days later, and interest is only charged when the
+// previous statement was not paid in full within card_grace_days. The
+// minimum payment is the greater of card_min_payment_cents and
+// card_min_payment_bps of the balance (plus the cycle's interest when
+// card_min_payment_plus_interest is set), never more than the balance.
+//
+// With card_autopay set to 'full' or 'minimum', projections pay each
+// statement from card_autopay_account_id on its due date, less whatever was
+// already paid (or scheduled to be paid) since the statement closed.
+
+type cardFields struct {
+	ClosingDay        *int64 `json:"card_closing_day"`
+	GraceDays         *int64 `json:"card_grace_days"`
+	DueOffsetDays     *int64 `json:"card_due_offset_days"`
+	MinPaymentCents   *int64 `json:"card_min_payment_cents"`
+	MinPaymentBps     *int64 `json:"card_min_payment_bps"`
+	MinPaymentPlusInt *int64 `json:"card_min_payment_plus_interest"`
+	Autopay           string `json:"card_autopay"`
+	AutopayAccountID  *int64 `json:"card_autopay_account_id"`
+}
+
+func int64Or(v *int64, def int64) *int64 {
+	if v == nil {
+		return &def
+	}
+	return v
+}
+
+// validateCardFields checks the statement-cycle settings of an account and
+// fills in defaults. currency is the account's currency, or "" to read it
+// from the existing account.
+func (s *server) validateCardFields(ledgerID, accountID, isLiability int64, currency string, loan *loanFields, f *cardFields) *apiErr {
+	if f.ClosingDay == nil {
+		if f.GraceDays != nil || f.DueOffsetDays != nil || f.MinPaymentCents != nil || f.MinPaymentBps != nil ||
+			f.MinPaymentPlusInt != nil || f.AutopayAccountID != nil || (f.Autopay != "" && f.Autopay != "none") {
+			return badRequest("card_closing_day is required for a credit card", nil)
+		}
+		f.Autopay = "none"
+		return nil
+	}
+	if isLiability != 1 {
+		return badRequest("only liability accounts can be credit cards", nil)
+	}
+	if loan.PrincipalCents != nil {
+		return badRequest("an account cannot be both a loan and a credit card", nil)
+	}
+	if *f.ClosingDay < 1 || *f.ClosingDay > 31 {
+		return badRequest("card_closing_day must be 1..31", nil)
+	}
+	f.DueOffsetDays = int64Or(f.DueOffsetDays, 25)
+	if *f.DueOffsetDays < 1 || *f.DueOffsetDays > 28 {
+		return badRequest("card_due_offset_days must be 1..28", nil)
+	}
+	f.GraceDays = int64Or(f.GraceDays, *f.DueOffsetDays)
+	if *f.GraceDays < 0 || *f.GraceDays > 28 {
+		return badRequest("card_grace_days must be 0..28", nil)
+	}
+	f.MinPaymentCents = int64Or(f.MinPaymentCents, 2500)
+	if *f.MinPaymentCents < 0 {
+		return badRequest("card_min_payment_cents must be >= 0", nil)
+	}
+	f.MinPaymentBps = int64Or(f.MinPaymentBps, 100)
+	if *f.MinPaymentBps < 0 || *f.MinPaymentBps > 10000 {
+		return badRequest("card_min_payment_bps must be 0..10000", nil)
+	}
+	f.MinPaymentPlusInt = int64Or(f.MinPaymentPlusInt, 1)
+	if *f.MinPaymentPlusInt != 0 {
+		*f.MinPaymentPlusInt = 1
+	}
+
+	switch f.Autopay {
+	case "", "none":
+		f.Autopay = "none"
+		f.AutopayAccountID = nil
+		return nil
+	case "full", "minimum":
+	default:
+		return badRequest("card_autopay must be 'none', 'full' or 'minimum'", nil)
+	}
+	if f.AutopayAccountID == nil {
+		return badRequest("card_autopay_account_id is required for autopay", nil)
+	}
+	if *f.AutopayAccountID == accountID {
+		return badRequest("card_autopay_account_id must be another account", nil)
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, f.AutopayAccountID); e != nil {
+		return e
+	}
+	var fundingCurrency, cardCurrency string
+	if err := s.db.QueryRow(
+		"SELECT (SELECT currency FROM account WHERE id = ?), COALESCE(NULLIF(?, ''), (SELECT currency FROM account WHERE id = ?), '')",
+		*f.AutopayAccountID, currency, accountID,
+	).Scan(&fundingCurrency, &cardCurrency); err != nil {
+		return serverError("failed to read account currencies", err)
+	}
+	if fundingCurrency != cardCurrency {
+		return badRequest("card_autopay_account_id must use the card's currency", nil)
+	}
+	return nil
+}
+
+type creditCard struct {
+	ID               int64
+	Name             string
+	OpeningDate      string
+	OpeningBalance   int64
+	AprBps           int64
+	Compound         string
+	ClosingDay       int
+	GraceDays        int
+	DueOffsetDays    int
+	MinPaymentCents  int64
+	MinPaymentBps    int64
+	MinPaymentPlus   bool
+	Autopay          string
+	AutopayAccountID *int64
+}
+
+const creditCardColumns = `id, name, opening_date, opening_balance_cents,
+	CASE WHEN is_interest_bearing = 1 THEN COALESCE(interest_apr_bps, 0) ELSE 0 END,
+	COALESCE(interest_compound, 'D'), card_closing_day, card_grace_days, card_due_offset_days,
+	card_min_payment_cents, card_min_payment_bps, card_min_payment_plus_interest, card_autopay, card_autopay_account_id`
+
+func scanCreditCard(row interface{ Scan(...any) error }) (creditCard, error) {
+	var c creditCard
+	err := row.Scan(&c.ID, &c.Name, &c.OpeningDate, &c.OpeningBalance, &c.AprBps, &c.Compound, &c.ClosingDay, &c.GraceDays, &c.DueOffsetDays,
+		&c.MinPaymentCents, &c.MinPaymentBps, &c.MinPaymentPlus, &c.Autopay, &c.AutopayAccountID)
+	return c, err
+}
+
+// closingDate is the card's closing date in the given month.
+func (c creditCard) closingDate(year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	day := c.ClosingDay
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+type datedCents struct {
+	Date  string
+	Cents int64
+}
+
+// accountActivity is the balance history of one account: its opening balance
+// and dated deltas in date order.
+type accountActivity struct {
+	opening int64
+	deltas  []datedCents
+}
+
+func (a *accountActivity) add(d datedCents) {
+	i := sort.Search(len(a.deltas), func(i int) bool { return a.deltas[i].Date > d.Date })
+	a.deltas = append(a.deltas, datedCents{})
+	copy(a.deltas[i+1:], a.deltas[i:])
+	a.deltas[i] = d
+}
+
+func (a *accountActivity) balanceAt(date string) int64 {
+	b := a.opening
+	for _, d := range a.deltas {
+		if d.Date > date {
+			break
+		}
+		b += d.Cents
+	}
+	return b
+}
+
+// sums returns the credits and debits (both positive) dated after after and
+// on or before through.
+func (a *accountActivity) sums(after, through string) (credits, debits int64) {
+	for _, d := range a.deltas {
+		if d.Date <= after {
+			continue
+		}
+		if d.Date > through {
+			break
+		}
+		if d.Cents > 0 {
+			credits += d.Cents
+		} else {
+			debits -= d.Cents
+		}
+	}
+	return credits, debits
+}
+
+type cardStatement struct {
+	PeriodStart           string `json:"period_start"`
+	ClosingDate           string `json:"closing_date"`
+	GraceEndDate          string `json:"grace_end_date"`
+	DueDate               string `json:"due_date"`
+	PreviousBalanceCents  int64  `json:"previous_balance_cents"`
+	ChargesCents          int64  `json:"charges_cents"`
+	CreditsCents          int64  `json:"credits_cents"`
+	StatementBalanceCents int64  `json:"statement_balance_cents"`
+	InterestCents         int64  `json:"interest_cents"`
+	MinimumPaymentCents   int64  `json:"minimum_payment_cents"`
+	PaidCents             int64  `json:"paid_cents"`
+	PaidInFull            bool   `json:"paid_in_full"`
+	MinimumMet            bool   `json:"minimum_met"`
+}
+
+// statement derives the statement closing on closing. Balances are reported
+// as amounts owed. interest_cents is the interest the cycle carries when the
+// previous statement was not paid in full by the end of its grace period.
+func (c creditCard) statement(a *accountActivity, closing time.Time) cardStatement {
+	prev := c.closingDate(closing.Year(), closing.Month()-1)
+	prevDate, closeDate := prev.Format("2006-01-02"), closing.Format("2006-01-02")
+	due := closing.AddDate(0, 0, c.DueOffsetDays)
+
+	st := cardStatement{
+		PeriodStart:           prev.AddDate(0, 0, 1).Format("2006-01-02"),
+		ClosingDate:           closeDate,
+		GraceEndDate:          closing.AddDate(0, 0, c.GraceDays).Format("2006-01-02"),
+		DueDate:               due.Format("2006-01-02"),
+		PreviousBalanceCents:  -a.balanceAt(prevDate),
+		StatementBalanceCents: -a.balanceAt(closeDate),
+	}
+	st.CreditsCents, st.ChargesCents = a.sums(prevDate, closeDate)
+	if st.PreviousBalanceCents > 0 {
+		paid, _ := a.sums(prevDate, prev.AddDate(0, 0, c.GraceDays).Format("2006-01-02"))
+		if paid < st.PreviousBalanceCents {
+			st.InterestCents = interestForPeriodCents(st.PreviousBalanceCents, c.AprBps, c.Compound, prev, closing)
+		}
+	}
+	if st.StatementBalanceCents > 0 {
+		minimum := st.StatementBalanceCents * c.MinPaymentBps / 10000
+		if c.MinPaymentPlus {
+			minimum += st.InterestCents
+		}
+		st.MinimumPaymentCents = min(max(minimum, c.MinPaymentCents), st.StatementBalanceCents)
+	}
+	st.PaidCents, _ = a.sums(closeDate, st.DueDate)
+	st.PaidInFull = st.PaidCents >= st.StatementBalanceCents
+	st.MinimumMet = st.PaidCents >= st.MinimumPaymentCents
+	return st
+}
+
+// loadAccountActivity reads an account's actual deltas up to through.
+func (s *server) loadAccountActivity(accountID int64, openingDate string, openingBalance int64, through string) (*accountActivity, error) {
+	rows, err := s.db.Query(`
+		SELECT entry_date, SUM(delta_cents)
+		FROM v_entry_delta
+		WHERE account_id = ? AND entry_date >= ? AND entry_date <= ?
+		GROUP BY entry_date
+		ORDER BY entry_date
+	`, accountID, openingDate, through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	a := &accountActivity{opening: openingBalance}
+	for rows.Next() {
+		var d datedCents
+		if err := rows.Scan(&d.Date, &d.Cents); err != nil {
+			return nil, err
+		}
+		a.deltas = append(a.deltas, d)
+	}
+	return a, rows.Err()
+}
+
+type cardPayment struct {
+	CardID      int64
+	CardName    string
+	FundingID   int64
+	ClosingDate string
+	Date        string
+	AmountCents int64
+}
+
+// cardAutopayments generates the autopay statement payments due between start
+// and asOf. Statement balances include scheduled occurrences from start on,
+// matching projectedBalanceQuery, and earlier generated payments. occs lists
+// the occurrences from start.
+func (s *server) cardAutopayments(ledgerID int64, start, asOf string, occs *projectionOccurrences) ([]cardPayment, error) {
+	rows, err := s.db.Query(
+		"SELECT "+creditCardColumns+" FROM account WHERE ledger_id = ? AND archived_at IS NULL AND card_closing_day IS NOT NULL AND card_autopay != 'none' AND card_autopay_account_id IS NOT NULL",
+		ledgerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var cards []creditCard
+	for rows.Next() {
+		c, err := scanCreditCard(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(cards) == 0 {
+		return nil, err
+	}
+
+	list, err := occs.through(asOf)
+	if err != nil {
+		return nil, err
+	}
+	byAccount := make(map[int64][]datedCents)
+	for _, o := range list {
+		if o.SrcAccountID != nil {
+			byAccount[*o.SrcAccountID] = append(byAccount[*o.SrcAccountID], datedCents{o.Date, -o.AmountCents})
+		}
+		if o.DestAccountID != nil {
+			byAccount[*o.DestAccountID] = append(byAccount[*o.DestAccountID], datedCents{o.Date, o.DestAmount})
+		}
+	}
+
+	startT, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return nil, err
+	}
+	var out []cardPayment
+	for _, c := range cards {
+		a, err := s.loadAccountActivity(c.ID, c.OpeningDate, c.OpeningBalance, asOf)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range byAccount[c.ID] {
+			a.add(d)
+		}
+		// The earliest statement that can fall due on or after start closed
+		// at most a month before it.
+		for m := startT.AddDate(0, -2, 0); ; m = m.AddDate(0, 1, 0) {
+			closing := c.closingDate(m.Year(), m.Month())
+			closeDate := closing.Format("2006-01-02")
+			if closeDate > asOf {
+				break
+			}
+			if closeDate <= c.OpeningDate {
+				continue
+			}
+			st := c.statement(a, closing)
+			if st.DueDate < start || st.DueDate > asOf {
+				continue
+			}
+			amount := st.StatementBalanceCents - st.PaidCents
+			if c.Autopay == "minimum" {
+				amount = st.MinimumPaymentCents - st.PaidCents
+			}
+			if amount <= 0 {
+				continue
+			}
+			a.add(datedCents{st.DueDate, amount})
+			out = append(out, cardPayment{
+				CardID:      c.ID,
+				CardName:    c.Name,
+				FundingID:   *c.AutopayAccountID,
+				ClosingDate: closeDate,
+				Date:        st.DueDate,
+				AmountCents: amount,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out, nil
+}
+
+// accountStatements serves GET /api/accounts/{id}/statements: the card's
+// statements closing between from_date and to_date (default: the year up to
+// today), derived from its entries.
+func (s *server) accountStatements(w http.ResponseWriter, r *http.Request, ledgerID, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	to := r.URL.Query().Get("to_date")
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	toT, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		writeErr(w, badRequest("to_date must be an ISO date YYYY-MM-DD", nil))
+		return
+	}
+	from := r.URL.Query().Get("from_date")
+	if from == "" {
+		from = toT.AddDate(-1, 0, 1).Format("2006-01-02")
+	}
+	fromT, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		writeErr(w, badRequest("from_date must be an ISO date YYYY-MM-DD", nil))
+		return
+	}
+	if toT.Before(fromT) || toT.After(fromT.AddDate(20, 0, 0)) {
+		writeErr(w, badRequest("to_date must be within 20 years after from_date", nil))
+		return
+	}
+
+	var isCard bool
+	err = s.db.QueryRow("SELECT card_closing_day IS NOT NULL FROM account WHERE id = ? AND ledger_id = ?", id, ledgerID).Scan(&isCard)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("account not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read account", err))
+		return
+	}
+	if !isCard {
+		writeErr(w, badRequest("account is not a credit card", nil))
+		return
+	}
+	c, err := scanCreditCard(s.db.QueryRow("SELECT "+creditCardColumns+" FROM account WHERE id = ?", id))
+	if err != nil {
+		writeErr(w, serverError("failed to read credit card", err))
+		return
+	}
+	// Payments up to the last statement's due date count towards it.
+	a, err := s.loadAccountActivity(c.ID, c.OpeningDate, c.OpeningBalance, toT.AddDate(0, 0, c.DueOffsetDays).Format("2006-01-02"))
+	if err != nil {
+		writeErr(w, serverError("failed to read card activity", err))
+		return
+	}
+
+	statements := []cardStatement{}
+	for m := time.Date(fromT.Year(), fromT.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(toT); m = m.AddDate(0, 1, 0) {
+		closing := c.closingDate(m.Year(), m.Month())
+		closeDate := closing.Format("2006-01-02")
+		if closeDate < from || closeDate > to || closeDate <= c.OpeningDate {
+			continue
+		}
+		statements = append(statements, c.statement(a, closing))
+	}
+	writeOK(w, map[string]any{
+		"account_id": id,
+		"from_date":  from,
+		"to_date":    to,
+		"statements": statements,
+	})
+}
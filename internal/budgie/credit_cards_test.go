@@ -0,0 +1,131 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreditCardStatementsAndAutopay(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	card := map[string]any{
+		"name": "Visa", "opening_date": "2026-01-01", "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 2400, "interest_compound": "D",
+		"card_closing_day": 20,
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", card)
+	created := mustMap(t, decodeAPIResponse(t, resp).Data)
+	cardID := mustInt64(t, created["id"])
+	if mustInt64(t, created["card_due_offset_days"]) != 25 || mustInt64(t, created["card_min_payment_cents"]) != 2500 || created["card_autopay"] != "none" {
+		t.Fatalf("expected card defaults, got %v", created)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Bad", "opening_date": "2026-01-01", "card_closing_day": 20,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a card that is not a liability, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	if _, err := db.Exec("INSERT INTO entry (entry_date, name, amount_cents, src_account_id) VALUES (?, ?, ?, ?)", "2026-01-10", "Laptop", int64(50000), cardID); err != nil {
+		t.Fatalf("insert purchase: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO entry (entry_date, name, amount_cents, src_account_id, dest_account_id) VALUES (?, ?, ?, ?, ?)", "2026-02-10", "Card payment", int64(2500), checkingID, cardID); err != nil {
+		t.Fatalf("insert payment: %v", err)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/accounts/"+fmtInt64(cardID)+"/statements?from_date=2026-01-01&to_date=2026-02-28", nil)
+	statements := mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["statements"])
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(statements))
+	}
+	jan, feb := mustMap(t, statements[0]), mustMap(t, statements[1])
+	if jan["closing_date"] != "2026-01-20" || jan["due_date"] != "2026-02-14" ||
+		mustInt64(t, jan["statement_balance_cents"]) != 50000 || mustInt64(t, jan["interest_cents"]) != 0 ||
+		mustInt64(t, jan["minimum_payment_cents"]) != 2500 || jan["paid_in_full"] != false || jan["minimum_met"] != true {
+		t.Fatalf("unexpected January statement: %v", jan)
+	}
+	// January's balance was not paid in full, so February carries interest.
+	interest := interestForPeriodCents(50000, 2400, "D", time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC))
+	if mustInt64(t, feb["previous_balance_cents"]) != 50000 || mustInt64(t, feb["credits_cents"]) != 2500 ||
+		mustInt64(t, feb["statement_balance_cents"]) != 47500 || mustInt64(t, feb["interest_cents"]) != interest ||
+		mustInt64(t, feb["minimum_payment_cents"]) != 2500 {
+		t.Fatalf("unexpected February statement: %v (interest %d)", feb, interest)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Streaming", "kind": "E", "amount_cents": 10000, "src_account_id": cardID,
+		"start_date": "2026-03-01", "freq": "M", "interval": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	card["card_autopay"] = "full"
+	card["card_autopay_account_id"] = checkingID
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(cardID), card)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("enable autopay: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	projected := func(asOf string) map[int64]int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of="+asOf, nil)
+		out := map[int64]int64{}
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			row := mustMap(t, item)
+			out[mustInt64(t, row["id"])] = mustInt64(t, row["projected_balance_cents"])
+		}
+		return out
+	}
+	// January's statement is paid off on Feb 14 less the earlier 2500; the
+	// March streaming charge is paid on Apr 14. April's charge stays owed.
+	got := projected("2026-04-30")
+	if got[cardID] != -10000 || got[checkingID] != 100000-2500-47500-10000 {
+		t.Fatalf("projected balances with full autopay = %v", got)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-01&to_date=2026-04-30", nil)
+	var payment map[string]any
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		if row := mustMap(t, item); row["statement_payment"] != nil {
+			payment = row
+		}
+	}
+	if payment == nil || payment["occ_date"] != "2026-04-14" || mustInt64(t, payment["amount_cents"]) != 10000 ||
+		mustInt64(t, payment["src_account_id"]) != checkingID || mustInt64(t, payment["dest_account_id"]) != cardID {
+		t.Fatalf("unexpected statement payment occurrence: %v", payment)
+	}
+
+	card["card_autopay"] = "minimum"
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(cardID), card)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("switch autopay: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	// The January minimum was already paid by hand.
+	if got := projected("2026-02-28"); got[cardID] != -47500 {
+		t.Fatalf("projected card balance with minimum autopay = %d, want -47500", got[cardID])
+	}
+
+	// The account editor leaves the card settings out; they are kept.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(cardID), map[string]any{
+		"name": "Visa Gold", "opening_date": "2026-01-01", "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 2400, "interest_compound": "D",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["card_closing_day"] == nil ||
+		mustInt64(t, updated["card_closing_day"]) != 20 || updated["card_autopay"] != "minimum" ||
+		mustInt64(t, updated["card_autopay_account_id"]) != checkingID {
+		t.Fatalf("card settings after edit = %v", updated)
+	}
+}
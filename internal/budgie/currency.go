@@ -0,0 +1,370 @@
+package budgie
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var currencyRE = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// requireCurrency normalizes a currency code to upper case and checks that it
+// looks like an ISO-4217 code.
+func requireCurrency(v string, field string) (string, *apiErr) {
+	c := strings.ToUpper(strings.TrimSpace(v))
+	if !currencyRE.MatchString(c) {
+		return "", badRequest(field+" must be a 3-letter currency code", nil)
+	}
+	return c, nil
+}
+
+// reportCurrencyParam reads the optional report_currency query parameter.
+func reportCurrencyParam(r *http.Request) (string, *apiErr) {
+	v := r.URL.Query().Get("report_currency")
+	if strings.TrimSpace(v) == "" {
+		return "", nil
+	}
+	return requireCurrency(v, "report_currency")
+}
+
+// transferDestAmount decides what the destination of a movement receives.
+// Movements between accounts of different currencies must state it; for all
+// others it is nil, meaning the destination receives the source amount.
+func (s *server) transferDestAmount(src, dest *int64, destAmount *int64) (*int64, *apiErr) {
+	if src == nil || dest == nil {
+		return nil, nil
+	}
+	var srcCur, destCur string
+	err := s.db.QueryRow(
+		"SELECT (SELECT currency FROM account WHERE id = ?), (SELECT currency FROM account WHERE id = ?)",
+		*src, *dest,
+	).Scan(&srcCur, &destCur)
+	if err != nil {
+		return nil, serverError("failed to read account currencies", err)
+	}
+	if srcCur == destCur {
+		return nil, nil
+	}
+	if destAmount == nil || *destAmount <= 0 {
+		return nil, badRequest("dest_amount_cents is required for transfers between currencies", map[string]any{"src_currency": srcCur, "dest_currency": destCur})
+	}
+	return destAmount, nil
+}
+
+// --- Exchange rates ---
+
+type fxRateBody struct {
+	BaseCurrency  string  `json:"base_currency"`
+	QuoteCurrency string  `json:"quote_currency"`
+	RateDate      string  `json:"rate_date"`
+	Rate          float64 `json:"rate"`
+}
+
+func validateFXRate(b *fxRateBody) *apiErr {
+	base, e := requireCurrency(b.BaseCurrency, "base_currency")
+	if e != nil {
+		return e
+	}
+	quote, e := requireCurrency(b.QuoteCurrency, "quote_currency")
+	if e != nil {
+		return e
+	}
+	if base == quote {
+		return badRequest("base_currency and quote_currency must differ", nil)
+	}
+	if _, e := requireDate(b.RateDate, "rate_date"); e != nil {
+		return e
+	}
+	if !(b.Rate > 0) || math.IsInf(b.Rate, 0) {
+		return badRequest("rate must be > 0", nil)
+	}
+	b.BaseCurrency, b.QuoteCurrency = base, quote
+	return nil
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// upsertFXRate stores a rate, replacing any rate already recorded for the same
+// pair and date.
+func upsertFXRate(q rowQuerier, ledgerID int64, b fxRateBody) (int64, error) {
+	var id int64
+	err := q.QueryRow(`
+		INSERT INTO fx_rate (ledger_id, base_currency, quote_currency, rate_date, rate)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (ledger_id, base_currency, quote_currency, rate_date) DO UPDATE SET rate = excluded.rate
+		RETURNING id
+	`, ledgerID, b.BaseCurrency, b.QuoteCurrency, b.RateDate, b.Rate).Scan(&id)
+	return id, err
+}
+
+// fxRates serves GET/POST /api/fx-rates. GET accepts optional base/quote
+// filters; POST creates or replaces the rate for a pair and date.
+func (s *server) fxRates(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := "SELECT * FROM fx_rate WHERE ledger_id = ?"
+		args := []any{ledgerID}
+		for _, f := range []struct{ param, col string }{{"base", "base_currency"}, {"quote", "quote_currency"}} {
+			if v := r.URL.Query().Get(f.param); v != "" {
+				c, e := requireCurrency(v, f.param)
+				if e != nil {
+					writeErr(w, e)
+					return
+				}
+				q += " AND " + f.col + " = ?"
+				args = append(args, c)
+			}
+		}
+		rows, err := s.db.Query(q+" ORDER BY base_currency, quote_currency, rate_date DESC", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query fx rates", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read fx rates", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body fxRateBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateFXRate(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		id, err := upsertFXRate(s.db, ledgerID, body)
+		if err != nil {
+			writeErr(w, serverError("failed to save fx rate", err))
+			return
+		}
+		created, apiE := scanRowToMap(s.db, "fx_rate", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) fxRateByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/fx-rates/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body fxRateBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateFXRate(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"UPDATE fx_rate SET base_currency=?, quote_currency=?, rate_date=?, rate=? WHERE id=? AND ledger_id=?",
+			body.BaseCurrency, body.QuoteCurrency, body.RateDate, body.Rate, id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not update fx rate (one already exists for this pair and date?)", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("fx rate not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "fx_rate", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM fx_rate WHERE id = ? AND ledger_id = ?", id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete fx rate", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("fx rate not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// fxRatesImport loads rates from a CSV request body with the header
+// date,base,quote,rate. Existing rates for the same pair and date are
+// replaced. The import is all-or-nothing.
+//
+//	POST /api/fx-rates/import
+func (s *server) fxRatesImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	rates, e := parseFXRateCSV(io.LimitReader(r.Body, 4<<20))
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+	for _, b := range rates {
+		if _, err := upsertFXRate(tx, ledgerID, b); err != nil {
+			writeErr(w, serverError("failed to save fx rate", err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeErr(w, serverError("failed to import fx rates", err))
+		return
+	}
+	writeOK(w, map[string]any{"imported": len(rates)})
+}
+
+func parseFXRateCSV(src io.Reader) ([]fxRateBody, *apiErr) {
+	cr := csv.NewReader(src)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, badRequest("CSV must start with a header row: date,base,quote,rate", nil)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, h := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := col[h]; !ok {
+			return nil, badRequest("CSV header is missing column "+h, nil)
+		}
+	}
+
+	var out []fxRateBody
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, badRequest("invalid CSV", map[string]any{"line": line, "error": err.Error()})
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[col["rate"]]), 64)
+		if err != nil {
+			return nil, badRequest("rate must be a number", map[string]any{"line": line})
+		}
+		b := fxRateBody{
+			RateDate:      strings.TrimSpace(rec[col["date"]]),
+			BaseCurrency:  rec[col["base"]],
+			QuoteCurrency: rec[col["quote"]],
+			Rate:          rate,
+		}
+		if e := validateFXRate(&b); e != nil {
+			e.Details = map[string]any{"line": line}
+			return nil, e
+		}
+		out = append(out, b)
+	}
+	if len(out) == 0 {
+		return nil, badRequest("CSV contains no rates", nil)
+	}
+	return out, nil
+}
+
+// --- Conversion ---
+
+type fxPoint struct {
+	Date string
+	Rate float64
+}
+
+// fxTable holds a ledger's exchange rates, sorted by date per pair.
+type fxTable struct {
+	pairs map[[2]string][]fxPoint
+}
+
+func (s *server) loadFXTable(ledgerID int64) (*fxTable, error) {
+	rows, err := s.db.Query(`
+		SELECT base_currency, quote_currency, rate_date, rate
+		FROM fx_rate
+		WHERE ledger_id = ?
+		ORDER BY rate_date
+	`, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t := &fxTable{pairs: make(map[[2]string][]fxPoint)}
+	for rows.Next() {
+		var base, quote string
+		var p fxPoint
+		if err := rows.Scan(&base, &quote, &p.Date, &p.Rate); err != nil {
+			return nil, err
+		}
+		key := [2]string{base, quote}
+		t.pairs[key] = append(t.pairs[key], p)
+	}
+	return t, rows.Err()
+}
+
+// rateOn returns the most recent rate on or before date for one direction of
+// a pair. Dates before the first known rate use that first rate.
+func (t *fxTable) rateOn(base, quote, date string) (float64, bool) {
+	pts := t.pairs[[2]string{base, quote}]
+	if len(pts) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].Date > date })
+	if i == 0 {
+		return pts[0].Rate, true
+	}
+	return pts[i-1].Rate, true
+}
+
+// convert converts cents between currencies using the rate in effect on date.
+// A stored rate for the inverse pair is used when the direct pair is missing.
+func (t *fxTable) convert(cents int64, from, to, date string) (int64, error) {
+	if from == to || cents == 0 {
+		return cents, nil
+	}
+	if rate, ok := t.rateOn(from, to, date); ok {
+		return int64(math.Round(float64(cents) * rate)), nil
+	}
+	if rate, ok := t.rateOn(to, from, date); ok {
+		return int64(math.Round(float64(cents) / rate)), nil
+	}
+	return 0, fmt.Errorf("no exchange rate between %s and %s", from, to)
+}
@@ -0,0 +1,118 @@
+package budgie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCrossCurrencyTransferAndReportCurrency(t *testing.T) {
+	db := newTestDB(t)
+	server := newTestAPIServer(t, db)
+
+	accounts := map[string]int64{}
+	for _, a := range []struct {
+		name, currency string
+		opening        int64
+	}{{"Euro Checking", "eur", 100000}, {"Dollar Checking", "USD", 50000}} {
+		resp := doJSON(t, http.MethodPost, server.URL+"/api/accounts", map[string]any{
+			"name":                  a.name,
+			"opening_date":          "2026-01-01",
+			"opening_balance_cents": a.opening,
+			"currency":              a.currency,
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create account %s: status %d", a.name, resp.StatusCode)
+		}
+		created := mustMap(t, decodeAPIResponse(t, resp).Data)
+		if created["currency"] != strings.ToUpper(a.currency) {
+			t.Fatalf("expected currency %s, got %v", strings.ToUpper(a.currency), created["currency"])
+		}
+		accounts[a.name] = mustInt64(t, created["id"])
+	}
+
+	csv := "date,base,quote,rate\n2026-01-01,EUR,USD,1.10\n2026-02-01,EUR,USD,1.20\n"
+	resp, err := http.Post(server.URL+"/api/fx-rates/import", "text/csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("import rates: %v", err)
+	}
+	if imported := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["imported"]); imported != 2 {
+		t.Fatalf("expected 2 imported rates, got %d", imported)
+	}
+
+	transfer := map[string]any{
+		"entry_date":      "2026-01-10",
+		"name":            "Move to USD",
+		"amount_cents":    10000,
+		"src_account_id":  accounts["Euro Checking"],
+		"dest_account_id": accounts["Dollar Checking"],
+	}
+	resp = doJSON(t, http.MethodPost, server.URL+"/api/entries", transfer)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without dest_amount_cents, got %d", resp.StatusCode)
+	}
+	transfer["dest_amount_cents"] = 10900
+	resp = doJSON(t, http.MethodPost, server.URL+"/api/entries", transfer)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create transfer: status %d", resp.StatusCode)
+	}
+	transferID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// An edit that leaves the received amount out, like the editor's, keeps it.
+	delete(transfer, "dest_amount_cents")
+	transfer["description"] = "Savings"
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/entries/"+fmtInt64(transferID), transfer)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit transfer: status %d", resp.StatusCode)
+	}
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); mustInt64(t, updated["dest_amount_cents"]) != 10900 {
+		t.Fatalf("expected an edit without dest_amount_cents to keep it: %v", updated)
+	}
+
+	balancesAt := func(asOf string) map[string]map[string]any {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/balances?as_of=" + asOf + "&report_currency=USD")
+		if err != nil {
+			t.Fatalf("get balances: %v", err)
+		}
+		out := map[string]map[string]any{}
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			row := mustMap(t, item)
+			out[row["name"].(string)] = row
+		}
+		return out
+	}
+
+	jan := balancesAt("2026-01-15")
+	if got := mustInt64(t, jan["Euro Checking"]["native_balance_cents"]); got != 90000 {
+		t.Fatalf("expected native EUR balance 90000, got %d", got)
+	}
+	if got := mustInt64(t, jan["Euro Checking"]["balance_cents"]); got != 99000 {
+		t.Fatalf("expected EUR balance 99000 USD cents at 1.10, got %d", got)
+	}
+	if got := mustInt64(t, jan["Dollar Checking"]["balance_cents"]); got != 60900 {
+		t.Fatalf("expected USD balance 60900, got %d", got)
+	}
+	if got := mustInt64(t, balancesAt("2026-02-15")["Euro Checking"]["balance_cents"]); got != 108000 {
+		t.Fatalf("expected EUR balance 108000 USD cents at 1.20, got %d", got)
+	}
+
+	// The inverse pair is derived from the stored rate.
+	resp, err = http.Get(server.URL + "/api/balances/series?mode=actual&from_date=2026-01-15&to_date=2026-02-15&step_days=31&report_currency=EUR")
+	if err != nil {
+		t.Fatalf("get series: %v", err)
+	}
+	series := mustMap(t, decodeAPIResponse(t, resp).Data)
+	totals := mustList(t, series["total_cents"])
+	if len(totals) != 2 || mustInt64(t, totals[0]) != 90000+55364 || mustInt64(t, totals[1]) != 90000+50750 {
+		t.Fatalf("unexpected EUR totals: %v", totals)
+	}
+
+	resp, err = http.Get(server.URL + "/api/balances?as_of=2026-01-15&report_currency=GBP")
+	if err != nil {
+		t.Fatalf("get balances: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a GBP rate, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,296 @@
+package budgie
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultForecastDays is the alert window when to_date is omitted.
+const defaultForecastDays = 30
+
+// balanceEvent is one movement of an account's balance during a forecast.
+type balanceEvent struct {
+	Date      string
+	AccountID int64
+	Name      string
+	Cents     int64
+}
+
+// orderBalanceEvents sorts events by date and, within a day, credits before
+// debits, or debits before credits when debitsFirst is set. Ties keep name
+// order so the walk is stable.
+func orderBalanceEvents(events []balanceEvent, debitsFirst bool) {
+	rank := func(e balanceEvent) int {
+		if (e.Cents < 0) == debitsFirst {
+			return 0
+		}
+		return 1
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra < rb
+		}
+		return a.Name < b.Name
+	})
+}
+
+type balanceAlert struct {
+	AccountID          int64   `json:"account_id"`
+	Name               string  `json:"name"`
+	Currency           string  `json:"currency"`
+	IsLiability        int64   `json:"is_liability"`
+	ThresholdCents     *int64  `json:"threshold_cents"`
+	StartingCents      int64   `json:"starting_balance_cents"`
+	EndingCents        int64   `json:"ending_balance_cents"`
+	LowestCents        int64   `json:"lowest_balance_cents"`
+	LowestDate         string  `json:"lowest_balance_date"`
+	Breached           bool    `json:"breached"`
+	FirstBreachDate    *string `json:"first_breach_date"`
+	FirstBreachCents   *int64  `json:"first_breach_balance_cents"`
+	FirstBreachTrigger *string `json:"first_breach_trigger"`
+}
+
+// forecastAlerts serves GET /api/forecast/alerts. It walks every projected
+// movement between from_date (default today) and to_date (default 30 days
+// later) one at a time and reports, per account, the first time the balance
+// drops below the account's min_balance_cents (zero for assets without one),
+// and the lowest balance reached. Within a day credits land before debits;
+// within_day=debits_first reverses that for a cautious forecast.
+//
+//	GET /api/forecast/alerts?from_date=2026-03-01&to_date=2026-03-31&account_id=2
+func (s *server) forecastAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+	q := r.URL.Query()
+
+	today := time.Now().Format("2006-01-02")
+	from := today
+	if v := q.Get("from_date"); v != "" {
+		if _, e := requireDate(v, "from_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		from = v
+	}
+	fromT, _ := time.Parse("2006-01-02", from)
+	to := fromT.AddDate(0, 0, defaultForecastDays).Format("2006-01-02")
+	if v := q.Get("to_date"); v != "" {
+		if _, e := requireDate(v, "to_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		to = v
+	}
+	if to < from {
+		writeErr(w, badRequest("to_date must be >= from_date", nil))
+		return
+	}
+	if toT, _ := time.Parse("2006-01-02", to); toT.Sub(fromT) > 3660*24*time.Hour {
+		writeErr(w, badRequest("forecast window must be at most 10 years", nil))
+		return
+	}
+	var debitsFirst bool
+	switch q.Get("within_day") {
+	case "", "credits_first":
+	case "debits_first":
+		debitsFirst = true
+	default:
+		writeErr(w, badRequest("within_day must be 'credits_first' or 'debits_first'", nil))
+		return
+	}
+	var onlyAccount *int64
+	if v := q.Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeErr(w, badRequest("account_id must be an integer", nil))
+			return
+		}
+		if e := s.ledgerOwnsAccounts(ledgerID, &id); e != nil {
+			writeErr(w, e)
+			return
+		}
+		onlyAccount = &id
+	}
+
+	// Balances at the end of the day before the window: actual up to today,
+	// projected beyond it.
+	prev := fromT.AddDate(0, 0, -1).Format("2006-01-02")
+	var (
+		opening []balancePoint
+		err     error
+	)
+	if from > today {
+		opening, err = s.projectedBalancesAsOf(ledgerID, today, prev)
+	} else {
+		opening, err = s.actualBalancesAsOf(ledgerID, prev)
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to compute opening balances", err))
+		return
+	}
+
+	events, err := s.forecastEvents(ledgerID, from, to, min(from, today))
+	if err != nil {
+		writeErr(w, serverError("failed to read projected movements", err))
+		return
+	}
+	orderBalanceEvents(events, debitsFirst)
+
+	meta := make(map[int64]struct {
+		isLiability int64
+		threshold   *int64
+	})
+	rows, err := s.db.Query("SELECT id, is_liability, min_balance_cents FROM account WHERE ledger_id = ?", ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to read accounts", err))
+		return
+	}
+	for rows.Next() {
+		var id, isLiability int64
+		var threshold sql.NullInt64
+		if err := rows.Scan(&id, &isLiability, &threshold); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read accounts", err))
+			return
+		}
+		m := meta[id]
+		m.isLiability = isLiability
+		if threshold.Valid {
+			m.threshold = &threshold.Int64
+		} else if isLiability == 0 {
+			zero := int64(0)
+			m.threshold = &zero
+		}
+		meta[id] = m
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read accounts", err))
+		return
+	}
+
+	alerts := []*balanceAlert{}
+	byID := make(map[int64]*balanceAlert)
+	for _, p := range opening {
+		if onlyAccount != nil && p.ID != *onlyAccount {
+			continue
+		}
+		m := meta[p.ID]
+		a := &balanceAlert{
+			AccountID: p.ID, Name: p.Name, Currency: p.Currency, IsLiability: m.isLiability, ThresholdCents: m.threshold,
+			StartingCents: p.BalanceCents, EndingCents: p.BalanceCents, LowestCents: p.BalanceCents, LowestDate: from,
+		}
+		alerts = append(alerts, a)
+		byID[p.ID] = a
+	}
+	breach := func(a *balanceAlert, date string, trigger *string) {
+		if a.Breached || a.ThresholdCents == nil || a.EndingCents >= *a.ThresholdCents {
+			return
+		}
+		bal := a.EndingCents
+		a.Breached, a.FirstBreachDate, a.FirstBreachCents, a.FirstBreachTrigger = true, &date, &bal, trigger
+	}
+	for _, a := range alerts {
+		breach(a, from, nil)
+	}
+	for _, e := range events {
+		a, ok := byID[e.AccountID]
+		if !ok {
+			continue
+		}
+		a.EndingCents += e.Cents
+		if a.EndingCents < a.LowestCents {
+			a.LowestCents, a.LowestDate = a.EndingCents, e.Date
+		}
+		name := e.Name
+		breach(a, e.Date, &name)
+	}
+	writeOK(w, map[string]any{"from_date": from, "to_date": to, "accounts": alerts})
+}
+
+// forecastEvents lists the balance movements dated from through to: entries
+// already recorded, unposted schedule occurrences, the interest part of
+// scheduled loan payments, which does not pay down the loan, and generated
+// card autopay payments (their statements counted from projectionStart).
+func (s *server) forecastEvents(ledgerID int64, from, to, projectionStart string) ([]balanceEvent, error) {
+	var events []balanceEvent
+	rows, err := s.db.Query(`
+		SELECT d.entry_date, d.account_id, d.name, d.delta_cents
+		FROM v_entry_delta d
+		JOIN account a ON a.id = d.account_id
+		WHERE a.ledger_id = ?
+			AND d.entry_date BETWEEN ? AND ?
+			AND d.entry_date >= a.opening_date
+	`, ledgerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e balanceEvent
+		if err := rows.Scan(&e.Date, &e.AccountID, &e.Name, &e.Cents); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Occurrences are listed from projectionStart, like the projection of the
+	// opening balances, so that payoff caps and loan interest agree with it.
+	occs := s.projectionOccurrences(ledgerID, 0, projectionStart, to)
+	list, err := occs.through(to)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range list {
+		if o.Date < from {
+			continue
+		}
+		if o.SrcAccountID != nil {
+			events = append(events, balanceEvent{o.Date, *o.SrcAccountID, o.Name, -o.AmountCents})
+		}
+		if o.DestAccountID != nil {
+			events = append(events, balanceEvent{o.Date, *o.DestAccountID, o.Name, o.DestAmount})
+		}
+	}
+
+	interest, err := scheduledLoanInterest(s.db, ledgerID, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range interest {
+		if p.Date >= from {
+			events = append(events, balanceEvent{p.Date, p.AccountID, "Interest", -p.Cents})
+		}
+	}
+
+	payments, err := s.cardAutopayments(ledgerID, projectionStart, to, occs)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		if p.Date < from {
+			continue
+		}
+		name := strings.TrimSpace(p.CardName + " payment")
+		events = append(events,
+			balanceEvent{p.Date, p.FundingID, name, -p.AmountCents},
+			balanceEvent{p.Date, p.CardID, name, p.AmountCents},
+		)
+	}
+	return events, nil
+}
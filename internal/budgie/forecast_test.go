@@ -0,0 +1,132 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestForecastAlerts(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000, "min_balance_cents": 50000,
+	})
+	checking := mustMap(t, decodeAPIResponse(t, resp).Data)
+	checkingID := mustInt64(t, checking["id"])
+	if mustInt64(t, checking["min_balance_cents"]) != 50000 {
+		t.Fatalf("expected min_balance_cents to be stored: %v", checking)
+	}
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(checkingID), map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000, "description": "Joint",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["min_balance_cents"] == nil || mustInt64(t, updated["min_balance_cents"]) != 50000 {
+		t.Fatalf("expected an edit without min_balance_cents to keep it: %v", updated)
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Savings", "opening_date": "2026-01-01", "opening_balance_cents": 10000,
+	})
+	resp.Body.Close()
+	for _, body := range []map[string]any{
+		{"name": "Salary", "kind": "I", "amount_cents": 60000, "dest_account_id": checkingID, "start_date": "2027-01-01", "freq": "M", "interval": 1},
+		{"name": "Rent", "kind": "E", "amount_cents": 80000, "src_account_id": checkingID, "start_date": "2027-01-01", "freq": "M", "interval": 1},
+	} {
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create schedule: status %d", resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+
+	alerts := func(query string) map[int64]map[string]any {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/forecast/alerts?from_date=2027-01-01&to_date=2027-03-15"+query, nil)
+		out := make(map[int64]map[string]any)
+		for _, a := range mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["accounts"]) {
+			m := mustMap(t, a)
+			out[mustInt64(t, m["account_id"])] = m
+		}
+		return out
+	}
+
+	// Salary lands before rent each month, so checking only dips below its
+	// minimum on the third payday.
+	got := alerts("")
+	if len(got) != 2 {
+		t.Fatalf("expected both accounts, got %v", got)
+	}
+	c := got[checkingID]
+	if c["first_breach_date"] != "2027-03-01" || mustInt64(t, c["first_breach_balance_cents"]) != 40000 ||
+		mustInt64(t, c["lowest_balance_cents"]) != 40000 || c["lowest_balance_date"] != "2027-03-01" ||
+		c["first_breach_trigger"] != "Rent" || mustInt64(t, c["ending_balance_cents"]) != 40000 {
+		t.Fatalf("unexpected checking alert: %v", c)
+	}
+	for id, a := range got {
+		if id != checkingID && a["breached"] != false {
+			t.Fatalf("expected savings to stay above zero: %v", a)
+		}
+	}
+
+	// Paying rent first breaches on the first day and goes lower.
+	c = alerts("&within_day=debits_first&account_id=" + fmtInt64(checkingID))[checkingID]
+	if c["first_breach_date"] != "2027-01-01" || mustInt64(t, c["first_breach_balance_cents"]) != 20000 ||
+		mustInt64(t, c["lowest_balance_cents"]) != -20000 || c["lowest_balance_date"] != "2027-03-01" {
+		t.Fatalf("unexpected debits-first alert: %v", c)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/forecast/alerts?within_day=random", nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown within_day, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestForecastAlertsLoanInterest(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-15", "opening_balance_cents": 100000,
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	loan := map[string]any{
+		"name": "Car loan", "opening_date": "2026-01-15", "opening_balance_cents": -120000, "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"loan_principal_cents": 120000, "loan_term_months": 12, "min_balance_cents": -120500,
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", loan)
+	loanID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Car payment", "kind": "T", "amount_cents": 10662, "src_account_id": checkingID, "dest_account_id": loanID,
+		"start_date": "2026-02-15", "freq": "M", "interval": 1,
+	})
+	loan["loan_payment_schedule_id"] = mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), loan)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set payment schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-15&as_of=2026-03-15", nil)
+	var projected int64
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		if row := mustMap(t, item); mustInt64(t, row["id"]) == loanID {
+			projected = mustInt64(t, row["projected_balance_cents"])
+		}
+	}
+
+	// Only the principal pays the loan down; debited first, the interest on
+	// the first payment takes the loan over its threshold.
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/forecast/alerts?from_date=2026-01-15&to_date=2026-03-15&within_day=debits_first&account_id="+fmtInt64(loanID), nil)
+	accounts := mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["accounts"])
+	if len(accounts) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(accounts))
+	}
+	a := mustMap(t, accounts[0])
+	if got := mustInt64(t, a["ending_balance_cents"]); got != projected || got != -120000+(10662-1200)+(10662-1105) {
+		t.Fatalf("ending balance = %d, projected %d", got, projected)
+	}
+	if a["breached"] != true || a["first_breach_date"] != "2026-02-15" || mustInt64(t, a["first_breach_balance_cents"]) != -121200 {
+		t.Fatalf("unexpected breach: %v", a)
+	}
+}
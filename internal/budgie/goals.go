@@ -0,0 +1,307 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goalHorizonMonths bounds how far ahead a goal's attainment date is
+// searched for.
+const goalHorizonMonths = 120
+
+type goalBody struct {
+	Name        string  `json:"name"`
+	AccountID   int64   `json:"account_id"`
+	ShareBps    *int64  `json:"share_bps"`
+	TargetCents int64   `json:"target_cents"`
+	TargetDate  *string `json:"target_date"`
+	Description *string `json:"description"`
+}
+
+func (s *server) validateGoal(ledgerID int64, b *goalBody) *apiErr {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return badRequest("name is required", nil)
+	}
+	if b.AccountID == 0 {
+		return badRequest("account_id is required", nil)
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, &b.AccountID); e != nil {
+		return e
+	}
+	if b.ShareBps == nil {
+		v := int64(10000)
+		b.ShareBps = &v
+	}
+	if *b.ShareBps < 1 || *b.ShareBps > 10000 {
+		return badRequest("share_bps must be 1..10000", nil)
+	}
+	if b.TargetCents <= 0 {
+		return badRequest("target_cents must be > 0", nil)
+	}
+	var e *apiErr
+	if b.TargetDate, e = optionalDate(b.TargetDate, "target_date"); e != nil {
+		return e
+	}
+	return nil
+}
+
+// goals serves GET/POST /api/goals.
+func (s *server) goals(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query(`
+			SELECT g.*, a.name AS account_name, a.currency
+			FROM goal g
+			JOIN account a ON a.id = g.account_id
+			WHERE g.ledger_id = ?
+			ORDER BY g.target_date IS NULL, g.target_date, g.name
+		`, ledgerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query goals", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read goals", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body goalBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateGoal(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO goal (ledger_id, name, account_id, share_bps, target_cents, target_date, description) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			ledgerID, body.Name, body.AccountID, body.ShareBps, body.TargetCents, body.TargetDate, body.Description,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create goal", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "goal", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// goalByID serves PUT/DELETE /api/goals/{id} and GET /api/goals/{id}/progress.
+func (s *server) goalByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/goals/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "progress") {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.goalProgress(w, r, ledgerID, id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body goalBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateGoal(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"UPDATE goal SET name=?, account_id=?, share_bps=?, target_cents=?, target_date=?, description=? WHERE id=? AND ledger_id=?",
+			body.Name, body.AccountID, body.ShareBps, body.TargetCents, body.TargetDate, body.Description, id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not update goal", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("goal not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "goal", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM goal WHERE id = ? AND ledger_id = ?", id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete goal", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("goal not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// goalAmount is the part of an account balance that counts towards a goal.
+func goalAmount(balanceCents, shareBps int64) int64 {
+	return balanceCents * shareBps / 10000
+}
+
+func balanceOf(points []balancePoint, accountID int64) int64 {
+	for _, p := range points {
+		if p.ID == accountID {
+			return p.BalanceCents
+		}
+	}
+	return 0
+}
+
+// goalProgress reports a goal's progress as of as_of (default today) from
+// actual balances, the first date projected balances meet the target, and,
+// for a goal with a target date it will miss, the extra monthly contribution
+// to the account that meets it. Contributions count towards the goal at its
+// share of the account.
+//
+//	GET /api/goals/{id}/progress?as_of=2026-03-01
+func (s *server) goalProgress(w http.ResponseWriter, r *http.Request, ledgerID, id int64) {
+	asOf := r.URL.Query().Get("as_of")
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	}
+	if _, e := requireDate(asOf, "as_of"); e != nil {
+		writeErr(w, e)
+		return
+	}
+	asOfT, _ := time.Parse("2006-01-02", asOf)
+
+	var g goalBody
+	err := s.db.QueryRow(
+		"SELECT name, account_id, share_bps, target_cents, target_date FROM goal WHERE id = ? AND ledger_id = ?", id, ledgerID,
+	).Scan(&g.Name, &g.AccountID, &g.ShareBps, &g.TargetCents, &g.TargetDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("goal not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read goal", err))
+		return
+	}
+	share := *g.ShareBps
+
+	actual, err := s.actualBalancesAsOf(ledgerID, asOf)
+	if err != nil {
+		writeErr(w, serverError("failed to compute balances", err))
+		return
+	}
+	current := goalAmount(balanceOf(actual, g.AccountID), share)
+
+	projectedAt := func(d time.Time) (int64, error) {
+		pts, err := s.projectedBalancesAsOf(ledgerID, asOf, d.Format("2006-01-02"))
+		if err != nil {
+			return 0, err
+		}
+		return goalAmount(balanceOf(pts, g.AccountID), share), nil
+	}
+
+	// Walk the account's projected balance a day at a time, as forecasts do,
+	// and take the first day that closes at or above the target.
+	var projectedDate *string
+	if current >= g.TargetCents {
+		projectedDate = &asOf
+	} else {
+		opening, err := s.projectedBalancesAsOf(ledgerID, asOf, asOf)
+		if err != nil {
+			writeErr(w, serverError("failed to compute projected balances", err))
+			return
+		}
+		horizon := addMonthsClamped(asOfT, goalHorizonMonths).Format("2006-01-02")
+		events, err := s.forecastEvents(ledgerID, asOfT.AddDate(0, 0, 1).Format("2006-01-02"), horizon, asOf)
+		if err != nil {
+			writeErr(w, serverError("failed to read projected movements", err))
+			return
+		}
+		orderBalanceEvents(events, false)
+		balance, day := balanceOf(opening, g.AccountID), asOf
+		for _, e := range events {
+			if e.AccountID != g.AccountID {
+				continue
+			}
+			if e.Date != day && goalAmount(balance, share) >= g.TargetCents {
+				break
+			}
+			balance += e.Cents
+			day = e.Date
+		}
+		if goalAmount(balance, share) >= g.TargetCents {
+			projectedDate = &day
+		}
+	}
+
+	out := map[string]any{
+		"goal_id":                          id,
+		"name":                             g.Name,
+		"account_id":                       g.AccountID,
+		"share_bps":                        share,
+		"target_cents":                     g.TargetCents,
+		"target_date":                      g.TargetDate,
+		"as_of":                            asOf,
+		"current_cents":                    current,
+		"remaining_cents":                  max(g.TargetCents-current, 0),
+		"progress_bps":                     min(current*10000/g.TargetCents, 10000),
+		"projected_date":                   projectedDate,
+		"on_track":                         nil,
+		"projected_at_target_date_cents":   nil,
+		"extra_monthly_contribution_cents": nil,
+	}
+	if g.TargetDate != nil && *g.TargetDate > asOf {
+		targetT, _ := time.Parse("2006-01-02", *g.TargetDate)
+		atTarget, err := projectedAt(targetT)
+		if err != nil {
+			writeErr(w, serverError("failed to compute projected balances", err))
+			return
+		}
+		// Contributions land monthly from a month after as_of up to the
+		// target date; a target less than a month away gets one.
+		months := int64(0)
+		for addMonthsClamped(asOfT, int(months)+1).Format("2006-01-02") <= *g.TargetDate {
+			months++
+		}
+		months = max(months, 1)
+		extra := int64(0)
+		if shortfall := g.TargetCents - atTarget; shortfall > 0 {
+			perMonth := (shortfall + months - 1) / months
+			extra = (perMonth*10000 + share - 1) / share
+		}
+		out["projected_at_target_date_cents"] = atTarget
+		out["on_track"] = atTarget >= g.TargetCents
+		out["extra_monthly_contribution_cents"] = extra
+	}
+	writeOK(w, out)
+}
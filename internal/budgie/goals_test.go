@@ -0,0 +1,97 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGoalProgress(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Savings", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	savingsID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Transfer in", "kind": "I", "amount_cents": 10000, "dest_account_id": savingsID,
+		"start_date": "2026-02-01", "freq": "M", "interval": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/goals", map[string]any{
+		"name": "Car", "account_id": savingsID, "target_cents": 0,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a zero target, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/goals", map[string]any{
+		"name": "Car", "account_id": savingsID, "target_cents": 150000, "target_date": "2026-04-15",
+	})
+	created := mustMap(t, decodeAPIResponse(t, resp).Data)
+	goalID := mustInt64(t, created["id"])
+	if mustInt64(t, created["share_bps"]) != 10000 {
+		t.Fatalf("expected the whole account by default, got %v", created["share_bps"])
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/goals/"+fmtInt64(goalID)+"/progress?as_of=2026-01-15", nil)
+	progress := mustMap(t, decodeAPIResponse(t, resp).Data)
+	// Deposits land Feb 1 to Jun 1; three by the target date leave 20000
+	// short over three monthly contributions.
+	if mustInt64(t, progress["current_cents"]) != 100000 || mustInt64(t, progress["progress_bps"]) != 6666 ||
+		progress["projected_date"] != "2026-06-01" || progress["on_track"] != false ||
+		mustInt64(t, progress["projected_at_target_date_cents"]) != 130000 ||
+		mustInt64(t, progress["extra_monthly_contribution_cents"]) != 6667 {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+
+	// Half the account already covers a smaller goal.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/goals/"+fmtInt64(goalID), map[string]any{
+		"name": "Car", "account_id": savingsID, "share_bps": 5000, "target_cents": 50000,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update goal: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/goals/"+fmtInt64(goalID)+"/progress?as_of=2026-01-15", nil)
+	progress = mustMap(t, decodeAPIResponse(t, resp).Data)
+	if mustInt64(t, progress["current_cents"]) != 50000 || mustInt64(t, progress["progress_bps"]) != 10000 ||
+		progress["projected_date"] != "2026-01-15" || progress["extra_monthly_contribution_cents"] != nil {
+		t.Fatalf("unexpected progress for a partial share: %v", progress)
+	}
+
+	// A bonus spent three days later still meets the target for a day.
+	for _, entry := range []map[string]any{
+		{"entry_date": "2026-03-05", "name": "Bonus", "amount_cents": 30000, "dest_account_id": savingsID},
+		{"entry_date": "2026-03-08", "name": "Repairs", "amount_cents": 30000, "src_account_id": savingsID},
+	} {
+		resp = doJSON(t, http.MethodPost, api.URL+"/api/entries", entry)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create entry: status %d", resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/goals/"+fmtInt64(goalID), map[string]any{
+		"name": "Car", "account_id": savingsID, "target_cents": 125000,
+	})
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/goals/"+fmtInt64(goalID)+"/progress?as_of=2026-01-15", nil)
+	if progress = mustMap(t, decodeAPIResponse(t, resp).Data); progress["projected_date"] != "2026-03-05" {
+		t.Fatalf("expected the bonus day, got %v", progress["projected_date"])
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/goals", nil)
+	if goals := mustList(t, decodeAPIResponse(t, resp).Data); len(goals) != 1 || mustMap(t, goals[0])["account_name"] != "Savings" {
+		t.Fatalf("unexpected goal list: %v", goals)
+	}
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/goals/"+fmtInt64(goalID), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete goal: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
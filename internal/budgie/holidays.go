@@ -0,0 +1,398 @@
+package budgie
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Holidays are non-business days used by schedules with a business_day_roll.
+// A schedule with a holiday_calendar only observes holidays from that
+// calendar; one without observes every holiday in the ledger.
+
+const (
+	defaultHolidayCalendar = "default"
+	// holidayRecurYears bounds the expansion of recurring iCalendar events.
+	holidayRecurYears = 10
+	// maxHolidayEventDays bounds a single multi-day iCalendar event.
+	maxHolidayEventDays = 31
+)
+
+type holidayBody struct {
+	Calendar    string  `json:"calendar"`
+	HolidayDate string  `json:"holiday_date"`
+	Name        *string `json:"name"`
+}
+
+func validateHoliday(b *holidayBody) *apiErr {
+	b.Calendar = strings.TrimSpace(b.Calendar)
+	if b.Calendar == "" {
+		b.Calendar = defaultHolidayCalendar
+	}
+	if _, e := requireDate(b.HolidayDate, "holiday_date"); e != nil {
+		return e
+	}
+	if b.Name != nil {
+		v := strings.TrimSpace(*b.Name)
+		if v == "" {
+			b.Name = nil
+		} else {
+			b.Name = &v
+		}
+	}
+	return nil
+}
+
+// upsertHoliday stores a holiday, renaming any holiday already recorded for
+// the same calendar and date.
+func upsertHoliday(q rowQuerier, ledgerID int64, b holidayBody) (int64, error) {
+	var id int64
+	err := q.QueryRow(`
+		INSERT INTO holiday (ledger_id, calendar, holiday_date, name)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (ledger_id, calendar, holiday_date) DO UPDATE SET name = excluded.name
+		RETURNING id
+	`, ledgerID, b.Calendar, b.HolidayDate, b.Name).Scan(&id)
+	return id, err
+}
+
+// holidays serves GET/POST /api/holidays. GET accepts optional calendar,
+// from_date and to_date filters; POST creates or renames the holiday for a
+// calendar and date.
+func (s *server) holidays(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := "SELECT * FROM holiday WHERE ledger_id = ?"
+		args := []any{ledgerID}
+		if v := strings.TrimSpace(r.URL.Query().Get("calendar")); v != "" {
+			q += " AND calendar = ?"
+			args = append(args, v)
+		}
+		for _, f := range []struct{ param, op string }{{"from_date", ">="}, {"to_date", "<="}} {
+			v := r.URL.Query().Get(f.param)
+			if v == "" {
+				continue
+			}
+			if _, e := requireDate(v, f.param); e != nil {
+				writeErr(w, e)
+				return
+			}
+			q += " AND holiday_date " + f.op + " ?"
+			args = append(args, v)
+		}
+		rows, err := s.db.Query(q+" ORDER BY holiday_date, calendar", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query holidays", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read holidays", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body holidayBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateHoliday(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		id, err := upsertHoliday(s.db, ledgerID, body)
+		if err != nil {
+			writeErr(w, serverError("failed to save holiday", err))
+			return
+		}
+		created, apiE := scanRowToMap(s.db, "holiday", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) holidayByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/holidays/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body holidayBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateHoliday(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"UPDATE holiday SET calendar=?, holiday_date=?, name=? WHERE id=? AND ledger_id=?",
+			body.Calendar, body.HolidayDate, body.Name, id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not update holiday (one already exists for this calendar and date?)", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("holiday not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "holiday", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM holiday WHERE id = ? AND ledger_id = ?", id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete holiday", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("holiday not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// holidaysImport loads holidays into the calendar named by the calendar query
+// parameter (default "default"). The body is either an iCalendar file
+// (Content-Type text/calendar, or starting with BEGIN:VCALENDAR) or a CSV
+// with the header date,name. Existing holidays on the same dates are renamed.
+// The import is all-or-nothing.
+//
+//	POST /api/holidays/import?calendar=us-federal
+func (s *server) holidaysImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+	if err != nil {
+		writeErr(w, badRequest("failed to read request body", nil))
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var list []holidayBody
+	var e *apiErr
+	if mediaType == "text/calendar" || bytes.HasPrefix(bytes.TrimSpace(raw), []byte("BEGIN:VCALENDAR")) {
+		list, e = parseHolidayICS(bytes.NewReader(raw))
+	} else {
+		list, e = parseHolidayCSV(bytes.NewReader(raw))
+	}
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	calendar := r.URL.Query().Get("calendar")
+	for i := range list {
+		list[i].Calendar = calendar
+		if e := validateHoliday(&list[i]); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+	for _, b := range list {
+		if _, err := upsertHoliday(tx, ledgerID, b); err != nil {
+			writeErr(w, serverError("failed to save holiday", err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeErr(w, serverError("failed to import holidays", err))
+		return
+	}
+	writeOK(w, map[string]any{"imported": len(list)})
+}
+
+func parseHolidayCSV(src io.Reader) ([]holidayBody, *apiErr) {
+	cr := csv.NewReader(src)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, badRequest("CSV must start with a header row: date,name", nil)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["date"]; !ok {
+		return nil, badRequest("CSV header is missing column date", nil)
+	}
+
+	var out []holidayBody
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, badRequest("invalid CSV", map[string]any{"line": line, "error": err.Error()})
+		}
+		if col["date"] >= len(rec) {
+			return nil, badRequest("row is missing the date column", map[string]any{"line": line})
+		}
+		b := holidayBody{HolidayDate: strings.TrimSpace(rec[col["date"]])}
+		if i, ok := col["name"]; ok && i < len(rec) {
+			name := rec[i]
+			b.Name = &name
+		}
+		if e := validateHoliday(&b); e != nil {
+			e.Details = map[string]any{"line": line}
+			return nil, e
+		}
+		out = append(out, b)
+	}
+	if len(out) == 0 {
+		return nil, badRequest("CSV contains no holidays", nil)
+	}
+	return out, nil
+}
+
+// parseHolidayICS reads the all-day VEVENTs of an iCalendar file. Each event
+// contributes every day from DTSTART up to (excluding) DTEND, and recurring
+// events are expanded with their RRULE for holidayRecurYears.
+func parseHolidayICS(src io.Reader) ([]holidayBody, *apiErr) {
+	// Unfold continuation lines (RFC 5545 3.1) first.
+	var lines []string
+	sc := bufio.NewScanner(src)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, badRequest("invalid iCalendar file", map[string]any{"error": err.Error()})
+	}
+
+	var out []holidayBody
+	var start, end, rule, summary string
+	inEvent := false
+	for n, l := range lines {
+		line := n + 1
+		name, value, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		prop, _, _ := strings.Cut(strings.ToUpper(name), ";")
+		switch {
+		case prop == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent = true
+			start, end, rule, summary = "", "", "", ""
+		case !inEvent:
+		case prop == "DTSTART":
+			start = value
+		case prop == "DTEND":
+			end = value
+		case prop == "RRULE":
+			rule = value
+		case prop == "SUMMARY":
+			summary = icsUnescape(value)
+		case prop == "END" && strings.EqualFold(value, "VEVENT"):
+			inEvent = false
+			days, e := icsEventDays(start, end, rule)
+			if e != nil {
+				e.Details = map[string]any{"line": line}
+				return nil, e
+			}
+			for _, d := range days {
+				b := holidayBody{HolidayDate: d}
+				if summary != "" {
+					name := summary
+					b.Name = &name
+				}
+				out = append(out, b)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, badRequest("iCalendar file contains no events", nil)
+	}
+	return out, nil
+}
+
+// icsEventDays lists the days covered by one event.
+func icsEventDays(start, end, rule string) ([]string, *apiErr) {
+	first, ok := icsDate(start)
+	if !ok {
+		return nil, badRequest("event has a missing or invalid DTSTART", nil)
+	}
+	length := 1
+	if end != "" {
+		last, ok := icsDate(end)
+		if !ok {
+			return nil, badRequest("event has an invalid DTEND", nil)
+		}
+		if d := int(last.Sub(first).Hours() / 24); d > 1 {
+			length = min(d, maxHolidayEventDays)
+		}
+	}
+	starts := []time.Time{first}
+	if rule != "" {
+		rr, err := parseRRule(rule)
+		if err != nil {
+			return nil, badRequest("event has an unsupported RRULE: "+err.Error(), nil)
+		}
+		starts = rr.expand(first, first.AddDate(holidayRecurYears, 0, -1), maxRRuleDates)
+	}
+	var out []string
+	for _, s := range starts {
+		for i := 0; i < length; i++ {
+			out = append(out, s.AddDate(0, 0, i).Format("2006-01-02"))
+		}
+	}
+	return out, nil
+}
+
+// icsDate reads the date part of a DATE or DATE-TIME value.
+func icsDate(v string) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 8 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("20060102", v[:8])
+	return t, err == nil
+}
+
+func icsUnescape(v string) string {
+	return strings.TrimSpace(strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(v))
+}
@@ -0,0 +1,143 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHolidayICS(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20260101\r\n" +
+		"SUMMARY:New Year\\, Day\r\n" +
+		"RRULE:FREQ=YEARLY\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20261224\r\n" +
+		"DTEND;VALUE=DATE:20261227\r\n" +
+		"SUMMARY:Winter \r\n" +
+		" break\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	list, e := parseHolidayICS(strings.NewReader(ics))
+	if e != nil {
+		t.Fatalf("parse: %v", e)
+	}
+	if len(list) != 13 {
+		t.Fatalf("expected 10 yearly + 3 multi-day holidays, got %d", len(list))
+	}
+	if list[0].HolidayDate != "2026-01-01" || *list[0].Name != "New Year, Day" || list[9].HolidayDate != "2035-01-01" {
+		t.Fatalf("unexpected recurring holidays: %+v %+v", list[0], list[9])
+	}
+	var dates []string
+	for _, b := range list[10:] {
+		dates = append(dates, b.HolidayDate)
+	}
+	if want := []string{"2026-12-24", "2026-12-25", "2026-12-26"}; !reflect.DeepEqual(dates, want) || *list[10].Name != "Winter break" {
+		t.Fatalf("multi-day event = %v (%q), want %v", dates, *list[10].Name, want)
+	}
+
+	if _, e := parseHolidayICS(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")); e == nil {
+		t.Fatalf("expected an event without DTSTART to be rejected")
+	}
+}
+
+func TestBusinessDayRoll(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(0),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	resp, err := http.Post(api.URL+"/api/holidays/import?calendar=bank", "text/csv", strings.NewReader("date,name\n2026-01-01,New Year\n2026-03-02,Bank Day\n"))
+	if err != nil {
+		t.Fatalf("import holidays: %v", err)
+	}
+	if imported := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["imported"]); imported != 2 {
+		t.Fatalf("expected 2 imported holidays, got %d", imported)
+	}
+
+	createSchedule := func(payload map[string]any) int64 {
+		t.Helper()
+		payload["kind"] = "I"
+		payload["dest_account_id"] = acctID
+		payload["start_date"] = "2026-01-01"
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", payload)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create schedule %v: status %d", payload["name"], resp.StatusCode)
+		}
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	payID := createSchedule(map[string]any{
+		"name": "Pay", "amount_cents": 100, "freq": "M", "interval": 1, "business_day_roll": "following",
+	})
+	createSchedule(map[string]any{
+		"name": "Bonus", "amount_cents": 10, "rrule": "FREQ=MONTHLY;BYMONTHDAY=-1", "business_day_roll": "modified_following",
+		"holiday_calendar": "other",
+	})
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Bad", "kind": "I", "amount_cents": 1, "dest_account_id": acctID, "start_date": "2026-01-01",
+		"freq": "M", "interval": 1, "business_day_roll": "nearest",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown roll convention, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// An edit that leaves the roll out, like the schedule editor's, keeps it.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(payID), map[string]any{
+		"name": "Pay", "kind": "I", "amount_cents": 100, "dest_account_id": acctID, "start_date": "2026-01-01",
+		"freq": "M", "interval": 1, "description": "Salary",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["business_day_roll"] != "following" {
+		t.Fatalf("business_day_roll after edit = %v", updated["business_day_roll"])
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-01&to_date=2026-03-31", nil)
+	var got []string
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		got = append(got, row["name"].(string)+" "+row["occ_date"].(string)+" "+row["original_date"].(string))
+	}
+	want := []string{
+		// New Year's Day is a holiday; Feb 1 is a Sunday; Mar 1 is a Sunday
+		// followed by a holiday.
+		"Pay 2026-01-02 2026-01-01",
+		"Bonus 2026-01-30 2026-01-31",
+		"Pay 2026-02-02 2026-02-01",
+		"Bonus 2026-02-27 2026-02-28",
+		"Pay 2026-03-03 2026-03-01",
+		// The bank calendar's Mar 2 holiday does not apply to Bonus.
+		"Bonus 2026-03-31 2026-03-31",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-03-02", nil)
+	row := mustMap(t, mustList(t, decodeAPIResponse(t, resp).Data)[0])
+	if got := mustInt64(t, row["projected_balance_cents"]); got != 220 {
+		t.Fatalf("expected projected balance 220, got %d", got)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": payID, "occurrence_date": "2026-03-01",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post rolled occurrence: status %d", resp.StatusCode)
+	}
+	entry := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if entry["entry_date"] != "2026-03-03" || entry["occurrence_date"] != "2026-03-01" {
+		t.Fatalf("unexpected posted entry: %v", entry)
+	}
+}
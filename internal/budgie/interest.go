@@ -0,0 +1,286 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Interest posting turns accrued interest into real entries so actual
+// balances follow the bank's. An account's interest_posting decides when:
+//   none       never (the default)
+//   monthly    on interest_posting_day of each month (clamped to month end)
+//   statement  on each credit card statement closing date
+// Each posted entry is named "Interest" and carries the period it covers in
+// interest_period_start/interest_period_end, which also marks it as generated.
+// A period is posted at most once.
+
+type interestPostingFields struct {
+	Posting    string `json:"interest_posting"`
+	PostingDay *int64 `json:"interest_posting_day"`
+}
+
+func validateInterestPosting(isInterestBearing int64, card *cardFields, f *interestPostingFields) *apiErr {
+	switch f.Posting {
+	case "", "none":
+		f.Posting = "none"
+		f.PostingDay = nil
+		return nil
+	case "monthly":
+		if f.PostingDay == nil {
+			v := int64(31)
+			f.PostingDay = &v
+		}
+		if *f.PostingDay < 1 || *f.PostingDay > 31 {
+			return badRequest("interest_posting_day must be 1..31", nil)
+		}
+	case "statement":
+		if card.ClosingDay == nil {
+			return badRequest("interest_posting 'statement' is only for credit cards", nil)
+		}
+		f.PostingDay = nil
+	default:
+		return badRequest("interest_posting must be 'none', 'monthly' or 'statement'", nil)
+	}
+	if isInterestBearing != 1 {
+		return badRequest("interest_posting requires is_interest_bearing=1", nil)
+	}
+	return nil
+}
+
+type interestAccount struct {
+	LedgerID   int64
+	ID         int64
+	Posting    string
+	PostingDay int
+	LastPosted string
+	card       creditCard
+}
+
+// postingDates lists the posting dates after the last posted period (or the
+// opening date) up to through.
+func (a interestAccount) postingDates(through string) []time.Time {
+	last, err := time.Parse("2006-01-02", a.LastPosted)
+	if err != nil {
+		return nil
+	}
+	var out []time.Time
+	for m := time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC); ; m = m.AddDate(0, 1, 0) {
+		var d time.Time
+		if a.Posting == "statement" {
+			d = a.card.closingDate(m.Year(), m.Month())
+		} else {
+			day := a.PostingDay
+			if lastDay := m.AddDate(0, 1, -1).Day(); day > lastDay {
+				day = lastDay
+			}
+			d = m.AddDate(0, 0, day-1)
+		}
+		ds := d.Format("2006-01-02")
+		if ds > through {
+			return out
+		}
+		if ds > a.LastPosted {
+			out = append(out, d)
+		}
+	}
+}
+
+// accruedInterestCents is the interest earned (positive) or charged
+// (negative) between from and to. The balance is followed day by day through
+// the period's entries, each stretch accruing with interestForPeriod.
+func accruedInterestCents(act *accountActivity, aprBps int64, compound string, from, to time.Time) int64 {
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	balance := act.balanceAt(fromDate)
+	segStart := from
+	var total float64
+	for _, d := range act.deltas {
+		if d.Date <= fromDate {
+			continue
+		}
+		if d.Date >= toDate {
+			break
+		}
+		day, err := time.Parse("2006-01-02", d.Date)
+		if err != nil {
+			continue
+		}
+		total += interestForPeriod(balance, aprBps, compound, segStart, day)
+		balance += d.Cents
+		segStart = day
+	}
+	total += interestForPeriod(balance, aprBps, compound, segStart, to)
+	return int64(math.Round(total))
+}
+
+// postAccruedInterest posts interest for every account with interest posting
+// enabled, in one ledger or (ledgerID 0) all of them, for periods ending on or
+// before through. It returns the ids of the created entries.
+func (s *server) postAccruedInterest(ledgerID int64, accountID *int64, through string) ([]int64, error) {
+	q := `
+		SELECT a.ledger_id, a.interest_posting, COALESCE(a.interest_posting_day, 31),
+		       COALESCE((SELECT MAX(e.interest_period_end) FROM entry e
+		                 WHERE e.interest_period_end IS NOT NULL AND (e.src_account_id = a.id OR e.dest_account_id = a.id)), a.opening_date),
+		       ` + creditCardColumns + `
+		FROM account a
+		WHERE a.interest_posting != 'none'
+		  AND a.is_interest_bearing = 1
+		  AND a.archived_at IS NULL`
+	var args []any
+	if ledgerID != 0 {
+		q += " AND a.ledger_id = ?"
+		args = append(args, ledgerID)
+	}
+	if accountID != nil {
+		q += " AND a.id = ?"
+		args = append(args, *accountID)
+	}
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []interestAccount
+	for rows.Next() {
+		var a interestAccount
+		var closingDay, graceDays, dueOffset, minCents, minBps, minPlus sql.NullInt64
+		c := &a.card
+		if err := rows.Scan(&a.LedgerID, &a.Posting, &a.PostingDay, &a.LastPosted,
+			&c.ID, &c.Name, &c.OpeningDate, &c.OpeningBalance, &c.AprBps, &c.Compound, &closingDay, &graceDays, &dueOffset,
+			&minCents, &minBps, &minPlus, &c.Autopay, &c.AutopayAccountID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.ID = c.ID
+		c.ClosingDay, c.GraceDays, c.DueOffsetDays = int(closingDay.Int64), int(graceDays.Int64), int(dueOffset.Int64)
+		c.MinPaymentCents, c.MinPaymentBps, c.MinPaymentPlus = minCents.Int64, minBps.Int64, minPlus.Int64 == 1
+		if a.Posting == "statement" && !closingDay.Valid {
+			continue
+		}
+		accounts = append(accounts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var created []int64
+	for _, a := range accounts {
+		dates := a.postingDates(through)
+		if len(dates) == 0 {
+			continue
+		}
+		act, err := s.loadAccountActivity(a.ID, a.card.OpeningDate, a.card.OpeningBalance, through)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		prev, _ := time.Parse("2006-01-02", a.LastPosted)
+		for _, end := range dates {
+			var cents int64
+			if a.Posting == "statement" {
+				// Card interest follows the statement's grace rules.
+				cents = -a.card.statement(act, end).InterestCents
+			} else {
+				cents = accruedInterestCents(act, a.card.AprBps, a.card.Compound, prev, end)
+			}
+			start, endDate := prev.Format("2006-01-02"), end.Format("2006-01-02")
+			prev = end
+			if cents == 0 {
+				continue
+			}
+			var src, dest *int64
+			amount := cents
+			if cents < 0 {
+				src, amount = &a.ID, -cents
+			} else {
+				dest = &a.ID
+			}
+			var id int64
+			err := tx.QueryRow(`
+				INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description, interest_period_start, interest_period_end)
+				VALUES (?, ?, 'Interest', ?, ?, ?, ?, ?, ?)
+				ON CONFLICT DO NOTHING
+				RETURNING id
+			`, a.LedgerID, endDate, amount, src, dest, fmt.Sprintf("Interest %s to %s", start, endDate), start, endDate).Scan(&id)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			// Later periods accrue on the balance including this entry.
+			act.add(datedCents{endDate, cents})
+			created = append(created, id)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// PostAccruedInterest posts interest due up to today in every ledger. It is
+// safe to run repeatedly.
+func PostAccruedInterest(db *sql.DB) error {
+	_, err := (&server{db: db}).postAccruedInterest(0, nil, time.Now().Format("2006-01-02"))
+	return err
+}
+
+// interestPost serves POST /api/interest/post, posting interest for periods
+// ending on or before through_date (default today), optionally for a single
+// account.
+//
+//	POST /api/interest/post
+//	{"through_date": "2026-03-31", "account_id": 3}
+func (s *server) interestPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var body struct {
+		ThroughDate *string `json:"through_date"`
+		AccountID   *int64  `json:"account_id"`
+	}
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+	through := time.Now().Format("2006-01-02")
+	if body.ThroughDate != nil && strings.TrimSpace(*body.ThroughDate) != "" {
+		if _, e := requireDate(*body.ThroughDate, "through_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		through = *body.ThroughDate
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, body.AccountID); e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	ids, err := s.postAccruedInterest(ledgerID, body.AccountID, through)
+	if err != nil {
+		writeErr(w, serverError("failed to post interest", err))
+		return
+	}
+	entries := []map[string]any{}
+	for _, id := range ids {
+		e, apiE := scanRowToMap(s.db, "entry", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		entries = append(entries, e)
+	}
+	writeOK(w, map[string]any{"through_date": through, "entries": entries})
+}
@@ -0,0 +1,93 @@
+package budgie
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPostAccruedInterest(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Savings", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"interest_posting": "statement",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for statement posting on a non-card account, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Savings", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"interest_posting": "monthly",
+	})
+	created := mustMap(t, decodeAPIResponse(t, resp).Data)
+	savingsID := mustInt64(t, created["id"])
+	if mustInt64(t, created["interest_posting_day"]) != 31 {
+		t.Fatalf("expected posting day to default to month end, got %v", created["interest_posting_day"])
+	}
+	// An edit that leaves the posting settings out keeps them.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(savingsID), map[string]any{
+		"name": "High-yield savings", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["interest_posting"] != "monthly" || mustInt64(t, updated["interest_posting_day"]) != 31 {
+		t.Fatalf("posting settings after edit = %v", updated)
+	}
+	if _, err := db.Exec("INSERT INTO entry (entry_date, name, amount_cents, dest_account_id) VALUES (?, ?, ?, ?)", "2026-02-10", "Deposit", int64(50000), savingsID); err != nil {
+		t.Fatalf("insert deposit: %v", err)
+	}
+
+	post := func() []any {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/interest/post", map[string]any{"through_date": "2026-03-15"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("post interest: status %d", resp.StatusCode)
+		}
+		return mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["entries"])
+	}
+	entries := post()
+	if len(entries) != 2 {
+		t.Fatalf("expected January and February interest, got %d entries", len(entries))
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	jan := interestForPeriodCents(100000, 1200, "M", day("2026-01-01"), day("2026-01-31"))
+	feb := int64(math.Round(
+		interestForPeriod(100000+jan, 1200, "M", day("2026-01-31"), day("2026-02-10")) +
+			interestForPeriod(150000+jan, 1200, "M", day("2026-02-10"), day("2026-02-28")),
+	))
+	first, second := mustMap(t, entries[0]), mustMap(t, entries[1])
+	if first["entry_date"] != "2026-01-31" || first["name"] != "Interest" || mustInt64(t, first["amount_cents"]) != jan ||
+		mustInt64(t, first["dest_account_id"]) != savingsID || first["interest_period_start"] != "2026-01-01" {
+		t.Fatalf("unexpected January interest entry: %v", first)
+	}
+	if second["entry_date"] != "2026-02-28" || mustInt64(t, second["amount_cents"]) != feb || second["interest_period_start"] != "2026-01-31" {
+		t.Fatalf("unexpected February interest entry (want %d): %v", feb, second)
+	}
+
+	if again := post(); len(again) != 0 {
+		t.Fatalf("expected reposting to be a no-op, got %d entries", len(again))
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?as_of=2026-03-15", nil)
+	row := mustMap(t, mustList(t, decodeAPIResponse(t, resp).Data)[0])
+	if got := mustInt64(t, row["balance_cents"]); got != 150000+jan+feb {
+		t.Fatalf("balance = %d, want %d", got, 150000+jan+feb)
+	}
+
+	// Posted periods are not accrued again by the series.
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances/series?mode=projected&from_date=2026-02-28&to_date=2026-02-28&include_interest=1", nil)
+	acct := mustMap(t, mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["accounts"])[0])
+	if got := mustInt64(t, mustList(t, acct["balance_cents"])[0]); got != 150000+jan+feb {
+		t.Fatalf("series balance with interest = %d, want %d", got, 150000+jan+feb)
+	}
+}
@@ -0,0 +1,972 @@
+package budgie
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// quantityEpsilon absorbs float noise when summing fractional share counts.
+const quantityEpsilon = 1e-9
+
+// --- Securities ---
+
+type securityBody struct {
+	Symbol   string  `json:"symbol"`
+	Name     *string `json:"name"`
+	Currency string  `json:"currency"`
+}
+
+func validateSecurity(b *securityBody) *apiErr {
+	b.Symbol = strings.ToUpper(strings.TrimSpace(b.Symbol))
+	if b.Symbol == "" {
+		return badRequest("symbol is required", nil)
+	}
+	if strings.TrimSpace(b.Currency) == "" {
+		b.Currency = "USD"
+	}
+	c, e := requireCurrency(b.Currency, "currency")
+	if e != nil {
+		return e
+	}
+	b.Currency = c
+	return nil
+}
+
+func (s *server) securities(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query(`
+			SELECT sec.*,
+			       (SELECT sp.price_cents FROM security_price sp WHERE sp.security_id = sec.id ORDER BY sp.price_date DESC LIMIT 1) AS last_price_cents,
+			       (SELECT MAX(sp.price_date) FROM security_price sp WHERE sp.security_id = sec.id) AS last_price_date
+			FROM security sec
+			WHERE sec.ledger_id = ?
+			ORDER BY sec.symbol
+		`, ledgerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query securities", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read securities", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body securityBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateSecurity(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO security (ledger_id, symbol, name, currency) VALUES (?, ?, ?, ?)",
+			ledgerID, body.Symbol, body.Name, body.Currency,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create security (symbol already used?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "security", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) securityByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/securities/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body securityBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateSecurity(&body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		var held int
+		if err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM investment_txn t
+			JOIN account a ON a.id = t.account_id
+			WHERE t.security_id = ? AND a.currency != ?
+		`, id, body.Currency).Scan(&held); err != nil {
+			writeErr(w, serverError("failed to check holdings", err))
+			return
+		}
+		if held > 0 {
+			writeErr(w, badRequest("currency must match the accounts holding this security", nil))
+			return
+		}
+		res, err := s.db.Exec(
+			"UPDATE security SET symbol=?, name=?, currency=? WHERE id=? AND ledger_id=?",
+			body.Symbol, body.Name, body.Currency, id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not update security (symbol already used?)", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("security not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "security", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM security WHERE id = ? AND ledger_id = ?", id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete security (it has transactions?)", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("security not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Prices ---
+
+type securityPriceBody struct {
+	SecurityID int64  `json:"security_id"`
+	PriceDate  string `json:"price_date"`
+	PriceCents int64  `json:"price_cents"`
+}
+
+// upsertSecurityPrice stores a price, replacing any price already recorded for
+// the same security and date.
+func upsertSecurityPrice(q rowQuerier, b securityPriceBody) (int64, error) {
+	var id int64
+	err := q.QueryRow(`
+		INSERT INTO security_price (security_id, price_date, price_cents)
+		VALUES (?, ?, ?)
+		ON CONFLICT (security_id, price_date) DO UPDATE SET price_cents = excluded.price_cents
+		RETURNING id
+	`, b.SecurityID, b.PriceDate, b.PriceCents).Scan(&id)
+	return id, err
+}
+
+// securityPrices serves GET/POST /api/security-prices. GET accepts an optional
+// security_id filter; POST creates or replaces the price for a date.
+func (s *server) securityPrices(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := `
+			SELECT sp.*, sec.symbol
+			FROM security_price sp
+			JOIN security sec ON sec.id = sp.security_id
+			WHERE sec.ledger_id = ?`
+		args := []any{ledgerID}
+		if v := r.URL.Query().Get("security_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				writeErr(w, badRequest("security_id must be an integer", nil))
+				return
+			}
+			q += " AND sp.security_id = ?"
+			args = append(args, id)
+		}
+		rows, err := s.db.Query(q+" ORDER BY sec.symbol, sp.price_date DESC", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query prices", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read prices", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body securityPriceBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsRow(ledgerID, "security", &body.SecurityID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if _, e := requireDate(body.PriceDate, "price_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if body.PriceCents < 0 {
+			writeErr(w, badRequest("price_cents must be >= 0", nil))
+			return
+		}
+		id, err := upsertSecurityPrice(s.db, body)
+		if err != nil {
+			writeErr(w, serverError("failed to save price", err))
+			return
+		}
+		created, apiE := scanRowToMap(s.db, "security_price", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) securityPriceByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/security-prices/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	res, err := s.db.Exec(
+		"DELETE FROM security_price WHERE id = ? AND security_id IN (SELECT id FROM security WHERE ledger_id = ?)",
+		id, ledgerFromContext(r.Context()),
+	)
+	if err != nil {
+		writeErr(w, badRequest("could not delete price", nil))
+		return
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		writeErr(w, notFound("price not found"))
+		return
+	}
+	writeJSON(w, 200, map[string]any{"ok": true})
+}
+
+// securityPricesImport loads closing prices from a CSV request body with the
+// header date,symbol,price where price is in major units (e.g. 123.45).
+// Symbols must already exist. The import is all-or-nothing.
+//
+//	POST /api/security-prices/import
+func (s *server) securityPricesImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	symbols := make(map[string]int64)
+	rows, err := s.db.Query("SELECT symbol, id FROM security WHERE ledger_id = ?", ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to query securities", err))
+		return
+	}
+	for rows.Next() {
+		var sym string
+		var id int64
+		if err := rows.Scan(&sym, &id); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read securities", err))
+			return
+		}
+		symbols[sym] = id
+	}
+	rows.Close()
+
+	prices, e := parseSecurityPriceCSV(io.LimitReader(r.Body, 4<<20), symbols)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+	for _, p := range prices {
+		if _, err := upsertSecurityPrice(tx, p); err != nil {
+			writeErr(w, serverError("failed to save price", err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeErr(w, serverError("failed to import prices", err))
+		return
+	}
+	writeOK(w, map[string]any{"imported": len(prices)})
+}
+
+func parseSecurityPriceCSV(src io.Reader, symbols map[string]int64) ([]securityPriceBody, *apiErr) {
+	cr := csv.NewReader(src)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, badRequest("CSV must start with a header row: date,symbol,price", nil)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, h := range []string{"date", "symbol", "price"} {
+		if _, ok := col[h]; !ok {
+			return nil, badRequest("CSV header is missing column "+h, nil)
+		}
+	}
+
+	var out []securityPriceBody
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, badRequest("invalid CSV", map[string]any{"line": line, "error": err.Error()})
+		}
+		date, e := requireDate(strings.TrimSpace(rec[col["date"]]), "date")
+		if e != nil {
+			e.Details = map[string]any{"line": line}
+			return nil, e
+		}
+		sym := strings.ToUpper(strings.TrimSpace(rec[col["symbol"]]))
+		id, ok := symbols[sym]
+		if !ok {
+			return nil, badRequest("unknown symbol "+sym, map[string]any{"line": line})
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(rec[col["price"]]), 64)
+		if err != nil || price < 0 || math.IsInf(price, 0) {
+			return nil, badRequest("price must be a non-negative number", map[string]any{"line": line})
+		}
+		out = append(out, securityPriceBody{SecurityID: id, PriceDate: date, PriceCents: int64(math.Round(price * 100))})
+	}
+	if len(out) == 0 {
+		return nil, badRequest("CSV contains no prices", nil)
+	}
+	return out, nil
+}
+
+// --- Transactions ---
+
+type investmentBody struct {
+	AccountID   int64   `json:"account_id"`
+	SecurityID  int64   `json:"security_id"`
+	TxnDate     string  `json:"txn_date"`
+	Kind        string  `json:"kind"`
+	Quantity    float64 `json:"quantity"`
+	PriceCents  int64   `json:"price_cents"`
+	FeeCents    int64   `json:"fee_cents"`
+	AmountCents int64   `json:"amount_cents"`
+	Description *string `json:"description"`
+}
+
+// validateInvestment checks a transaction and fills in AmountCents with its
+// cash effect: what a buy costs, what a sell returns, or the dividend paid.
+func (s *server) validateInvestment(ledgerID int64, b *investmentBody) (symbol string, e *apiErr) {
+	if e := s.ledgerOwnsAccounts(ledgerID, &b.AccountID); e != nil {
+		return "", e
+	}
+	var accountCurrency, securityCurrency string
+	err := s.db.QueryRow(`
+		SELECT sec.symbol, sec.currency, (SELECT currency FROM account WHERE id = ?)
+		FROM security sec
+		WHERE sec.id = ? AND sec.ledger_id = ?
+	`, b.AccountID, b.SecurityID, ledgerID).Scan(&symbol, &securityCurrency, &accountCurrency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", badRequest("security not found", map[string]any{"security_id": b.SecurityID})
+	}
+	if err != nil {
+		return "", serverError("failed to read security", err)
+	}
+	if securityCurrency != accountCurrency {
+		return "", badRequest("security currency must match the account currency", map[string]any{"security_currency": securityCurrency, "account_currency": accountCurrency})
+	}
+	if _, e := requireDate(b.TxnDate, "txn_date"); e != nil {
+		return "", e
+	}
+	if b.FeeCents < 0 {
+		return "", badRequest("fee_cents must be >= 0", nil)
+	}
+
+	switch b.Kind {
+	case "B", "S":
+		if !(b.Quantity > 0) || math.IsInf(b.Quantity, 0) {
+			return "", badRequest("quantity must be > 0", nil)
+		}
+		if b.PriceCents < 0 {
+			return "", badRequest("price_cents must be >= 0", nil)
+		}
+		gross := int64(math.Round(b.Quantity * float64(b.PriceCents)))
+		if b.Kind == "B" {
+			b.AmountCents = gross + b.FeeCents
+		} else {
+			b.AmountCents = gross - b.FeeCents
+			if b.AmountCents < 0 {
+				return "", badRequest("fee_cents cannot exceed the sale proceeds", nil)
+			}
+		}
+	case "D":
+		if b.AmountCents <= 0 {
+			return "", badRequest("amount_cents must be > 0 for dividends", nil)
+		}
+		b.Quantity, b.PriceCents, b.FeeCents = 0, 0, 0
+	default:
+		return "", badRequest("kind must be one of B, S, D", nil)
+	}
+	return symbol, nil
+}
+
+// writeInvestmentEntry records the cash side of a transaction as an entry on
+// its account and returns the entry id (nil when no cash moved).
+func writeInvestmentEntry(tx *sql.Tx, ledgerID int64, b investmentBody, symbol string) (*int64, error) {
+	if b.AmountCents == 0 {
+		return nil, nil
+	}
+	var name string
+	var src, dest *int64
+	switch b.Kind {
+	case "B":
+		name, src = "Buy "+symbol, &b.AccountID
+	case "S":
+		name, dest = "Sell "+symbol, &b.AccountID
+	default:
+		name, dest = "Dividend "+symbol, &b.AccountID
+	}
+	res, err := tx.Exec(
+		"INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		ledgerID, b.TxnDate, name, b.AmountCents, src, dest, b.Description,
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &id, nil
+}
+
+// checkPosition rejects transaction histories that sell more units than the
+// account held at the time.
+func checkPosition(tx *sql.Tx, accountID, securityID int64) *apiErr {
+	rows, err := tx.Query(`
+		SELECT txn_date, kind, quantity
+		FROM investment_txn
+		WHERE account_id = ? AND security_id = ? AND kind IN ('B', 'S')
+		ORDER BY txn_date, kind, id
+	`, accountID, securityID)
+	if err != nil {
+		return serverError("failed to read transactions", err)
+	}
+	defer rows.Close()
+	var held float64
+	for rows.Next() {
+		var date, kind string
+		var qty float64
+		if err := rows.Scan(&date, &kind, &qty); err != nil {
+			return serverError("failed to read transactions", err)
+		}
+		if kind == "S" {
+			qty = -qty
+		}
+		held += qty
+		if held < -quantityEpsilon {
+			return badRequest("sell exceeds the position held", map[string]any{"date": date})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return serverError("failed to read transactions", err)
+	}
+	return nil
+}
+
+func (s *server) investments(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := `
+			SELECT t.*, sec.symbol, a.name AS account_name
+			FROM investment_txn t
+			JOIN security sec ON sec.id = t.security_id
+			JOIN account a ON a.id = t.account_id
+			WHERE t.ledger_id = ?`
+		args := []any{ledgerID}
+		if v := r.URL.Query().Get("account_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				writeErr(w, badRequest("account_id must be an integer", nil))
+				return
+			}
+			q += " AND t.account_id = ?"
+			args = append(args, id)
+		}
+		rows, err := s.db.Query(q+" ORDER BY t.txn_date DESC, t.id DESC", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query investment transactions", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read investment transactions", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body investmentBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		symbol, e := s.validateInvestment(ledgerID, &body)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		entryID, err := writeInvestmentEntry(tx, ledgerID, body, symbol)
+		if err != nil {
+			writeErr(w, serverError("failed to record cash movement", err))
+			return
+		}
+		res, err := tx.Exec(`
+			INSERT INTO investment_txn (ledger_id, account_id, security_id, txn_date, kind, quantity, price_cents, fee_cents, amount_cents, entry_id, description)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ledgerID, body.AccountID, body.SecurityID, body.TxnDate, body.Kind, body.Quantity, body.PriceCents, body.FeeCents, body.AmountCents, entryID, body.Description)
+		if err != nil {
+			writeErr(w, badRequest("could not create investment transaction", nil))
+			return
+		}
+		if e := checkPosition(tx, body.AccountID, body.SecurityID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to create investment transaction", err))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "investment_txn", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) investmentByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/investments/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var oldAccount, oldSecurity int64
+	var oldEntry sql.NullInt64
+	err := s.db.QueryRow(
+		"SELECT account_id, security_id, entry_id FROM investment_txn WHERE id = ? AND ledger_id = ?",
+		id, ledgerID,
+	).Scan(&oldAccount, &oldSecurity, &oldEntry)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("investment transaction not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read investment transaction", err))
+		return
+	}
+	if oldEntry.Valid && r.Method != http.MethodGet {
+		if e := s.reconciledEntryGuard(oldEntry.Int64); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body investmentBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		symbol, e := s.validateInvestment(ledgerID, &body)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		if oldEntry.Valid {
+			if _, err := tx.Exec("DELETE FROM entry WHERE id = ?", oldEntry.Int64); err != nil {
+				writeErr(w, serverError("failed to replace cash movement", err))
+				return
+			}
+		}
+		entryID, err := writeInvestmentEntry(tx, ledgerID, body, symbol)
+		if err != nil {
+			writeErr(w, serverError("failed to record cash movement", err))
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE investment_txn
+			SET account_id=?, security_id=?, txn_date=?, kind=?, quantity=?, price_cents=?, fee_cents=?, amount_cents=?, entry_id=?, description=?
+			WHERE id=?
+		`, body.AccountID, body.SecurityID, body.TxnDate, body.Kind, body.Quantity, body.PriceCents, body.FeeCents, body.AmountCents, entryID, body.Description, id); err != nil {
+			writeErr(w, badRequest("could not update investment transaction", nil))
+			return
+		}
+		for _, p := range [][2]int64{{oldAccount, oldSecurity}, {body.AccountID, body.SecurityID}} {
+			if e := checkPosition(tx, p[0], p[1]); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to update investment transaction", err))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "investment_txn", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM investment_txn WHERE id = ?", id); err != nil {
+			writeErr(w, badRequest("could not delete investment transaction", nil))
+			return
+		}
+		if oldEntry.Valid {
+			if _, err := tx.Exec("DELETE FROM entry WHERE id = ?", oldEntry.Int64); err != nil {
+				writeErr(w, serverError("failed to delete cash movement", err))
+				return
+			}
+		}
+		if e := checkPosition(tx, oldAccount, oldSecurity); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to delete investment transaction", err))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// investmentEntryGuard rejects direct edits of entries that record the cash
+// side of an investment transaction.
+func (s *server) investmentEntryGuard(entryID int64) *apiErr {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM investment_txn WHERE entry_id = ?", entryID).Scan(&n); err != nil {
+		return serverError("failed to check investment transactions", err)
+	}
+	if n > 0 {
+		return badRequest("this entry belongs to an investment transaction; change it through /api/investments", nil)
+	}
+	return nil
+}
+
+// --- Valuation ---
+
+type position struct {
+	AccountID  int64
+	SecurityID int64
+	Quantity   float64
+	PriceCents int64
+	PriceDate  *string
+}
+
+// ValueCents is the market value of the position.
+func (p position) ValueCents() int64 {
+	return int64(math.Round(p.Quantity * float64(p.PriceCents)))
+}
+
+// positionsAsOf returns the open positions of a ledger's accounts on asOf,
+// priced at the latest security_price on or before asOf, or else at the
+// latest traded price.
+func (s *server) positionsAsOf(ledgerID int64, asOf string) ([]position, error) {
+	rows, err := s.db.Query(`
+		WITH pos AS (
+		  SELECT t.account_id, t.security_id,
+		         SUM(CASE t.kind WHEN 'B' THEN t.quantity WHEN 'S' THEN -t.quantity ELSE 0 END) AS qty
+		  FROM investment_txn t
+		  WHERE t.ledger_id = ?
+		    AND t.txn_date <= ?
+		  GROUP BY t.account_id, t.security_id
+		),
+		quoted AS (
+		  SELECT pos.*,
+		         (SELECT sp.price_date FROM security_price sp
+		          WHERE sp.security_id = pos.security_id AND sp.price_date <= ?
+		          ORDER BY sp.price_date DESC LIMIT 1) AS price_date
+		  FROM pos
+		  WHERE ABS(pos.qty) > ?
+		)
+		SELECT q.account_id, q.security_id, q.qty,
+		       COALESCE(
+		         (SELECT sp.price_cents FROM security_price sp WHERE sp.security_id = q.security_id AND sp.price_date = q.price_date),
+		         (SELECT t.price_cents FROM investment_txn t
+		          WHERE t.security_id = q.security_id AND t.kind IN ('B', 'S') AND t.txn_date <= ?
+		          ORDER BY t.txn_date DESC, t.id DESC LIMIT 1),
+		         0
+		       ) AS price_cents,
+		       q.price_date
+		FROM quoted q
+		ORDER BY q.account_id, q.security_id
+	`, ledgerID, asOf, asOf, quantityEpsilon, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []position
+	for rows.Next() {
+		var p position
+		var priceDate sql.NullString
+		if err := rows.Scan(&p.AccountID, &p.SecurityID, &p.Quantity, &p.PriceCents, &priceDate); err != nil {
+			return nil, err
+		}
+		if priceDate.Valid {
+			d := priceDate.String
+			p.PriceDate = &d
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// holdingsValueAsOf returns the market value of each account's positions.
+func (s *server) holdingsValueAsOf(ledgerID int64, asOf string) (map[int64]int64, error) {
+	positions, err := s.positionsAsOf(ledgerID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]int64)
+	for _, p := range positions {
+		out[p.AccountID] += p.ValueCents()
+	}
+	return out, nil
+}
+
+// addHoldingsToRows adds market value of holdings to each balance row's col,
+// exposing the parts as cash_balance_cents and holdings_value_cents.
+func (s *server) addHoldingsToRows(ledgerID int64, rows []map[string]any, col string, asOf string) *apiErr {
+	values, err := s.holdingsValueAsOf(ledgerID, asOf)
+	if err != nil {
+		return serverError("failed to value holdings", err)
+	}
+	for _, row := range rows {
+		id, _ := row["id"].(int64)
+		cash, _ := row[col].(int64)
+		row["cash_balance_cents"] = cash
+		row["holdings_value_cents"] = values[id]
+		row[col] = cash + values[id]
+	}
+	return nil
+}
+
+// addHoldingsToPoints adds market value of holdings to balance points.
+func (s *server) addHoldingsToPoints(ledgerID int64, pts []balancePoint, asOf string) error {
+	values, err := s.holdingsValueAsOf(ledgerID, asOf)
+	if err != nil {
+		return err
+	}
+	for i := range pts {
+		pts[i].BalanceCents += values[pts[i].ID]
+	}
+	return nil
+}
+
+type holding struct {
+	AccountID           int64   `json:"account_id"`
+	SecurityID          int64   `json:"security_id"`
+	Symbol              string  `json:"symbol"`
+	Quantity            float64 `json:"quantity"`
+	PriceCents          int64   `json:"price_cents"`
+	PriceDate           *string `json:"price_date"`
+	MarketValueCents    int64   `json:"market_value_cents"`
+	CostBasisCents      int64   `json:"cost_basis_cents"`
+	UnrealizedGainCents int64   `json:"unrealized_gain_cents"`
+	RealizedGainCents   int64   `json:"realized_gain_cents"`
+	DividendsCents      int64   `json:"dividends_cents"`
+	Lots                []lot   `json:"lots"`
+}
+
+type lot struct {
+	Date      string  `json:"date"`
+	Quantity  float64 `json:"quantity"`
+	CostCents float64 `json:"cost_cents"`
+}
+
+// holdings reports positions per account and security as of a date, with
+// FIFO lots, cost basis and gains.
+//
+//	GET /api/holdings?as_of=YYYY-MM-DD[&account_id=]
+func (s *server) holdings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	asOf := r.URL.Query().Get("as_of")
+	if _, e := requireDate(asOf, "as_of"); e != nil {
+		writeErr(w, e)
+		return
+	}
+	var accountFilter int64
+	if v := r.URL.Query().Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeErr(w, badRequest("account_id must be an integer", nil))
+			return
+		}
+		accountFilter = id
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	rows, err := s.db.Query(`
+		SELECT t.account_id, t.security_id, sec.symbol, t.txn_date, t.kind, t.quantity, t.price_cents, t.fee_cents, t.amount_cents
+		FROM investment_txn t
+		JOIN security sec ON sec.id = t.security_id
+		WHERE t.ledger_id = ?
+		  AND t.txn_date <= ?
+		  AND (? = 0 OR t.account_id = ?)
+		ORDER BY t.txn_date, t.kind, t.id
+	`, ledgerID, asOf, accountFilter, accountFilter)
+	if err != nil {
+		writeErr(w, serverError("failed to query investment transactions", err))
+		return
+	}
+	type key struct{ account, security int64 }
+	byKey := make(map[key]*holding)
+	var order []key
+	for rows.Next() {
+		var (
+			k                  key
+			symbol, date, kind string
+			qty                float64
+			price, fee, amount int64
+		)
+		if err := rows.Scan(&k.account, &k.security, &symbol, &date, &kind, &qty, &price, &fee, &amount); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read investment transactions", err))
+			return
+		}
+		h := byKey[k]
+		if h == nil {
+			h = &holding{AccountID: k.account, SecurityID: k.security, Symbol: symbol, Lots: []lot{}}
+			byKey[k] = h
+			order = append(order, k)
+		}
+		switch kind {
+		case "B":
+			h.Lots = append(h.Lots, lot{Date: date, Quantity: qty, CostCents: float64(amount)})
+		case "S":
+			// Consume the oldest lots first.
+			remaining := qty
+			var cost float64
+			for remaining > quantityEpsilon && len(h.Lots) > 0 {
+				l := &h.Lots[0]
+				take := math.Min(remaining, l.Quantity)
+				part := l.CostCents * take / l.Quantity
+				cost += part
+				l.CostCents -= part
+				l.Quantity -= take
+				remaining -= take
+				if l.Quantity <= quantityEpsilon {
+					h.Lots = h.Lots[1:]
+				}
+			}
+			h.RealizedGainCents += amount - int64(math.Round(cost))
+		case "D":
+			h.DividendsCents += amount
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read investment transactions", err))
+		return
+	}
+
+	positions, err := s.positionsAsOf(ledgerID, asOf)
+	if err != nil {
+		writeErr(w, serverError("failed to value holdings", err))
+		return
+	}
+	for _, p := range positions {
+		if h := byKey[key{p.AccountID, p.SecurityID}]; h != nil {
+			h.PriceCents = p.PriceCents
+			h.PriceDate = p.PriceDate
+		}
+	}
+
+	out := make([]holding, 0, len(order))
+	for _, k := range order {
+		h := byKey[k]
+		var cost float64
+		for _, l := range h.Lots {
+			h.Quantity += l.Quantity
+			cost += l.CostCents
+		}
+		h.CostBasisCents = int64(math.Round(cost))
+		h.MarketValueCents = int64(math.Round(h.Quantity * float64(h.PriceCents)))
+		h.UnrealizedGainCents = h.MarketValueCents - h.CostBasisCents
+		out = append(out, *h)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].AccountID != out[j].AccountID {
+			return out[i].AccountID < out[j].AccountID
+		}
+		return out[i].Symbol < out[j].Symbol
+	})
+	writeOK(w, map[string]any{"as_of": asOf, "holdings": out})
+}
@@ -0,0 +1,107 @@
+package budgie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestInvestmentHoldingsValuedAtMarket(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Brokerage", "2026-01-01", int64(200000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/securities", map[string]any{"symbol": "vti", "name": "Total Market"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create security: status %d", resp.StatusCode)
+	}
+	secID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	txn := func(body map[string]any) *http.Response {
+		t.Helper()
+		body["account_id"] = acctID
+		body["security_id"] = secID
+		return doJSON(t, http.MethodPost, api.URL+"/api/investments", body)
+	}
+	resp = txn(map[string]any{"txn_date": "2026-01-05", "kind": "B", "quantity": 10, "price_cents": 10000, "fee_cents": 500})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("buy: status %d", resp.StatusCode)
+	}
+	buy := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if got := mustInt64(t, buy["amount_cents"]); got != 100500 {
+		t.Fatalf("expected buy cost 100500, got %d", got)
+	}
+	if resp := txn(map[string]any{"txn_date": "2026-02-10", "kind": "S", "quantity": 20, "price_cents": 12000}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 selling more than held, got %d", resp.StatusCode)
+	}
+	if resp := txn(map[string]any{"txn_date": "2026-02-10", "kind": "S", "quantity": 4, "price_cents": 12000}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("sell: status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(api.URL+"/api/security-prices/import", "text/csv", strings.NewReader("date,symbol,price\n2026-01-31,VTI,110.00\n"))
+	if err != nil {
+		t.Fatalf("import prices: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("import prices: status %d", resp.StatusCode)
+	}
+
+	balResp, err := http.Get(api.URL + "/api/balances?as_of=2026-01-31")
+	if err != nil {
+		t.Fatalf("get balances: %v", err)
+	}
+	row := mustMap(t, mustList(t, decodeAPIResponse(t, balResp).Data)[0])
+	if cash, holdings := mustInt64(t, row["cash_balance_cents"]), mustInt64(t, row["holdings_value_cents"]); cash != 99500 || holdings != 110000 {
+		t.Fatalf("expected cash 99500 and holdings 110000, got %d and %d", cash, holdings)
+	}
+	if got := mustInt64(t, row["balance_cents"]); got != 209500 {
+		t.Fatalf("expected balance 209500, got %d", got)
+	}
+
+	srv := &server{db: db}
+	pts, err := srv.actualBalancesAsOf(defaultLedgerID, "2026-02-15")
+	if err != nil {
+		t.Fatalf("actualBalancesAsOf: %v", err)
+	}
+	// Cash 99500 + 48000 from the sale, plus 6 units at 110.00.
+	if len(pts) != 1 || pts[0].BalanceCents != 147500+66000 {
+		t.Fatalf("unexpected balances after sale: %+v", pts)
+	}
+
+	holdResp, err := http.Get(api.URL + "/api/holdings?as_of=2026-02-15")
+	if err != nil {
+		t.Fatalf("get holdings: %v", err)
+	}
+	holdings := mustList(t, mustMap(t, decodeAPIResponse(t, holdResp).Data)["holdings"])
+	h := mustMap(t, holdings[0])
+	for key, want := range map[string]int64{
+		"cost_basis_cents":      60300,
+		"market_value_cents":    66000,
+		"unrealized_gain_cents": 5700,
+		"realized_gain_cents":   7800,
+	} {
+		if got := mustInt64(t, h[key]); got != want {
+			t.Fatalf("expected %s=%d, got %d", key, want, got)
+		}
+	}
+
+	// The cash entry of a buy can only change through the investment.
+	entryID := mustInt64(t, buy["entry_id"])
+	req, _ := http.NewRequest(http.MethodDelete, api.URL+"/api/entries/"+fmtInt64(entryID), nil)
+	delResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete entry: %v", err)
+	}
+	if delResp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 deleting an investment entry, got %d", delResp.StatusCode)
+	}
+}
@@ -0,0 +1,447 @@
+package budgie
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultLedgerID is the ledger created by the ledger migration. Pre-existing
+// data lives there, and it is the only ledger used when auth is disabled.
+const defaultLedgerID int64 = 1
+
+// ledgerFromContext returns the active ledger resolved by requireAuth.
+func ledgerFromContext(ctx context.Context) int64 {
+	if v := ctx.Value(ctxLedgerKey); v != nil {
+		if id, ok := v.(int64); ok {
+			return id
+		}
+	}
+	return defaultLedgerID
+}
+
+func (s *server) isLedgerMember(ledgerID, userID int64) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM ledger_member WHERE ledger_id = ? AND user_id = ?`, ledgerID, userID).Scan(&n)
+	return n > 0, err
+}
+
+func (s *server) ledgerRole(ledgerID, userID int64) (string, error) {
+	var role string
+	err := s.db.QueryRow(`SELECT role FROM ledger_member WHERE ledger_id = ? AND user_id = ?`, ledgerID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// resolveLedger picks the ledger a session works in: the one selected on the
+// session if the user is still a member, otherwise the user's first ledger.
+func (s *server) resolveLedger(sess *sessionInfo, user *userInfo) (int64, error) {
+	if sess.LedgerID != nil {
+		ok, err := s.isLedgerMember(*sess.LedgerID, user.ID)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return *sess.LedgerID, nil
+		}
+	}
+	return s.ensureUserLedger(user)
+}
+
+// ensureUserLedger returns the user's first ledger, provisioning one if the
+// user has none. The first user to sign in claims the default ledger once
+// (see migration 025_ledger_claim.sql); later users get a fresh ledger of
+// their own.
+func (s *server) ensureUserLedger(user *userInfo) (int64, error) {
+	var id int64
+	err := s.db.QueryRow(`SELECT ledger_id FROM ledger_member WHERE user_id = ? ORDER BY ledger_id LIMIT 1`, user.ID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE ledger SET claimable = 0 WHERE id = ? AND claimable = 1`, defaultLedgerID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		id = defaultLedgerID
+		if _, err := tx.Exec(`INSERT INTO ledger_member (ledger_id, user_id, role) VALUES (?, ?, 'owner')`, id, user.ID); err != nil {
+			return 0, err
+		}
+	} else {
+		res, err := tx.Exec(`INSERT INTO ledger (name) VALUES (?)`, user.DisplayName+"'s ledger")
+		if err != nil {
+			return 0, err
+		}
+		id, _ = res.LastInsertId()
+		if _, err := tx.Exec(`INSERT INTO ledger_member (ledger_id, user_id, role) VALUES (?, ?, 'owner')`, id, user.ID); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// ledgerOwnsAccounts verifies that every non-nil account id belongs to the ledger.
+func (s *server) ledgerOwnsAccounts(ledgerID int64, ids ...*int64) *apiErr {
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		var n int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM account WHERE id = ? AND ledger_id = ?`, *id, ledgerID).Scan(&n); err != nil {
+			return serverError("failed to check account", err)
+		}
+		if n == 0 {
+			return badRequest("account not found", map[string]any{"account_id": *id})
+		}
+	}
+	return nil
+}
+
+// ledgerOwnsSchedule verifies that a (possibly nil) schedule id belongs to the ledger.
+func (s *server) ledgerOwnsSchedule(ledgerID int64, id *int64) *apiErr {
+	return s.ledgerOwnsRow(ledgerID, "schedule", id)
+}
+
+// ledgerOwnsCategory verifies that a (possibly nil) category id belongs to the ledger.
+func (s *server) ledgerOwnsCategory(ledgerID int64, id *int64) *apiErr {
+	return s.ledgerOwnsRow(ledgerID, "category", id)
+}
+
+// ledgerOwnsRow checks a (possibly nil) id against a ledger-owned table.
+// table is always a trusted identifier, never user input.
+func (s *server) ledgerOwnsRow(ledgerID int64, table string, id *int64) *apiErr {
+	if id == nil {
+		return nil
+	}
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ? AND ledger_id = ?", *id, ledgerID).Scan(&n); err != nil {
+		return serverError("failed to check "+table, err)
+	}
+	if n == 0 {
+		return badRequest(table+" not found", map[string]any{table + "_id": *id})
+	}
+	return nil
+}
+
+func (s *server) ledgers(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+	active := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		var (
+			rows *sql.Rows
+			err  error
+		)
+		if user == nil {
+			rows, err = s.db.Query(`SELECT l.*, 'owner' AS role, l.id = ? AS is_active FROM ledger l WHERE l.id = ?`, active, active)
+		} else {
+			rows, err = s.db.Query(`
+				SELECT l.*, m.role, l.id = ? AS is_active
+				FROM ledger l
+				JOIN ledger_member m ON m.ledger_id = l.id
+				WHERE m.user_id = ?
+				ORDER BY l.id
+			`, active, user.ID)
+		}
+		if err != nil {
+			writeErr(w, serverError("failed to query ledgers", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read ledgers", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		if user == nil {
+			writeErr(w, badRequest("ledgers require authentication", nil))
+			return
+		}
+		var body struct {
+			Name string `json:"name"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if strings.TrimSpace(body.Name) == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(`INSERT INTO ledger (name) VALUES (?)`, strings.TrimSpace(body.Name))
+		if err != nil {
+			writeErr(w, badRequest("could not create ledger", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		if _, err := tx.Exec(`INSERT INTO ledger_member (ledger_id, user_id, role) VALUES (?, ?, 'owner')`, id, user.ID); err != nil {
+			writeErr(w, serverError("failed to add ledger owner", err))
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to create ledger", err))
+			return
+		}
+		created, apiE := scanRowToMap(s.db, "ledger", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ledgerByID serves the per-ledger routes:
+//
+//	PUT    /api/ledgers/{id}                    rename (owner)
+//	DELETE /api/ledgers/{id}                    delete an empty ledger (owner)
+//	POST   /api/ledgers/{id}/activate           switch the session to this ledger
+//	GET    /api/ledgers/{id}/members            list members
+//	POST   /api/ledgers/{id}/members            add a member by email (owner)
+//	DELETE /api/ledgers/{id}/members/{user_id}  remove a member (owner, or self)
+func (s *server) ledgerByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/ledgers/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 3 {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	user := userFromContext(r.Context())
+	if user == nil {
+		writeErr(w, badRequest("ledgers require authentication", nil))
+		return
+	}
+	role, err := s.ledgerRole(id, user.ID)
+	if err != nil {
+		writeErr(w, serverError("failed to check ledger membership", err))
+		return
+	}
+	if role == "" {
+		writeErr(w, notFound("ledger not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.ledgerItem(w, r, id, role)
+	case parts[1] == "activate" && len(parts) == 2:
+		s.ledgerActivate(w, r, id)
+	case parts[1] == "members":
+		var memberID int64
+		if len(parts) == 3 {
+			memberID, err = strconv.ParseInt(parts[2], 10, 64)
+			if err != nil || memberID <= 0 {
+				writeErr(w, notFound("not found"))
+				return
+			}
+		}
+		s.ledgerMembers(w, r, id, role, user, memberID)
+	default:
+		writeErr(w, notFound("not found"))
+	}
+}
+
+func (s *server) ledgerItem(w http.ResponseWriter, r *http.Request, id int64, role string) {
+	switch r.Method {
+	case http.MethodPut:
+		if role != "owner" {
+			writeErr(w, forbidden("only ledger owners can rename a ledger"))
+			return
+		}
+		var body struct {
+			Name string `json:"name"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if strings.TrimSpace(body.Name) == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		if _, err := s.db.Exec(`UPDATE ledger SET name = ? WHERE id = ?`, strings.TrimSpace(body.Name), id); err != nil {
+			writeErr(w, badRequest("could not update ledger", nil))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "ledger", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		if role != "owner" {
+			writeErr(w, forbidden("only ledger owners can delete a ledger"))
+			return
+		}
+		var n int
+		if err := s.db.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM account WHERE ledger_id = ?)
+			     + (SELECT COUNT(*) FROM schedule WHERE ledger_id = ?)
+			     + (SELECT COUNT(*) FROM entry WHERE ledger_id = ?)
+		`, id, id, id).Scan(&n); err != nil {
+			writeErr(w, serverError("failed to check ledger contents", err))
+			return
+		}
+		if n > 0 {
+			writeErr(w, badRequest("ledger is not empty", nil))
+			return
+		}
+		if _, err := s.db.Exec(`DELETE FROM ledger WHERE id = ?`, id); err != nil {
+			writeErr(w, badRequest("could not delete ledger", nil))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) ledgerActivate(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	sess := sessionFromContext(r.Context())
+	if sess == nil {
+		writeErr(w, badRequest("ledgers require authentication", nil))
+		return
+	}
+	if _, err := s.db.Exec(`UPDATE auth_session SET ledger_id = ? WHERE id = ?`, id, sess.ID); err != nil {
+		writeErr(w, serverError("failed to switch ledger", err))
+		return
+	}
+	writeOK(w, map[string]any{"ledger_id": id})
+}
+
+func (s *server) ledgerMembers(w http.ResponseWriter, r *http.Request, id int64, role string, user *userInfo, memberID int64) {
+	switch {
+	case r.Method == http.MethodGet && memberID == 0:
+		rows, err := s.db.Query(`
+			SELECT m.user_id, u.email, u.display_name, m.role, m.created_at
+			FROM ledger_member m
+			JOIN user u ON u.id = m.user_id
+			WHERE m.ledger_id = ?
+			ORDER BY m.role DESC, u.email
+		`, id)
+		if err != nil {
+			writeErr(w, serverError("failed to query ledger members", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read ledger members", err))
+			return
+		}
+		writeOK(w, data)
+	case r.Method == http.MethodPost && memberID == 0:
+		if role != "owner" {
+			writeErr(w, forbidden("only ledger owners can add members"))
+			return
+		}
+		var body struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if strings.TrimSpace(body.Role) == "" {
+			body.Role = "member"
+		}
+		if body.Role != "owner" && body.Role != "member" {
+			writeErr(w, badRequest("role must be 'owner' or 'member'", nil))
+			return
+		}
+		var newID int64
+		if err := s.db.QueryRow(`SELECT id FROM user WHERE email = ? AND disabled_at IS NULL`, normalizeEmail(body.Email)).Scan(&newID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeErr(w, badRequest("user not found", nil))
+				return
+			}
+			writeErr(w, serverError("failed to lookup user", err))
+			return
+		}
+		if body.Role != "owner" {
+			var owners int
+			if err := s.db.QueryRow(`
+				SELECT COUNT(*) FROM ledger_member
+				WHERE ledger_id = ? AND role = 'owner' AND user_id != ?
+			`, id, newID).Scan(&owners); err != nil {
+				writeErr(w, serverError("failed to check ledger owners", err))
+				return
+			}
+			if owners == 0 {
+				writeErr(w, badRequest("a ledger must keep at least one owner", nil))
+				return
+			}
+		}
+		_, err := s.db.Exec(`
+			INSERT INTO ledger_member (ledger_id, user_id, role) VALUES (?, ?, ?)
+			ON CONFLICT(ledger_id, user_id) DO UPDATE SET role = excluded.role
+		`, id, newID, body.Role)
+		if err != nil {
+			writeErr(w, badRequest("could not add member", nil))
+			return
+		}
+		writeOK(w, map[string]any{"ledger_id": id, "user_id": newID, "role": body.Role})
+	case r.Method == http.MethodDelete && memberID != 0:
+		if role != "owner" && memberID != user.ID {
+			writeErr(w, forbidden("only ledger owners can remove other members"))
+			return
+		}
+		var owners int
+		if err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM ledger_member
+			WHERE ledger_id = ? AND role = 'owner' AND user_id != ?
+		`, id, memberID).Scan(&owners); err != nil {
+			writeErr(w, serverError("failed to check ledger owners", err))
+			return
+		}
+		if owners == 0 {
+			writeErr(w, badRequest("a ledger must keep at least one owner", nil))
+			return
+		}
+		res, err := s.db.Exec(`DELETE FROM ledger_member WHERE ledger_id = ? AND user_id = ?`, id, memberID)
+		if err != nil {
+			writeErr(w, badRequest("could not remove member", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("member not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,140 @@
+package budgie
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testSession struct {
+	cookie *http.Cookie
+	csrf   string
+}
+
+func newTestSession(t *testing.T, auth *AuthService, email string) testSession {
+	t.Helper()
+	userID, err := auth.createUser(email, email)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	sess, raw, err := auth.createSession(userID, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return testSession{cookie: &http.Cookie{Name: auth.Config().CookieName, Value: raw}, csrf: sess.CSRFToken}
+}
+
+func (ts testSession) do(t *testing.T, h http.Handler, method, path string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatalf("encode payload: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, "http://example.com"+path, &body)
+	req.AddCookie(ts.cookie)
+	req.Header.Set("X-CSRF-Token", ts.csrf)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestLedgerIsolation(t *testing.T) {
+	db := newTestDB(t)
+	cfg := AuthConfig{CookieName: "budgie_session", SessionTTL: time.Hour, AllowSignup: true, PasswordMin: 6}
+	auth := newTestAuthService(t, db, cfg)
+	mux := http.NewServeMux()
+	RegisterAPI(mux, db, auth)
+
+	// Pre-existing data lives in the default ledger.
+	if _, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(10000),
+	); err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+
+	alice := newTestSession(t, auth, "alice@example.com")
+	bob := newTestSession(t, auth, "bob@example.com")
+
+	// The first user to sign in claims the default ledger and its data.
+	rr := alice.do(t, mux, http.MethodGet, "/api/accounts", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("alice accounts status %d: %s", rr.Code, rr.Body.String())
+	}
+	var list apiResponseAny
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if n := len(mustList(t, list.Data)); n != 1 {
+		t.Fatalf("expected alice to see 1 account, got %d", n)
+	}
+
+	// Bob gets his own ledger; the same account name is allowed there.
+	rr = bob.do(t, mux, http.MethodPost, "/api/accounts", map[string]any{
+		"name":         "Checking",
+		"opening_date": "2026-01-01",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("bob create account status %d: %s", rr.Code, rr.Body.String())
+	}
+	var created apiResponseAny
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	bobAcct := mustInt64(t, mustMap(t, created.Data)["id"])
+
+	rr = bob.do(t, mux, http.MethodGet, "/api/accounts", nil)
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if n := len(mustList(t, list.Data)); n != 1 {
+		t.Fatalf("expected bob to see 1 account, got %d", n)
+	}
+
+	// Alice can neither reference nor modify Bob's account.
+	rr = alice.do(t, mux, http.MethodPost, "/api/entries", map[string]any{
+		"entry_date":     "2026-01-05",
+		"name":           "Sneaky",
+		"amount_cents":   100,
+		"src_account_id": bobAcct,
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for foreign account, got %d", rr.Code)
+	}
+	rr = alice.do(t, mux, http.MethodDelete, "/api/accounts/"+fmtInt64(bobAcct), nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting foreign account, got %d", rr.Code)
+	}
+
+	// Alice cannot demote herself while she is the only owner.
+	rr = alice.do(t, mux, http.MethodPost, "/api/ledgers/1/members", map[string]any{"email": "alice@example.com", "role": "member"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 demoting the last owner, got %d", rr.Code)
+	}
+
+	// Once added as a member, Bob can switch to the default ledger.
+	rr = alice.do(t, mux, http.MethodPost, "/api/ledgers/1/members", map[string]any{"email": "bob@example.com"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add member status %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = bob.do(t, mux, http.MethodPost, "/api/ledgers/1/activate", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("activate status %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = bob.do(t, mux, http.MethodGet, "/api/balances?as_of=2026-01-10", nil)
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	rows := mustList(t, list.Data)
+	if len(rows) != 1 || mustInt64(t, mustMap(t, rows[0])["balance_cents"]) != 10000 {
+		t.Fatalf("expected default ledger balances after switching, got %v", rows)
+	}
+
+	// A default ledger left without members is not claimed again.
+	if _, err := db.Exec("DELETE FROM ledger_member WHERE ledger_id = 1"); err != nil {
+		t.Fatalf("clear members: %v", err)
+	}
+	carol := newTestSession(t, auth, "carol@example.com")
+	rr = carol.do(t, mux, http.MethodGet, "/api/accounts", nil)
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if n := len(mustList(t, list.Data)); n != 0 {
+		t.Fatalf("expected carol to start with an empty ledger, got %d accounts", n)
+	}
+}
@@ -0,0 +1,469 @@
+package budgie
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Loans are liability accounts with loan_principal_cents set. Balances of
+// liabilities are negative while money is owed; the amortization math below
+// works on the positive amount owed instead.
+
+type loanFields struct {
+	PrincipalCents    *int64 `json:"loan_principal_cents"`
+	TermMonths        *int64 `json:"loan_term_months"`
+	PaymentScheduleID *int64 `json:"loan_payment_schedule_id"`
+}
+
+// validateLoanFields checks the loan settings of an account. accountID is 0
+// for an account that does not exist yet, which cannot have a payment
+// schedule.
+func (s *server) validateLoanFields(ledgerID, accountID int64, isLiability int64, f *loanFields) *apiErr {
+	if f.PrincipalCents == nil {
+		if f.TermMonths != nil || f.PaymentScheduleID != nil {
+			return badRequest("loan_principal_cents is required for a loan", nil)
+		}
+		return nil
+	}
+	if isLiability != 1 {
+		return badRequest("only liability accounts can be loans", nil)
+	}
+	if *f.PrincipalCents <= 0 {
+		return badRequest("loan_principal_cents must be > 0", nil)
+	}
+	if f.TermMonths == nil || *f.TermMonths < 1 || *f.TermMonths > 1200 {
+		return badRequest("loan_term_months must be 1..1200", nil)
+	}
+	if f.PaymentScheduleID == nil {
+		return nil
+	}
+	if e := s.ledgerOwnsSchedule(ledgerID, f.PaymentScheduleID); e != nil {
+		return e
+	}
+	var dest sql.NullInt64
+	if err := s.db.QueryRow("SELECT dest_account_id FROM schedule WHERE id = ?", *f.PaymentScheduleID).Scan(&dest); err != nil {
+		return serverError("failed to read schedule", err)
+	}
+	if !dest.Valid || dest.Int64 != accountID {
+		return badRequest("loan_payment_schedule_id must be a schedule paying into this account", nil)
+	}
+	return nil
+}
+
+// addMonthsClamped adds n calendar months to t, clamping to the end of the
+// target month (Jan 31 + 1 month = Feb 28).
+func addMonthsClamped(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// levelLoanPayment is the fixed monthly payment that repays principal over
+// termMonths at the given APR.
+func levelLoanPayment(principal, aprBps, termMonths int64) int64 {
+	if aprBps <= 0 {
+		return int64(math.Ceil(float64(principal) / float64(termMonths)))
+	}
+	r := float64(aprBps) / 10000.0 / 12.0
+	return int64(math.Round(float64(principal) * r / (1 - math.Pow(1+r, -float64(termMonths)))))
+}
+
+// loanInterestCents is the interest owed accrues from one payment to the next.
+func loanInterestCents(owedCents, aprBps int64, compound string, from, to time.Time) int64 {
+	if owedCents <= 0 {
+		return 0
+	}
+	return interestForPeriodCents(owedCents, aprBps, compound, from, to)
+}
+
+type loanPayment struct {
+	Date        string
+	AmountCents int64
+	Posted      bool
+	// OriginalDate is the nominal date of a scheduled payment.
+	OriginalDate string
+	// Final payments settle whatever is left regardless of AmountCents.
+	Final bool
+}
+
+type amortizationRow struct {
+	Date           string `json:"date"`
+	PaymentCents   int64  `json:"payment_cents"`
+	InterestCents  int64  `json:"interest_cents"`
+	PrincipalCents int64  `json:"principal_cents"`
+	BalanceCents   int64  `json:"remaining_balance_cents"`
+	Posted         bool   `json:"posted"`
+}
+
+// amortize applies payments in date order to a loan of owedCents starting on
+// from. Interest accrues between payments with interestForPeriod; a payment
+// is capped at what is left, and payments after payoff are dropped.
+func amortize(owedCents int64, from string, payments []loanPayment, aprBps int64, compound string) []amortizationRow {
+	prev, _ := time.Parse("2006-01-02", from)
+	var out []amortizationRow
+	for _, p := range payments {
+		if owedCents <= 0 {
+			break
+		}
+		d, err := time.Parse("2006-01-02", p.Date)
+		if err != nil || d.Before(prev) {
+			continue
+		}
+		interest := loanInterestCents(owedCents, aprBps, compound, prev, d)
+		pay := min(p.AmountCents, owedCents+interest)
+		if p.Final {
+			pay = owedCents + interest
+		}
+		owedCents -= pay - interest
+		out = append(out, amortizationRow{
+			Date:           p.Date,
+			PaymentCents:   pay,
+			InterestCents:  interest,
+			PrincipalCents: pay - interest,
+			BalanceCents:   owedCents,
+			Posted:         p.Posted,
+		})
+		prev = d
+	}
+	return out
+}
+
+type loanAccount struct {
+	ID                int64
+	OpeningDate       string
+	OpeningBalance    int64
+	AprBps            int64
+	Compound          string
+	PrincipalCents    int64
+	TermMonths        int64
+	PaymentScheduleID *int64
+}
+
+const loanAccountColumns = `id, opening_date, opening_balance_cents,
+	CASE WHEN is_interest_bearing = 1 THEN COALESCE(interest_apr_bps, 0) ELSE 0 END,
+	COALESCE(interest_compound, 'D'), loan_principal_cents, loan_term_months, loan_payment_schedule_id`
+
+func scanLoanAccount(row interface{ Scan(...any) error }) (loanAccount, error) {
+	var l loanAccount
+	err := row.Scan(&l.ID, &l.OpeningDate, &l.OpeningBalance, &l.AprBps, &l.Compound, &l.PrincipalCents, &l.TermMonths, &l.PaymentScheduleID)
+	return l, err
+}
+
+// loanOccurrences picks the payments of loan payment schedules out of occs,
+// keyed by the account they pay.
+func loanOccurrences(loans []loanAccount, occs []scheduledOccurrence) map[int64][]loanPayment {
+	bySchedule := make(map[int64]int64)
+	for _, l := range loans {
+		if l.PaymentScheduleID != nil {
+			bySchedule[*l.PaymentScheduleID] = l.ID
+		}
+	}
+	out := make(map[int64][]loanPayment)
+	for _, o := range occs {
+		accountID, ok := bySchedule[o.ScheduleID]
+		if !ok || o.DestAccountID == nil || *o.DestAccountID != accountID {
+			continue
+		}
+		out[accountID] = append(out[accountID], loanPayment{Date: o.Date, AmountCents: o.DestAmount, OriginalDate: o.OriginalDate})
+	}
+	return out
+}
+
+// scheduledLoans lists the ledger's loans that have a payment schedule; with
+// payoffOnly set, only those whose schedule stops once the loan is paid off.
+func scheduledLoans(q querier, ledgerID int64, payoffOnly bool) ([]loanAccount, error) {
+	query := "SELECT " + loanAccountColumns + " FROM account WHERE ledger_id = ? AND archived_at IS NULL AND loan_principal_cents IS NOT NULL AND loan_payment_schedule_id IS NOT NULL"
+	if payoffOnly {
+		query += " AND loan_payment_schedule_id IN (SELECT id FROM schedule WHERE stop_when_paid_off = 1)"
+	}
+	rows, err := q.Query(query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var loans []loanAccount
+	for rows.Next() {
+		l, err := scanLoanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, l)
+	}
+	return loans, rows.Err()
+}
+
+// loanInterest returns the interest part of each of a loan's scheduled
+// payments, which are in date order. Interest accrues from the later of the
+// previous payment and the loan's last real entry, on what is owed after real
+// entries and the principal of earlier payments.
+func loanInterest(q querier, l loanAccount, payments []loanPayment) ([]int64, error) {
+	if len(payments) == 0 {
+		return nil, nil
+	}
+	type delta struct {
+		date  string
+		cents int64
+	}
+	var deltas []delta
+	rows, err := q.Query(`
+		SELECT entry_date, SUM(delta_cents)
+		FROM v_entry_delta
+		WHERE account_id = ? AND entry_date >= ? AND entry_date <= ?
+		GROUP BY entry_date
+		ORDER BY entry_date
+	`, l.ID, l.OpeningDate, payments[len(payments)-1].Date)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d delta
+		if err := rows.Scan(&d.date, &d.cents); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deltas = append(deltas, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	balance := l.OpeningBalance
+	prev := l.OpeningDate
+	var principalPaid int64
+	out := make([]int64, len(payments))
+	i := 0
+	for k, p := range payments {
+		for ; i < len(deltas) && deltas[i].date <= p.Date; i++ {
+			balance += deltas[i].cents
+			prev = max(prev, deltas[i].date)
+		}
+		from, _ := time.Parse("2006-01-02", prev)
+		to, _ := time.Parse("2006-01-02", p.Date)
+		out[k] = loanInterestCents(-(balance + principalPaid), l.AprBps, l.Compound, from, to)
+		principalPaid += p.AmountCents - out[k]
+		prev = p.Date
+	}
+	return out, nil
+}
+
+// payoffInterest returns the interest part of the payments between from and
+// to of loans whose payment schedule stops once the loan is paid off, as the
+// JSON list payoffCTEDefs takes. It works on the uncapped payments: interest
+// up to the payment that pays the loan off does not depend on the cap, and
+// none accrues after it.
+func payoffInterest(q querier, ledgerID, scenarioID int64, from, to string) (string, error) {
+	loans, err := scheduledLoans(q, ledgerID, true)
+	if err != nil || len(loans) == 0 {
+		return "[]", err
+	}
+	occs, err := queryOccurrences(q, occurrenceQueryFor(scenarioID != 0), occurrenceParams(scenarioID, ledgerID, from, to, nil)...)
+	if err != nil {
+		return "", err
+	}
+	byLoan := loanOccurrences(loans, occs)
+	out := [][]any{}
+	for _, l := range loans {
+		payments := byLoan[l.ID]
+		interest, err := loanInterest(q, l, payments)
+		if err != nil {
+			return "", err
+		}
+		for k, p := range payments {
+			if interest[k] != 0 {
+				out = append(out, []any{*l.PaymentScheduleID, p.OriginalDate, interest[k]})
+			}
+		}
+	}
+	b, err := json.Marshal(out)
+	return string(b), err
+}
+
+// loanInterestPart is the interest part of one scheduled loan payment.
+type loanInterestPart struct {
+	AccountID int64
+	Date      string
+	Cents     int64
+}
+
+// scheduledLoanInterest lists the interest part of the payments in occs of
+// loans with a payment schedule (see loanInterest). occs lists the
+// occurrences of a projection from its start.
+func scheduledLoanInterest(q querier, ledgerID int64, occs []scheduledOccurrence) ([]loanInterestPart, error) {
+	loans, err := scheduledLoans(q, ledgerID, false)
+	if err != nil || len(loans) == 0 {
+		return nil, err
+	}
+	byLoan := loanOccurrences(loans, occs)
+	var out []loanInterestPart
+	for _, l := range loans {
+		payments := byLoan[l.ID]
+		interest, err := loanInterest(q, l, payments)
+		if err != nil {
+			return nil, err
+		}
+		for k, p := range payments {
+			if interest[k] != 0 {
+				out = append(out, loanInterestPart{AccountID: l.ID, Date: p.Date, Cents: interest[k]})
+			}
+		}
+	}
+	return out, nil
+}
+
+// loanProjectionAdjustments returns, per loan account with a payment schedule,
+// the amount to add to its projected balance so that scheduled payments between
+// start and asOf only pay down principal. occs lists the occurrences from start.
+func (s *server) loanProjectionAdjustments(ledgerID int64, asOf string, occs *projectionOccurrences) (map[int64]int64, error) {
+	list, err := occs.through(asOf)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := scheduledLoanInterest(s.db, ledgerID, list)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]int64)
+	for _, p := range parts {
+		out[p.AccountID] -= p.Cents
+	}
+	return out, nil
+}
+
+// accountAmortization serves GET /api/accounts/{id}/amortization: the loan's
+// payments from its opening date with the interest and principal portion of
+// each and the balance left after it. Payments are the posted entries and
+// upcoming occurrences of the payment schedule within the term, or level
+// monthly payments (the last one settling any rounding) when the loan has no
+// payment schedule.
+func (s *server) accountAmortization(w http.ResponseWriter, r *http.Request, ledgerID, id int64) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var isLoan bool
+	err := s.db.QueryRow("SELECT loan_principal_cents IS NOT NULL FROM account WHERE id = ? AND ledger_id = ?", id, ledgerID).Scan(&isLoan)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("account not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read account", err))
+		return
+	}
+	if !isLoan {
+		writeErr(w, badRequest("account is not a loan", nil))
+		return
+	}
+	l, err := scanLoanAccount(s.db.QueryRow("SELECT "+loanAccountColumns+" FROM account WHERE id = ?", id))
+	if err != nil {
+		writeErr(w, serverError("failed to read loan", err))
+		return
+	}
+
+	opened, err := time.Parse("2006-01-02", l.OpeningDate)
+	if err != nil {
+		writeErr(w, serverError("account has an invalid opening_date", err))
+		return
+	}
+	termEnd := addMonthsClamped(opened, int(l.TermMonths))
+	level := levelLoanPayment(l.PrincipalCents, l.AprBps, l.TermMonths)
+
+	var payments []loanPayment
+	if l.PaymentScheduleID == nil {
+		for k := 1; k <= int(l.TermMonths); k++ {
+			payments = append(payments, loanPayment{
+				Date:        addMonthsClamped(opened, k).Format("2006-01-02"),
+				AmountCents: level,
+				Final:       k == int(l.TermMonths),
+			})
+		}
+	} else {
+		rows, err := s.db.Query(`
+			SELECT entry_date, COALESCE(dest_amount_cents, amount_cents)
+			FROM entry
+			WHERE schedule_id = ? AND dest_account_id = ? AND entry_date > ?
+			ORDER BY entry_date, id
+		`, *l.PaymentScheduleID, id, l.OpeningDate)
+		if err != nil {
+			writeErr(w, serverError("failed to query loan payments", err))
+			return
+		}
+		for rows.Next() {
+			p := loanPayment{Posted: true}
+			if err := rows.Scan(&p.Date, &p.AmountCents); err != nil {
+				rows.Close()
+				writeErr(w, serverError("failed to read loan payments", err))
+				return
+			}
+			payments = append(payments, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			writeErr(w, serverError("failed to read loan payments", err))
+			return
+		}
+		// Allow for the last occurrence rolling past the term to a business day.
+		to := termEnd.AddDate(0, 0, 14).Format("2006-01-02")
+		occs, err := s.listOccurrences(ledgerID, 0, opened.AddDate(0, 0, 1).Format("2006-01-02"), to)
+		if err != nil {
+			writeErr(w, serverError("failed to compute loan payments", err))
+			return
+		}
+		payments = mergeLoanPayments(payments, loanOccurrences([]loanAccount{l}, occs)[id])
+	}
+
+	schedule := amortize(l.PrincipalCents, l.OpeningDate, payments, l.AprBps, l.Compound)
+	remaining := l.PrincipalCents
+	var totalInterest, totalPaid int64
+	var payoff *string
+	for i, row := range schedule {
+		totalInterest += row.InterestCents
+		totalPaid += row.PaymentCents
+		remaining = row.BalanceCents
+		if remaining == 0 {
+			payoff = &schedule[i].Date
+		}
+	}
+	if schedule == nil {
+		schedule = []amortizationRow{}
+	}
+	writeOK(w, map[string]any{
+		"account_id":              id,
+		"principal_cents":         l.PrincipalCents,
+		"term_months":             l.TermMonths,
+		"term_end_date":           termEnd.Format("2006-01-02"),
+		"interest_apr_bps":        l.AprBps,
+		"interest_compound":       l.Compound,
+		"payment_schedule_id":     l.PaymentScheduleID,
+		"level_payment_cents":     level,
+		"payments":                schedule,
+		"total_interest_cents":    totalInterest,
+		"total_paid_cents":        totalPaid,
+		"remaining_balance_cents": remaining,
+		"payoff_date":             payoff,
+	})
+}
+
+// mergeLoanPayments merges two date-sorted payment lists.
+func mergeLoanPayments(a, b []loanPayment) []loanPayment {
+	out := make([]loanPayment, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if j == len(b) || (i < len(a) && a[i].Date <= b[j].Date) {
+			out = append(out, a[i])
+			i++
+		} else {
+			out = append(out, b[j])
+			j++
+		}
+	}
+	return out
+}
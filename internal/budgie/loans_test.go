@@ -0,0 +1,197 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLevelLoanAmortization(t *testing.T) {
+	opened := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	payments := []loanPayment{}
+	level := levelLoanPayment(120000, 1200, 12)
+	if level != 10662 {
+		t.Fatalf("level payment = %d, want 10662", level)
+	}
+	for k := 1; k <= 12; k++ {
+		payments = append(payments, loanPayment{Date: addMonthsClamped(opened, k).Format("2006-01-02"), AmountCents: level, Final: k == 12})
+	}
+	if payments[0].Date != "2026-02-28" || payments[1].Date != "2026-03-31" {
+		t.Fatalf("unexpected payment dates %v %v", payments[0].Date, payments[1].Date)
+	}
+	rows := amortize(120000, "2026-01-31", payments, 1200, "M")
+	if len(rows) != 12 {
+		t.Fatalf("expected 12 payments, got %d", len(rows))
+	}
+	if rows[0].InterestCents != 1200 || rows[0].PrincipalCents != 9462 || rows[0].BalanceCents != 110538 {
+		t.Fatalf("unexpected first payment: %+v", rows[0])
+	}
+	last := rows[11]
+	if last.BalanceCents != 0 || last.PaymentCents != last.InterestCents+last.PrincipalCents {
+		t.Fatalf("unexpected final payment: %+v", last)
+	}
+}
+
+func TestLoanAccountProjection(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	createAccount := func(payload map[string]any) *http.Response {
+		t.Helper()
+		payload["opening_date"] = "2026-01-15"
+		return doJSON(t, http.MethodPost, api.URL+"/api/accounts", payload)
+	}
+	resp := createAccount(map[string]any{"name": "Checking", "opening_balance_cents": 100000})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	resp = createAccount(map[string]any{"name": "Not a loan", "loan_principal_cents": 1000, "loan_term_months": 12})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a loan that is not a liability, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	loan := map[string]any{
+		"name": "Car loan", "opening_balance_cents": -120000, "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"loan_principal_cents": 120000, "loan_term_months": 12,
+	}
+	resp = createAccount(loan)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create loan: status %d", resp.StatusCode)
+	}
+	loanID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/accounts/"+fmtInt64(loanID)+"/amortization", nil)
+	plan := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if got := len(mustList(t, plan["payments"])); got != 12 {
+		t.Fatalf("expected 12 level payments, got %d", got)
+	}
+	if plan["payoff_date"] != "2027-01-15" || mustInt64(t, plan["remaining_balance_cents"]) != 0 {
+		t.Fatalf("unexpected level plan: %v", plan)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Car payment", "kind": "T", "amount_cents": 10662,
+		"src_account_id": checkingID, "dest_account_id": loanID,
+		"start_date": "2026-02-15", "freq": "M", "interval": 1,
+	})
+	schedID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	loan["opening_date"] = "2026-01-15"
+	loan["loan_payment_schedule_id"] = schedID + 1000
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), loan)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown payment schedule, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	loan["loan_payment_schedule_id"] = schedID
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), loan)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set payment schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	projected := func(query string) map[int64]int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-15&as_of=2026-03-15"+query, nil)
+		out := map[int64]int64{}
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			row := mustMap(t, item)
+			out[mustInt64(t, row["id"])] = mustInt64(t, row["projected_balance_cents"])
+		}
+		return out
+	}
+	// Interest is 1200 on the first payment and 1105 on the second; only
+	// the rest pays down the loan. Checking pays the full amounts.
+	want := int64(-120000 + (10662 - 1200) + (10662 - 1105))
+	if got := projected(""); got[loanID] != want || got[checkingID] != 100000-2*10662 {
+		t.Fatalf("projected balances = %v, want loan %d", got, want)
+	}
+
+	// A scenario that pays 20000 a month owes less interest on the second.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/scenarios", map[string]any{"name": "Pay faster"})
+	scenarioID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/scenarios/"+fmtInt64(scenarioID)+"/overrides", map[string]any{
+		"schedule_id": schedID, "kind": "amount", "amount_cents": 20000, "effective_date": "2026-02-01",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("add override: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	scenarioWant := int64(-120000 + (20000 - 1200) + (20000 - 1012))
+	if got := projected("&scenario_id=" + fmtInt64(scenarioID)); got[loanID] != scenarioWant || got[checkingID] != 100000-2*20000 {
+		t.Fatalf("scenario balances = %v, want loan %d", got, scenarioWant)
+	}
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances/series?mode=projected&from_date=2026-03-15&to_date=2026-03-15&include_interest=1&scenario_id="+fmtInt64(scenarioID), nil)
+	for _, item := range mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["accounts"]) {
+		a := mustMap(t, item)
+		if mustInt64(t, a["id"]) == loanID {
+			if got := mustInt64(t, mustList(t, a["balance_cents"])[0]); got != scenarioWant {
+				t.Fatalf("scenario series balance = %d, want %d", got, scenarioWant)
+			}
+		}
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances/series?mode=projected&from_date=2026-03-15&to_date=2026-03-15&include_interest=1", nil)
+	for _, item := range mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["accounts"]) {
+		a := mustMap(t, item)
+		if mustInt64(t, a["id"]) == loanID {
+			if got := mustInt64(t, mustList(t, a["balance_cents"])[0]); got != want {
+				t.Fatalf("series balance with interest = %d, want %d", got, want)
+			}
+		}
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-02-15",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post payment: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/accounts/"+fmtInt64(loanID)+"/amortization", nil)
+	plan = mustMap(t, decodeAPIResponse(t, resp).Data)
+	payments := mustList(t, plan["payments"])
+	first, second := mustMap(t, payments[0]), mustMap(t, payments[1])
+	if first["posted"] != true || mustInt64(t, first["interest_cents"]) != 1200 || second["posted"] != false || mustInt64(t, second["interest_cents"]) != 1105 {
+		t.Fatalf("unexpected amortization rows: %v %v", first, second)
+	}
+	if plan["payoff_date"] != "2027-01-15" {
+		t.Fatalf("expected payoff on the last scheduled payment, got %v", plan["payoff_date"])
+	}
+}
+
+func TestLoanFieldsSurviveAccountEdit(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Car loan", "opening_date": "2026-01-15", "opening_balance_cents": -120000, "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"loan_principal_cents": 120000, "loan_term_months": 12,
+	})
+	loanID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// What the account editor sends: no loan settings at all.
+	edit := map[string]any{
+		"name": "Auto loan", "opening_date": "2026-01-15", "opening_balance_cents": -120000, "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M", "exclude_from_dashboard": 0,
+	}
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), edit)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit loan: status %d", resp.StatusCode)
+	}
+	updated := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if updated["name"] != "Auto loan" || updated["loan_principal_cents"] == nil || mustInt64(t, updated["loan_principal_cents"]) != 120000 ||
+		updated["loan_term_months"] == nil || mustInt64(t, updated["loan_term_months"]) != 12 {
+		t.Fatalf("loan settings after edit = %v", updated)
+	}
+
+	// Sending them as null still turns loan mode off.
+	edit["loan_principal_cents"], edit["loan_term_months"] = nil, nil
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), edit)
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["loan_principal_cents"] != nil || updated["loan_term_months"] != nil {
+		t.Fatalf("loan settings after clearing = %v", updated)
+	}
+}
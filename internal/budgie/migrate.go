@@ -1,6 +1,7 @@
 package budgie
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
@@ -12,7 +13,22 @@ import (
 var migrationsFS embed.FS
 
 func runMigrations(db *sql.DB) error {
-	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS _migrations (
+	ctx := context.Background()
+
+	// Migrations that rebuild tables must run with foreign key enforcement off
+	// (SQLite ignores the pragma inside a transaction), so pin one connection
+	// and verify integrity with foreign_key_check before each commit.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire migration connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("disable foreign keys: %w", err)
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS _migrations (
 		name       TEXT PRIMARY KEY,
 		applied_at TEXT NOT NULL DEFAULT (datetime('now'))
 	)`); err != nil {
@@ -36,7 +52,7 @@ func runMigrations(db *sql.DB) error {
 		name := e.Name()
 
 		var count int
-		if err := db.QueryRow("SELECT COUNT(*) FROM _migrations WHERE name = ?", name).Scan(&count); err != nil {
+		if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM _migrations WHERE name = ?", name).Scan(&count); err != nil {
 			return fmt.Errorf("check migration %s: %w", name, err)
 		}
 		if count > 0 {
@@ -48,7 +64,7 @@ func runMigrations(db *sql.DB) error {
 			return fmt.Errorf("read migration %s: %w", name, err)
 		}
 
-		tx, err := db.Begin()
+		tx, err := conn.BeginTx(ctx, nil)
 		if err != nil {
 			return fmt.Errorf("begin tx for %s: %w", name, err)
 		}
@@ -56,6 +72,10 @@ func runMigrations(db *sql.DB) error {
 			_ = tx.Rollback()
 			return fmt.Errorf("apply migration %s: %w", name, err)
 		}
+		if err := checkForeignKeys(tx); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("apply migration %s: %w", name, err)
+		}
 		if _, err := tx.Exec("INSERT INTO _migrations (name) VALUES (?)", name); err != nil {
 			_ = tx.Rollback()
 			return fmt.Errorf("record migration %s: %w", name, err)
@@ -66,3 +86,22 @@ func runMigrations(db *sql.DB) error {
 	}
 	return nil
 }
+
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table string
+		var rowid sql.NullInt64
+		var parent string
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation in %s (rowid %d) referencing %s", table, rowid.Int64, parent)
+	}
+	return rows.Err()
+}
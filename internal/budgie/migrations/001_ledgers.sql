@@ -0,0 +1,143 @@
+-- Ledgers (households)
+-- Every financial row (account, entry, schedule) is owned by a ledger.
+-- Schedule revisions are owned through their schedule.
+-- Users see a ledger's data only while they are a member of it.
+
+CREATE TABLE IF NOT EXISTS ledger (
+  id          INTEGER PRIMARY KEY,
+  name        TEXT    NOT NULL,
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS ledger_member (
+  ledger_id   INTEGER NOT NULL,
+  user_id     INTEGER NOT NULL,
+  role        TEXT    NOT NULL DEFAULT 'member', -- 'owner' | 'member'
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  PRIMARY KEY (ledger_id, user_id),
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (user_id)   REFERENCES user(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (role IN ('owner', 'member'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_member_user ON ledger_member(user_id);
+
+-- Existing data moves into the default ledger, owned by the first user (if any).
+-- When no user exists yet, the first user to sign in claims it.
+INSERT INTO ledger (id, name) VALUES (1, 'Default');
+INSERT INTO ledger_member (ledger_id, user_id, role)
+SELECT 1, id, 'owner' FROM user ORDER BY id LIMIT 1;
+
+-- ----
+-- Accounts are rebuilt so account names are unique per ledger instead of globally.
+-- ----
+DROP VIEW IF EXISTS v_account_balance_actual;
+
+CREATE TABLE account_new (
+  id                   INTEGER PRIMARY KEY,
+  ledger_id            INTEGER NOT NULL DEFAULT 1,
+  name                 TEXT    NOT NULL,
+  opening_date         TEXT    NOT NULL, -- ISO-8601 date: YYYY-MM-DD
+  opening_balance_cents INTEGER NOT NULL DEFAULT 0,
+  description          TEXT,
+  archived_at          TEXT, -- NULL = active; otherwise ISO date/time
+
+  -- Account metadata
+  -- Loans/credit cards are typically liabilities (their balances are often negative).
+  -- Interest-bearing accounts can accrue interest in projections.
+  is_liability          INTEGER NOT NULL DEFAULT 0,
+  is_interest_bearing   INTEGER NOT NULL DEFAULT 0,
+  interest_apr_bps      INTEGER,                 -- APR in basis points (18.99% -> 1899)
+  interest_compound     TEXT    NOT NULL DEFAULT 'D', -- 'D' daily (default) | 'M' monthly
+  exclude_from_dashboard INTEGER NOT NULL DEFAULT 0,
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  UNIQUE (ledger_id, name),
+  CHECK (opening_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'),
+  CHECK (is_liability IN (0, 1)),
+  CHECK (is_interest_bearing IN (0, 1)),
+  CHECK (interest_apr_bps IS NULL OR interest_apr_bps >= 0),
+  CHECK (interest_compound IN ('D', 'M')),
+  CHECK (exclude_from_dashboard IN (0, 1))
+);
+
+INSERT INTO account_new (
+  id, ledger_id, name, opening_date, opening_balance_cents, description, archived_at,
+  is_liability, is_interest_bearing, interest_apr_bps, interest_compound, exclude_from_dashboard
+)
+SELECT
+  id, 1, name, opening_date, opening_balance_cents, description, archived_at,
+  is_liability, is_interest_bearing, interest_apr_bps, interest_compound, exclude_from_dashboard
+FROM account;
+
+DROP TABLE account;
+ALTER TABLE account_new RENAME TO account;
+
+CREATE INDEX IF NOT EXISTS idx_account_archived_at ON account(archived_at);
+CREATE INDEX IF NOT EXISTS idx_account_ledger ON account(ledger_id);
+
+-- ----
+-- Entries and schedules
+-- ----
+ALTER TABLE entry ADD COLUMN ledger_id INTEGER NOT NULL DEFAULT 1
+  REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE schedule ADD COLUMN ledger_id INTEGER NOT NULL DEFAULT 1
+  REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_entry_ledger ON entry(ledger_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_ledger ON schedule(ledger_id);
+
+-- The ledger a session is currently working in (NULL = user's first ledger).
+ALTER TABLE auth_session ADD COLUMN ledger_id INTEGER
+  REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+-- ----
+-- Views
+-- ----
+DROP VIEW IF EXISTS v_entry_delta;
+
+CREATE VIEW v_entry_delta AS
+SELECT
+  e.id            AS entry_id,
+  e.ledger_id     AS ledger_id,
+  e.entry_date    AS entry_date,
+  e.name          AS name,
+  e.description   AS description,
+  e.schedule_id   AS schedule_id,
+  e.src_account_id  AS account_id,
+  -e.amount_cents AS delta_cents
+FROM entry e
+WHERE e.src_account_id IS NOT NULL
+
+UNION ALL
+
+SELECT
+  e.id            AS entry_id,
+  e.ledger_id     AS ledger_id,
+  e.entry_date    AS entry_date,
+  e.name          AS name,
+  e.description   AS description,
+  e.schedule_id   AS schedule_id,
+  e.dest_account_id AS account_id,
+  e.amount_cents  AS delta_cents
+FROM entry e
+WHERE e.dest_account_id IS NOT NULL;
+
+CREATE VIEW v_account_balance_actual AS
+SELECT
+  a.id,
+  a.ledger_id,
+  a.name,
+  a.opening_date,
+  a.opening_balance_cents,
+  a.description,
+  a.archived_at,
+  a.opening_balance_cents + COALESCE(SUM(d.delta_cents), 0) AS balance_cents
+FROM account a
+LEFT JOIN v_entry_delta d
+  ON d.account_id = a.id
+ AND d.entry_date >= a.opening_date
+GROUP BY a.id;
@@ -0,0 +1,30 @@
+-- Categories
+-- Categories nest through parent_id. Entries and schedules may point at any
+-- level of the tree; reports roll child totals up into their ancestors.
+
+CREATE TABLE IF NOT EXISTS category (
+  id          INTEGER PRIMARY KEY,
+  ledger_id   INTEGER NOT NULL DEFAULT 1,
+  parent_id   INTEGER,
+  name        TEXT    NOT NULL,
+  description TEXT,
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (parent_id) REFERENCES category(id) ON UPDATE CASCADE ON DELETE RESTRICT,
+
+  CHECK (parent_id IS NULL OR parent_id != id)
+);
+
+-- Sibling names are unique; top-level categories share the NULL parent.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_sibling_name
+  ON category(ledger_id, COALESCE(parent_id, 0), name);
+CREATE INDEX IF NOT EXISTS idx_category_parent ON category(parent_id);
+
+ALTER TABLE entry ADD COLUMN category_id INTEGER
+  REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE schedule ADD COLUMN category_id INTEGER
+  REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_entry_category ON entry(category_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_category ON schedule(category_id);
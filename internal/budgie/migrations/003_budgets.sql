@@ -0,0 +1,26 @@
+-- Monthly category budgets
+-- A budget plans spending for one category (including its subcategories) in
+-- one calendar month. The rollover policy decides what moves into next month:
+--   'none'    nothing carries over
+--   'surplus' unspent money carries over; overspending does not
+--   'both'    surplus and overspending both carry over
+CREATE TABLE IF NOT EXISTS budget (
+  id            INTEGER PRIMARY KEY,
+  ledger_id     INTEGER NOT NULL DEFAULT 1,
+  category_id   INTEGER NOT NULL,
+  month         TEXT    NOT NULL, -- YYYY-MM
+  planned_cents INTEGER NOT NULL,
+  rollover      TEXT    NOT NULL DEFAULT 'none',
+  description   TEXT,
+  created_at    TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id)   REFERENCES ledger(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (category_id) REFERENCES category(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (month GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]'),
+  CHECK (planned_cents >= 0),
+  CHECK (rollover IN ('none', 'surplus', 'both')),
+  UNIQUE (category_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_ledger_month ON budget(ledger_id, month);
@@ -0,0 +1,55 @@
+-- Split transactions
+-- An entry may be split into lines that share its money movement but carry
+-- their own category and memo. Lines must sum to the entry's amount_cents
+-- (enforced by the API). Balances keep using the entry itself; category
+-- reports read v_entry_category_line instead.
+
+CREATE TABLE IF NOT EXISTS entry_split (
+  id           INTEGER PRIMARY KEY,
+  entry_id     INTEGER NOT NULL,
+  category_id  INTEGER,
+  amount_cents INTEGER NOT NULL,
+  memo         TEXT,
+  position     INTEGER NOT NULL DEFAULT 0,
+
+  FOREIGN KEY (entry_id)    REFERENCES entry(id)    ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (category_id) REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+  CHECK (amount_cents > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_split_entry ON entry_split(entry_id, position);
+CREATE INDEX IF NOT EXISTS idx_entry_split_category ON entry_split(category_id);
+
+-- One row per categorized amount: the split lines of split entries, and the
+-- entry itself otherwise.
+CREATE VIEW IF NOT EXISTS v_entry_category_line AS
+SELECT
+  e.id              AS entry_id,
+  e.ledger_id       AS ledger_id,
+  e.entry_date      AS entry_date,
+  e.src_account_id  AS src_account_id,
+  e.dest_account_id AS dest_account_id,
+  e.schedule_id     AS schedule_id,
+  e.category_id     AS category_id,
+  e.amount_cents    AS amount_cents,
+  NULL              AS split_id,
+  NULL              AS memo
+FROM entry e
+WHERE NOT EXISTS (SELECT 1 FROM entry_split sp WHERE sp.entry_id = e.id)
+
+UNION ALL
+
+SELECT
+  e.id              AS entry_id,
+  e.ledger_id       AS ledger_id,
+  e.entry_date      AS entry_date,
+  e.src_account_id  AS src_account_id,
+  e.dest_account_id AS dest_account_id,
+  e.schedule_id     AS schedule_id,
+  sp.category_id    AS category_id,
+  sp.amount_cents   AS amount_cents,
+  sp.id             AS split_id,
+  sp.memo           AS memo
+FROM entry e
+JOIN entry_split sp ON sp.entry_id = e.id;
@@ -0,0 +1,60 @@
+-- Currencies
+-- Every account holds one ISO-4217 currency. Amounts stay integer minor units
+-- of the account's own currency; reports convert with fx_rate on demand.
+
+ALTER TABLE account ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD'
+  CHECK (length(currency) = 3 AND currency = upper(currency));
+
+-- Transfers between accounts of different currencies record what arrived in
+-- the destination. NULL means the destination received amount_cents.
+ALTER TABLE entry ADD COLUMN dest_amount_cents INTEGER CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0);
+ALTER TABLE schedule ADD COLUMN dest_amount_cents INTEGER CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0);
+ALTER TABLE schedule_revision ADD COLUMN dest_amount_cents INTEGER CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0);
+
+-- One unit of base_currency is worth rate units of quote_currency from
+-- rate_date until the next rate for the same pair.
+CREATE TABLE IF NOT EXISTS fx_rate (
+  id             INTEGER PRIMARY KEY,
+  ledger_id      INTEGER NOT NULL DEFAULT 1,
+  base_currency  TEXT    NOT NULL,
+  quote_currency TEXT    NOT NULL,
+  rate_date      TEXT    NOT NULL, -- ISO-8601 date
+  rate           REAL    NOT NULL,
+  created_at     TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (rate_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'),
+  CHECK (rate > 0),
+  CHECK (base_currency != quote_currency),
+  UNIQUE (ledger_id, base_currency, quote_currency, rate_date)
+);
+
+DROP VIEW IF EXISTS v_entry_delta;
+
+CREATE VIEW v_entry_delta AS
+SELECT
+  e.id            AS entry_id,
+  e.ledger_id     AS ledger_id,
+  e.entry_date    AS entry_date,
+  e.name          AS name,
+  e.description   AS description,
+  e.schedule_id   AS schedule_id,
+  e.src_account_id  AS account_id,
+  -e.amount_cents AS delta_cents
+FROM entry e
+WHERE e.src_account_id IS NOT NULL
+
+UNION ALL
+
+SELECT
+  e.id            AS entry_id,
+  e.ledger_id     AS ledger_id,
+  e.entry_date    AS entry_date,
+  e.name          AS name,
+  e.description   AS description,
+  e.schedule_id   AS schedule_id,
+  e.dest_account_id AS account_id,
+  COALESCE(e.dest_amount_cents, e.amount_cents) AS delta_cents
+FROM entry e
+WHERE e.dest_account_id IS NOT NULL;
@@ -0,0 +1,69 @@
+-- Investments
+-- Securities are held in accounts through buy/sell transactions. Each
+-- transaction also records its cash side as an entry on the same account, so
+-- cash balances keep coming from entries while positions are valued from
+-- security_price (falling back to the latest traded price).
+
+CREATE TABLE IF NOT EXISTS security (
+  id          INTEGER PRIMARY KEY,
+  ledger_id   INTEGER NOT NULL DEFAULT 1,
+  symbol      TEXT    NOT NULL,
+  name        TEXT,
+  currency    TEXT    NOT NULL DEFAULT 'USD',
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (length(currency) = 3 AND currency = upper(currency)),
+  UNIQUE (ledger_id, symbol)
+);
+
+-- Closing price per unit, in cents of the security's currency.
+CREATE TABLE IF NOT EXISTS security_price (
+  id          INTEGER PRIMARY KEY,
+  security_id INTEGER NOT NULL,
+  price_date  TEXT    NOT NULL, -- ISO-8601 date
+  price_cents INTEGER NOT NULL,
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (security_id) REFERENCES security(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (price_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'),
+  CHECK (price_cents >= 0),
+  UNIQUE (security_id, price_date)
+);
+
+-- kind: 'B' buy, 'S' sell, 'D' dividend
+-- Buys and sells carry quantity and price_cents (per unit); dividends carry
+-- amount_cents. fee_cents is added to a buy's cost and taken from a sell's
+-- proceeds.
+CREATE TABLE IF NOT EXISTS investment_txn (
+  id           INTEGER PRIMARY KEY,
+  ledger_id    INTEGER NOT NULL DEFAULT 1,
+  account_id   INTEGER NOT NULL,
+  security_id  INTEGER NOT NULL,
+  txn_date     TEXT    NOT NULL, -- ISO-8601 date
+  kind         TEXT    NOT NULL,
+  quantity     REAL    NOT NULL DEFAULT 0,
+  price_cents  INTEGER NOT NULL DEFAULT 0,
+  fee_cents    INTEGER NOT NULL DEFAULT 0,
+  amount_cents INTEGER NOT NULL DEFAULT 0,
+  entry_id     INTEGER,
+  description  TEXT,
+  created_at   TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id)   REFERENCES ledger(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (account_id)  REFERENCES account(id)  ON UPDATE CASCADE ON DELETE RESTRICT,
+  FOREIGN KEY (security_id) REFERENCES security(id) ON UPDATE CASCADE ON DELETE RESTRICT,
+  FOREIGN KEY (entry_id)    REFERENCES entry(id)    ON UPDATE CASCADE ON DELETE SET NULL,
+
+  CHECK (txn_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'),
+  CHECK (kind IN ('B', 'S', 'D')),
+  CHECK (quantity >= 0),
+  CHECK (price_cents >= 0),
+  CHECK (fee_cents >= 0),
+  CHECK (amount_cents >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_investment_txn_account ON investment_txn(account_id, security_id, txn_date);
+CREATE INDEX IF NOT EXISTS idx_investment_txn_entry ON investment_txn(entry_id);
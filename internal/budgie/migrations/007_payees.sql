@@ -0,0 +1,41 @@
+-- Payees
+-- A payee groups the many spellings a bank uses for one counterparty.
+-- Aliases are matched on match_key, a normalized form of the text (see
+-- payeeMatchKey); the payee's own name is stored as an alias too.
+
+CREATE TABLE IF NOT EXISTS payee (
+  id                  INTEGER PRIMARY KEY,
+  ledger_id           INTEGER NOT NULL DEFAULT 1,
+  name                TEXT    NOT NULL,
+  default_category_id INTEGER,
+  created_at          TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id)           REFERENCES ledger(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (default_category_id) REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+  UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS payee_alias (
+  id         INTEGER PRIMARY KEY,
+  ledger_id  INTEGER NOT NULL DEFAULT 1,
+  payee_id   INTEGER NOT NULL,
+  alias      TEXT    NOT NULL,
+  match_key  TEXT    NOT NULL,
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (payee_id)  REFERENCES payee(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (match_key != ''),
+  UNIQUE (ledger_id, match_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payee_alias_payee ON payee_alias(payee_id);
+
+ALTER TABLE entry ADD COLUMN payee_id INTEGER
+  REFERENCES payee(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE schedule ADD COLUMN payee_id INTEGER
+  REFERENCES payee(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_entry_payee ON entry(payee_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_payee ON schedule(payee_id);
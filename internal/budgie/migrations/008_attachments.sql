@@ -0,0 +1,32 @@
+-- Attachments
+-- Receipts, invoices and statements linked to an entry or an account.
+-- Content lives either inline in data (storage = 'db') or in the configured
+-- attachments directory under a content-addressed path (storage = 'dir').
+
+CREATE TABLE IF NOT EXISTS attachment (
+  id          INTEGER PRIMARY KEY,
+  ledger_id   INTEGER NOT NULL DEFAULT 1,
+  entry_id    INTEGER,
+  account_id  INTEGER,
+  filename    TEXT    NOT NULL,
+  mime_type   TEXT    NOT NULL,
+  size_bytes  INTEGER NOT NULL,
+  sha256      TEXT    NOT NULL,
+  storage     TEXT    NOT NULL DEFAULT 'db',
+  data        BLOB,
+  path        TEXT,
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id)  REFERENCES ledger(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (entry_id)   REFERENCES entry(id)   ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (account_id) REFERENCES account(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK ((entry_id IS NULL) != (account_id IS NULL)),
+  CHECK (storage IN ('db', 'dir')),
+  CHECK ((storage = 'db' AND data IS NOT NULL) OR (storage = 'dir' AND path IS NOT NULL)),
+  CHECK (size_bytes >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_entry ON attachment(entry_id);
+CREATE INDEX IF NOT EXISTS idx_attachment_account ON attachment(account_id);
+CREATE INDEX IF NOT EXISTS idx_attachment_sha ON attachment(sha256);
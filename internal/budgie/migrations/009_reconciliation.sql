@@ -0,0 +1,35 @@
+-- Reconciliation
+-- Entries move pending -> cleared (seen on a statement) -> reconciled (locked
+-- by a finished reconciliation). A reconciliation compares the account's
+-- cleared balance with a statement's ending balance; finished sessions are
+-- kept as an audit trail.
+
+CREATE TABLE IF NOT EXISTS reconciliation (
+  id                      INTEGER PRIMARY KEY,
+  ledger_id               INTEGER NOT NULL DEFAULT 1,
+  account_id              INTEGER NOT NULL,
+  statement_date          TEXT    NOT NULL,
+  statement_balance_cents INTEGER NOT NULL,
+  status                  TEXT    NOT NULL DEFAULT 'open',
+  cleared_balance_cents   INTEGER,
+  adjustment_entry_id     INTEGER,
+  created_at              TEXT    NOT NULL DEFAULT (datetime('now')),
+  finished_at             TEXT,
+
+  FOREIGN KEY (ledger_id)           REFERENCES ledger(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (account_id)          REFERENCES account(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (adjustment_entry_id) REFERENCES entry(id)   ON UPDATE CASCADE ON DELETE SET NULL,
+
+  CHECK (status IN ('open', 'finished')),
+  CHECK ((status = 'finished') = (finished_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_account ON reconciliation(account_id, statement_date);
+CREATE UNIQUE INDEX IF NOT EXISTS ux_reconciliation_open ON reconciliation(account_id) WHERE status = 'open';
+
+ALTER TABLE entry ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+  CHECK (status IN ('pending', 'cleared', 'reconciled'));
+ALTER TABLE entry ADD COLUMN reconciliation_id INTEGER
+  REFERENCES reconciliation(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_entry_reconciliation ON entry(reconciliation_id);
@@ -0,0 +1,10 @@
+-- Posted occurrences
+-- An entry created from a schedule occurrence remembers which occurrence it
+-- settles, so the projection can skip it even when the entry is dated
+-- differently. Each occurrence can be posted once.
+
+ALTER TABLE entry ADD COLUMN occurrence_date TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_entry_occurrence
+  ON entry(schedule_id, occurrence_date)
+  WHERE schedule_id IS NOT NULL AND occurrence_date IS NOT NULL;
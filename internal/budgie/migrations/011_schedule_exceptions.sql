@@ -0,0 +1,35 @@
+-- Schedule exceptions
+-- Per-occurrence changes to a schedule, keyed by the date the occurrence
+-- would normally fall on (occ_date):
+--   skip     drop the occurrence
+--   move     pay it on move_to_date instead (amount override optional)
+--   amount   pay amount_cents (and dest_amount_cents) this once
+--   suspend  drop every occurrence from occ_date through end_date
+
+CREATE TABLE IF NOT EXISTS schedule_exception (
+  id                INTEGER PRIMARY KEY,
+  schedule_id       INTEGER NOT NULL,
+  kind              TEXT    NOT NULL,
+  occ_date          TEXT    NOT NULL,
+  end_date          TEXT,
+  move_to_date      TEXT,
+  amount_cents      INTEGER,
+  dest_amount_cents INTEGER,
+  note              TEXT,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (schedule_id) REFERENCES schedule(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (kind IN ('skip', 'move', 'amount', 'suspend')),
+  CHECK ((kind = 'suspend') = (end_date IS NOT NULL)),
+  CHECK (end_date IS NULL OR end_date >= occ_date),
+  CHECK ((kind = 'move') = (move_to_date IS NOT NULL)),
+  CHECK (kind IN ('move', 'amount') OR (amount_cents IS NULL AND dest_amount_cents IS NULL)),
+  CHECK (kind != 'amount' OR amount_cents IS NOT NULL),
+  CHECK (amount_cents IS NULL OR amount_cents > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_schedule_exception_occ
+  ON schedule_exception(schedule_id, occ_date)
+  WHERE kind != 'suspend';
+CREATE INDEX IF NOT EXISTS idx_schedule_exception_schedule ON schedule_exception(schedule_id, kind);
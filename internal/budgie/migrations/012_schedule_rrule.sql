@@ -0,0 +1,16 @@
+-- RRULE schedules
+-- A schedule with an rrule (RFC 5545 subset, see rrule.go) ignores
+-- bymonthday/byweekday; freq and interval mirror the rule. Its dates are
+-- expanded when the schedule is saved and stored in schedule_rrule_date,
+-- which the occurrence CTE reads instead of the freq recursion.
+
+ALTER TABLE schedule ADD COLUMN rrule TEXT;
+
+CREATE TABLE IF NOT EXISTS schedule_rrule_date (
+  schedule_id INTEGER NOT NULL,
+  occ_date    TEXT    NOT NULL,
+
+  FOREIGN KEY (schedule_id) REFERENCES schedule(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  PRIMARY KEY (schedule_id, occ_date)
+) WITHOUT ROWID;
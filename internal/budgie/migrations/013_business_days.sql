@@ -0,0 +1,28 @@
+-- Business days
+-- Occurrences landing on a weekend or holiday can roll to a business day:
+--   none                keep the date
+--   following           next business day
+--   preceding           previous business day
+--   modified_following  next business day unless that leaves the month,
+--                       then the previous one
+-- holiday_calendar limits which holidays apply; NULL means all of the
+-- ledger's holidays.
+
+ALTER TABLE schedule ADD COLUMN business_day_roll TEXT NOT NULL DEFAULT 'none'
+  CHECK (business_day_roll IN ('none', 'following', 'preceding', 'modified_following'));
+ALTER TABLE schedule ADD COLUMN holiday_calendar TEXT;
+
+CREATE TABLE IF NOT EXISTS holiday (
+  id           INTEGER PRIMARY KEY,
+  ledger_id    INTEGER NOT NULL DEFAULT 1,
+  calendar     TEXT    NOT NULL DEFAULT 'default',
+  holiday_date TEXT    NOT NULL,
+  name         TEXT,
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (calendar != ''),
+  UNIQUE (ledger_id, calendar, holiday_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holiday_date ON holiday(ledger_id, holiday_date);
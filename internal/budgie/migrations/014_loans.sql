@@ -0,0 +1,9 @@
+-- Loans
+-- A liability account in loan mode records how it started: the original
+-- principal and term (in months), and optionally the schedule that pays it.
+-- Loan mode is on when loan_principal_cents is set. Projections credit the
+-- account with only the principal portion of each scheduled payment.
+
+ALTER TABLE account ADD COLUMN loan_principal_cents INTEGER CHECK (loan_principal_cents IS NULL OR loan_principal_cents > 0);
+ALTER TABLE account ADD COLUMN loan_term_months INTEGER CHECK (loan_term_months IS NULL OR loan_term_months > 0);
+ALTER TABLE account ADD COLUMN loan_payment_schedule_id INTEGER REFERENCES schedule(id) ON DELETE SET NULL;
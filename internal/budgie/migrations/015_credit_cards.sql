@@ -0,0 +1,18 @@
+-- Credit cards
+-- A liability account with card_closing_day set is a credit card with monthly
+-- statement cycles. Statements are due card_due_offset_days after closing;
+-- paying the statement balance within card_grace_days avoids interest. The
+-- minimum payment is the greater of card_min_payment_cents and
+-- card_min_payment_bps of the balance (plus the cycle's interest when
+-- card_min_payment_plus_interest = 1), capped at the balance.
+-- card_autopay makes projections pay each statement ('full') or its minimum
+-- ('minimum') from card_autopay_account_id on the due date.
+
+ALTER TABLE account ADD COLUMN card_closing_day INTEGER CHECK (card_closing_day IS NULL OR card_closing_day BETWEEN 1 AND 31);
+ALTER TABLE account ADD COLUMN card_grace_days INTEGER;
+ALTER TABLE account ADD COLUMN card_due_offset_days INTEGER;
+ALTER TABLE account ADD COLUMN card_min_payment_cents INTEGER;
+ALTER TABLE account ADD COLUMN card_min_payment_bps INTEGER;
+ALTER TABLE account ADD COLUMN card_min_payment_plus_interest INTEGER;
+ALTER TABLE account ADD COLUMN card_autopay TEXT NOT NULL DEFAULT 'none' CHECK (card_autopay IN ('none', 'full', 'minimum'));
+ALTER TABLE account ADD COLUMN card_autopay_account_id INTEGER REFERENCES account(id) ON DELETE SET NULL;
@@ -0,0 +1,14 @@
+-- Interest postings
+-- Accounts can post accrued interest as real entries, monthly on
+-- interest_posting_day or on each credit card statement closing. Generated
+-- entries record the period they cover; each account posts a period once.
+
+ALTER TABLE account ADD COLUMN interest_posting TEXT NOT NULL DEFAULT 'none' CHECK (interest_posting IN ('none', 'monthly', 'statement'));
+ALTER TABLE account ADD COLUMN interest_posting_day INTEGER CHECK (interest_posting_day IS NULL OR interest_posting_day BETWEEN 1 AND 31);
+
+ALTER TABLE entry ADD COLUMN interest_period_start TEXT;
+ALTER TABLE entry ADD COLUMN interest_period_end TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_entry_interest_period
+  ON entry(COALESCE(src_account_id, dest_account_id), interest_period_end)
+  WHERE interest_period_end IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Savings goals
+-- A goal tracks a target amount held in an account, or in a share of one
+-- (share_bps, 10000 = the whole balance), optionally by a target date.
+
+CREATE TABLE IF NOT EXISTS goal (
+  id           INTEGER PRIMARY KEY,
+  ledger_id    INTEGER NOT NULL DEFAULT 1,
+  name         TEXT    NOT NULL,
+  account_id   INTEGER NOT NULL,
+  share_bps    INTEGER NOT NULL DEFAULT 10000,
+  target_cents INTEGER NOT NULL,
+  target_date  TEXT,
+  description  TEXT,
+  created_at   TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id)  REFERENCES ledger(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (account_id) REFERENCES account(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (share_bps BETWEEN 1 AND 10000),
+  CHECK (target_cents > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_ledger ON goal(ledger_id);
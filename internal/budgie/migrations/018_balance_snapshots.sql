@@ -0,0 +1,39 @@
+-- Balance snapshots
+-- Recorded net worth history. Each snapshot holds one ledger's actual
+-- balances on snapshot_date in one currency: assets_cents sums non-liability
+-- accounts, liabilities_cents sums liability accounts (negative while owed),
+-- and net_worth_cents is their sum. Ledgers with several currencies get one
+-- snapshot per currency; reports convert with fx_rate on demand.
+
+CREATE TABLE IF NOT EXISTS balance_snapshot (
+  id                INTEGER PRIMARY KEY,
+  ledger_id         INTEGER NOT NULL DEFAULT 1,
+  snapshot_date     TEXT    NOT NULL,
+  currency          TEXT    NOT NULL,
+  assets_cents      INTEGER NOT NULL,
+  liabilities_cents INTEGER NOT NULL,
+  net_worth_cents   INTEGER NOT NULL,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  UNIQUE (ledger_id, snapshot_date, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshot_ledger ON balance_snapshot(ledger_id);
+
+-- Per-account balances behind a snapshot. The account name is kept so the
+-- history survives deleting the account.
+CREATE TABLE IF NOT EXISTS balance_snapshot_account (
+  id            INTEGER PRIMARY KEY,
+  snapshot_id   INTEGER NOT NULL,
+  account_id    INTEGER,
+  account_name  TEXT    NOT NULL,
+  is_liability  INTEGER NOT NULL DEFAULT 0 CHECK (is_liability IN (0, 1)),
+  balance_cents INTEGER NOT NULL,
+
+  FOREIGN KEY (snapshot_id) REFERENCES balance_snapshot(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (account_id)  REFERENCES account(id)          ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshot_account_snapshot ON balance_snapshot_account(snapshot_id);
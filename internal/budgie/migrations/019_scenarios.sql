@@ -0,0 +1,108 @@
+-- What-if scenarios
+-- A scenario is a named overlay on the ledger's plan. Projections run with a
+-- scenario_id see its changes; nothing here touches schedule or entry.
+--   scenario_schedule  schedules that exist only in the scenario
+--   scenario_override  changes to real schedules from effective_date on
+--                      (NULL = every occurrence):
+--                        disable  drop the occurrences
+--                        amount   pay amount_cents (and dest_amount_cents)
+--   scenario_entry     hypothetical one-off entries
+
+CREATE TABLE IF NOT EXISTS scenario (
+  id          INTEGER PRIMARY KEY,
+  ledger_id   INTEGER NOT NULL DEFAULT 1,
+  name        TEXT    NOT NULL,
+  description TEXT,
+  created_at  TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (ledger_id) REFERENCES ledger(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scenario_ledger ON scenario(ledger_id);
+
+CREATE TABLE IF NOT EXISTS scenario_schedule (
+  id                INTEGER PRIMARY KEY,
+  scenario_id       INTEGER NOT NULL,
+  name              TEXT    NOT NULL,
+  kind              TEXT    NOT NULL,
+  amount_cents      INTEGER NOT NULL,
+  src_account_id    INTEGER,
+  dest_account_id   INTEGER,
+  dest_amount_cents INTEGER,
+  start_date        TEXT    NOT NULL,
+  end_date          TEXT,
+  freq              TEXT    NOT NULL,
+  interval          INTEGER NOT NULL DEFAULT 1,
+  bymonthday        INTEGER,
+  byweekday         INTEGER,
+  business_day_roll TEXT    NOT NULL DEFAULT 'none',
+  holiday_calendar  TEXT,
+  description       TEXT,
+  category_id       INTEGER,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (scenario_id)     REFERENCES scenario(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (src_account_id)  REFERENCES account(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (dest_account_id) REFERENCES account(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (category_id)     REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+  CHECK (kind IN ('I','E','T')),
+  CHECK (freq IN ('D','W','M','Y')),
+  CHECK (interval >= 1),
+  CHECK (amount_cents > 0),
+  CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0),
+  CHECK (end_date IS NULL OR end_date >= start_date),
+  CHECK (bymonthday IS NULL OR (bymonthday BETWEEN 1 AND 31)),
+  CHECK (byweekday IS NULL OR (byweekday BETWEEN 0 AND 6)),
+  CHECK (business_day_roll IN ('none', 'following', 'preceding', 'modified_following'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scenario_schedule_scenario ON scenario_schedule(scenario_id);
+
+CREATE TABLE IF NOT EXISTS scenario_override (
+  id                INTEGER PRIMARY KEY,
+  scenario_id       INTEGER NOT NULL,
+  schedule_id       INTEGER NOT NULL,
+  kind              TEXT    NOT NULL,
+  effective_date    TEXT,
+  amount_cents      INTEGER,
+  dest_amount_cents INTEGER,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (scenario_id) REFERENCES scenario(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (schedule_id) REFERENCES schedule(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  CHECK (kind IN ('disable', 'amount')),
+  CHECK ((kind = 'amount') = (amount_cents IS NOT NULL)),
+  CHECK (kind = 'amount' OR dest_amount_cents IS NULL),
+  CHECK (amount_cents IS NULL OR amount_cents > 0),
+  CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scenario_override_lookup ON scenario_override(scenario_id, schedule_id, kind);
+
+CREATE TABLE IF NOT EXISTS scenario_entry (
+  id                INTEGER PRIMARY KEY,
+  scenario_id       INTEGER NOT NULL,
+  entry_date        TEXT    NOT NULL,
+  name              TEXT    NOT NULL,
+  amount_cents      INTEGER NOT NULL,
+  src_account_id    INTEGER,
+  dest_account_id   INTEGER,
+  dest_amount_cents INTEGER,
+  description       TEXT,
+  category_id       INTEGER,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (scenario_id)     REFERENCES scenario(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (src_account_id)  REFERENCES account(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (dest_account_id) REFERENCES account(id)  ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (category_id)     REFERENCES category(id) ON UPDATE CASCADE ON DELETE SET NULL,
+
+  CHECK (amount_cents > 0),
+  CHECK (dest_amount_cents IS NULL OR dest_amount_cents > 0),
+  CHECK (src_account_id IS NOT NULL OR dest_account_id IS NOT NULL),
+  CHECK (src_account_id IS NULL OR dest_account_id IS NULL OR src_account_id != dest_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scenario_entry_scenario ON scenario_entry(scenario_id);
@@ -0,0 +1,8 @@
+-- Schedule variance
+-- How much a schedule's amounts can vary, for simulated projections:
+--   fixed    always the scheduled amount (the default)
+--   percent  uniformly within +/- variance_bps of the scheduled amount
+--   history  resampled from the spread of the schedule's linked entries
+
+ALTER TABLE schedule ADD COLUMN variance_model TEXT NOT NULL DEFAULT 'fixed' CHECK (variance_model IN ('fixed', 'percent', 'history'));
+ALTER TABLE schedule ADD COLUMN variance_bps INTEGER CHECK (variance_bps IS NULL OR variance_bps BETWEEN 1 AND 10000);
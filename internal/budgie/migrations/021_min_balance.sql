@@ -0,0 +1,5 @@
+-- Minimum balances
+-- An account's balance should stay at or above min_balance_cents. NULL means
+-- no threshold; forecasts then watch asset accounts for going below zero.
+
+ALTER TABLE account ADD COLUMN min_balance_cents INTEGER;
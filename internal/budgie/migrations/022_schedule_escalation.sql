@@ -0,0 +1,18 @@
+-- Schedule escalation
+-- A schedule's amount can step up (or down) over time instead of needing a
+-- revision for every change:
+--   none     the amount stays as scheduled (the default)
+--   percent  each step multiplies the amount by 1 + escalation_bps / 10000
+--   fixed    each step adds escalation_cents
+-- Steps happen every escalation_every occurrences, or on each
+-- escalation_anniversary ('MM-DD') after the start date. Each stepped amount is
+-- rounded to a multiple of escalation_round_cents (default 1). A revision
+-- replaces the escalated amount from its effective date and later steps
+-- build on it.
+
+ALTER TABLE schedule ADD COLUMN escalation_kind TEXT NOT NULL DEFAULT 'none' CHECK (escalation_kind IN ('none', 'percent', 'fixed'));
+ALTER TABLE schedule ADD COLUMN escalation_bps INTEGER CHECK (escalation_bps IS NULL OR escalation_bps > -10000);
+ALTER TABLE schedule ADD COLUMN escalation_cents INTEGER;
+ALTER TABLE schedule ADD COLUMN escalation_every INTEGER CHECK (escalation_every IS NULL OR escalation_every >= 1);
+ALTER TABLE schedule ADD COLUMN escalation_anniversary TEXT CHECK (escalation_anniversary IS NULL OR escalation_anniversary GLOB '[0-1][0-9]-[0-3][0-9]');
+ALTER TABLE schedule ADD COLUMN escalation_round_cents INTEGER CHECK (escalation_round_cents IS NULL OR escalation_round_cents >= 1);
@@ -0,0 +1,39 @@
+-- Schedule versions
+-- A version changes a schedule from effective_date on, keeping its id so
+-- posted entries stay linked. The occurrence engine uses the version in force
+-- on each occurrence date for accounts and recurrence:
+--   - a version that changes freq, interval, bymonthday or byweekday starts
+--     the recurrence afresh from its effective date; otherwise the existing
+--     dates carry on
+--   - amount_cents NULL keeps the amount in force (revisions and escalation
+--     included); otherwise it sets the amount like a revision, and the later
+--     of the two wins
+
+CREATE TABLE IF NOT EXISTS schedule_version (
+  id                INTEGER PRIMARY KEY,
+  schedule_id       INTEGER NOT NULL,
+  effective_date    TEXT    NOT NULL,
+  amount_cents      INTEGER,
+  dest_amount_cents INTEGER,
+  src_account_id    INTEGER,
+  dest_account_id   INTEGER,
+  freq              TEXT    NOT NULL,
+  interval          INTEGER NOT NULL DEFAULT 1,
+  bymonthday        INTEGER,
+  byweekday         INTEGER,
+  description       TEXT,
+  created_at        TEXT    NOT NULL DEFAULT (datetime('now')),
+
+  FOREIGN KEY (schedule_id)     REFERENCES schedule(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  FOREIGN KEY (src_account_id)  REFERENCES account(id)  ON UPDATE CASCADE ON DELETE RESTRICT,
+  FOREIGN KEY (dest_account_id) REFERENCES account(id)  ON UPDATE CASCADE ON DELETE RESTRICT,
+
+  CHECK (effective_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'),
+  CHECK (amount_cents IS NULL OR amount_cents > 0),
+  CHECK (dest_amount_cents IS NULL OR (amount_cents IS NOT NULL AND dest_amount_cents > 0)),
+  CHECK (freq IN ('D', 'W', 'M', 'Y')),
+  CHECK (interval >= 1),
+  CHECK (bymonthday IS NULL OR bymonthday BETWEEN 1 AND 31),
+  CHECK (byweekday IS NULL OR byweekday BETWEEN 0 AND 6),
+  UNIQUE (schedule_id, effective_date)
+);
@@ -0,0 +1,8 @@
+-- Schedule termination
+-- max_occurrences ends a schedule after that many occurrences (posted ones
+-- included; skipped and suspended ones do not count). stop_when_paid_off ends
+-- a schedule paying into a liability once that liability's projected balance
+-- reaches zero, cutting the last payment down to what is owed.
+
+ALTER TABLE schedule ADD COLUMN max_occurrences INTEGER CHECK (max_occurrences IS NULL OR max_occurrences >= 1);
+ALTER TABLE schedule ADD COLUMN stop_when_paid_off INTEGER NOT NULL DEFAULT 0 CHECK (stop_when_paid_off IN (0, 1));
@@ -0,0 +1,10 @@
+-- Ledger claim
+-- The default ledger holds the data from before ledgers existed. While no
+-- user owns it, claimable = 1 and the first user to sign in takes it over,
+-- clearing the flag. A ledger that later ends up without members is never
+-- handed to someone else.
+
+ALTER TABLE ledger ADD COLUMN claimable INTEGER NOT NULL DEFAULT 0 CHECK (claimable IN (0, 1));
+
+UPDATE ledger SET claimable = 1
+WHERE id = 1 AND NOT EXISTS (SELECT 1 FROM ledger_member WHERE ledger_id = 1);
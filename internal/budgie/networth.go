@@ -0,0 +1,362 @@
+package budgie
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Net worth history is recorded rather than recomputed: snapshots keep the
+// balances as they were when taken, so editing an old entry does not rewrite
+// past net worth. Snapshots are taken daily for every ledger and on demand.
+
+type snapshotAccount struct {
+	AccountID    *int64 `json:"account_id"`
+	AccountName  string `json:"account_name"`
+	IsLiability  int64  `json:"is_liability"`
+	BalanceCents int64  `json:"balance_cents"`
+}
+
+type balanceSnapshot struct {
+	ID               int64             `json:"id,omitempty"`
+	SnapshotDate     string            `json:"snapshot_date"`
+	Currency         string            `json:"currency"`
+	AssetsCents      int64             `json:"assets_cents"`
+	LiabilitiesCents int64             `json:"liabilities_cents"`
+	NetWorthCents    int64             `json:"net_worth_cents"`
+	Accounts         []snapshotAccount `json:"accounts,omitempty"`
+}
+
+// snapshotBalances records a ledger's actual balances on date. Taking today's
+// snapshot again replaces it; an earlier date that already has snapshots
+// keeps them as recorded and returns them instead.
+func (s *server) snapshotBalances(ledgerID int64, date string) ([]balanceSnapshot, error) {
+	sameDay := date == time.Now().Format("2006-01-02")
+	if !sameDay {
+		stored, err := s.storedSnapshots(ledgerID, date)
+		if err != nil || len(stored) > 0 {
+			return stored, err
+		}
+	}
+	points, err := s.actualBalancesAsOf(ledgerID, date)
+	if err != nil {
+		return nil, err
+	}
+	liability := make(map[int64]int64)
+	rows, err := s.db.Query("SELECT id, is_liability FROM account WHERE ledger_id = ?", ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, isLiability int64
+		if err := rows.Scan(&id, &isLiability); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		liability[id] = isLiability
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var snaps []balanceSnapshot
+	byCurrency := make(map[string]int)
+	for _, p := range points {
+		i, ok := byCurrency[p.Currency]
+		if !ok {
+			i = len(snaps)
+			byCurrency[p.Currency] = i
+			snaps = append(snaps, balanceSnapshot{SnapshotDate: date, Currency: p.Currency})
+		}
+		snap := &snaps[i]
+		id := p.ID
+		snap.Accounts = append(snap.Accounts, snapshotAccount{AccountID: &id, AccountName: p.Name, IsLiability: liability[p.ID], BalanceCents: p.BalanceCents})
+		if liability[p.ID] == 1 {
+			snap.LiabilitiesCents += p.BalanceCents
+		} else {
+			snap.AssetsCents += p.BalanceCents
+		}
+		snap.NetWorthCents += p.BalanceCents
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if sameDay {
+		if _, err := tx.Exec("DELETE FROM balance_snapshot WHERE ledger_id = ? AND snapshot_date = ?", ledgerID, date); err != nil {
+			return nil, err
+		}
+	}
+	for i := range snaps {
+		snap := &snaps[i]
+		if err := tx.QueryRow(`
+			INSERT INTO balance_snapshot (ledger_id, snapshot_date, currency, assets_cents, liabilities_cents, net_worth_cents)
+			VALUES (?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, ledgerID, date, snap.Currency, snap.AssetsCents, snap.LiabilitiesCents, snap.NetWorthCents).Scan(&snap.ID); err != nil {
+			return nil, err
+		}
+		for _, a := range snap.Accounts {
+			if _, err := tx.Exec(
+				"INSERT INTO balance_snapshot_account (snapshot_id, account_id, account_name, is_liability, balance_cents) VALUES (?, ?, ?, ?, ?)",
+				snap.ID, a.AccountID, a.AccountName, a.IsLiability, a.BalanceCents,
+			); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return snaps, nil
+}
+
+// storedSnapshots returns the snapshots recorded for a ledger on date with
+// their accounts.
+func (s *server) storedSnapshots(ledgerID int64, date string) ([]balanceSnapshot, error) {
+	rows, err := s.db.Query(`
+		SELECT b.id, b.currency, b.assets_cents, b.liabilities_cents, b.net_worth_cents,
+			sa.account_id, sa.account_name, sa.is_liability, sa.balance_cents
+		FROM balance_snapshot b
+		JOIN balance_snapshot_account sa ON sa.snapshot_id = b.id
+		WHERE b.ledger_id = ? AND b.snapshot_date = ?
+		ORDER BY b.currency, sa.account_name
+	`, ledgerID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var snaps []balanceSnapshot
+	for rows.Next() {
+		b := balanceSnapshot{SnapshotDate: date}
+		var a snapshotAccount
+		if err := rows.Scan(&b.ID, &b.Currency, &b.AssetsCents, &b.LiabilitiesCents, &b.NetWorthCents,
+			&a.AccountID, &a.AccountName, &a.IsLiability, &a.BalanceCents); err != nil {
+			return nil, err
+		}
+		if n := len(snaps); n == 0 || snaps[n-1].ID != b.ID {
+			snaps = append(snaps, b)
+		}
+		snaps[len(snaps)-1].Accounts = append(snaps[len(snaps)-1].Accounts, a)
+	}
+	return snaps, rows.Err()
+}
+
+// SnapshotBalances records today's balances for every ledger. Running it again
+// the same day replaces that day's snapshot. A ledger that fails is logged and
+// skipped so the others still get theirs.
+func SnapshotBalances(db *sql.DB) error {
+	s := &server{db: db}
+	rows, err := db.Query("SELECT id FROM ledger ORDER BY id")
+	if err != nil {
+		return err
+	}
+	var ledgers []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ledgers = append(ledgers, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	today := time.Now().Format("2006-01-02")
+	for _, id := range ledgers {
+		if _, err := s.snapshotBalances(id, today); err != nil {
+			log.Printf("ERROR: balance snapshot of ledger %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// networthSnapshot serves POST /api/networth/snapshot, recording balances on
+// snapshot_date (default today). Past dates can be backfilled from the
+// entries as they stand now, but one already recorded is returned unchanged;
+// future dates are refused.
+//
+//	POST /api/networth/snapshot
+//	{"snapshot_date": "2026-03-31"}
+func (s *server) networthSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var body struct {
+		SnapshotDate *string `json:"snapshot_date"`
+	}
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	date := today
+	if body.SnapshotDate != nil && strings.TrimSpace(*body.SnapshotDate) != "" {
+		if _, e := requireDate(*body.SnapshotDate, "snapshot_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		date = *body.SnapshotDate
+	}
+	if date > today {
+		writeErr(w, badRequest("snapshot_date cannot be in the future", nil))
+		return
+	}
+
+	snaps, err := s.snapshotBalances(ledgerID, date)
+	if err != nil {
+		writeErr(w, serverError("failed to record snapshot", err))
+		return
+	}
+	if snaps == nil {
+		snaps = []balanceSnapshot{}
+	}
+	writeOK(w, snaps)
+}
+
+// networthHistory serves GET /api/networth/history, the recorded snapshots in
+// date order. With report_currency each date's snapshots are converted at that
+// date's rates and combined into one; include_accounts=1 adds the per-account
+// balances.
+//
+//	GET /api/networth/history?from_date=2026-01-01&to_date=2026-12-31&report_currency=USD
+func (s *server) networthHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+	q := r.URL.Query()
+
+	reportCurrency, e := reportCurrencyParam(r)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	where := "b.ledger_id = ?"
+	args := []any{ledgerID}
+	for _, f := range []struct{ param, op string }{{"from_date", ">="}, {"to_date", "<="}} {
+		v := q.Get(f.param)
+		if v == "" {
+			continue
+		}
+		if _, e := requireDate(v, f.param); e != nil {
+			writeErr(w, e)
+			return
+		}
+		where += " AND b.snapshot_date " + f.op + " ?"
+		args = append(args, v)
+	}
+	includeAccounts := q.Get("include_accounts") == "1"
+
+	rows, err := s.db.Query(`
+		SELECT b.id, b.snapshot_date, b.currency, b.assets_cents, b.liabilities_cents, b.net_worth_cents
+		FROM balance_snapshot b
+		WHERE `+where+`
+		ORDER BY b.snapshot_date, b.currency
+	`, args...)
+	if err != nil {
+		writeErr(w, serverError("failed to query snapshots", err))
+		return
+	}
+	snaps := []balanceSnapshot{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var b balanceSnapshot
+		if err := rows.Scan(&b.ID, &b.SnapshotDate, &b.Currency, &b.AssetsCents, &b.LiabilitiesCents, &b.NetWorthCents); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read snapshots", err))
+			return
+		}
+		index[b.ID] = len(snaps)
+		snaps = append(snaps, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read snapshots", err))
+		return
+	}
+
+	if includeAccounts {
+		rows, err := s.db.Query(`
+			SELECT sa.snapshot_id, sa.account_id, sa.account_name, sa.is_liability, sa.balance_cents
+			FROM balance_snapshot_account sa
+			JOIN balance_snapshot b ON b.id = sa.snapshot_id
+			WHERE `+where+`
+			ORDER BY sa.account_name
+		`, args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query snapshot accounts", err))
+			return
+		}
+		for rows.Next() {
+			var snapID int64
+			var a snapshotAccount
+			if err := rows.Scan(&snapID, &a.AccountID, &a.AccountName, &a.IsLiability, &a.BalanceCents); err != nil {
+				rows.Close()
+				writeErr(w, serverError("failed to read snapshot accounts", err))
+				return
+			}
+			if i, ok := index[snapID]; ok {
+				snaps[i].Accounts = append(snaps[i].Accounts, a)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			writeErr(w, serverError("failed to read snapshot accounts", err))
+			return
+		}
+	}
+
+	if reportCurrency == "" {
+		writeOK(w, map[string]any{"snapshots": snaps})
+		return
+	}
+
+	fx, err := s.loadFXTable(ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to load exchange rates", err))
+		return
+	}
+	convert := func(cents int64, b balanceSnapshot) (int64, error) {
+		return fx.convert(cents, b.Currency, reportCurrency, b.SnapshotDate)
+	}
+	combined := []balanceSnapshot{}
+	for _, b := range snaps {
+		if n := len(combined); n == 0 || combined[n-1].SnapshotDate != b.SnapshotDate {
+			combined = append(combined, balanceSnapshot{SnapshotDate: b.SnapshotDate, Currency: reportCurrency})
+		}
+		c := &combined[len(combined)-1]
+		assets, err := convert(b.AssetsCents, b)
+		if err != nil {
+			writeErr(w, badRequest(err.Error(), nil))
+			return
+		}
+		liabilities, err := convert(b.LiabilitiesCents, b)
+		if err != nil {
+			writeErr(w, badRequest(err.Error(), nil))
+			return
+		}
+		c.AssetsCents += assets
+		c.LiabilitiesCents += liabilities
+		c.NetWorthCents += assets + liabilities
+		for _, a := range b.Accounts {
+			if a.BalanceCents, err = convert(a.BalanceCents, b); err != nil {
+				writeErr(w, badRequest(err.Error(), nil))
+				return
+			}
+			c.Accounts = append(c.Accounts, a)
+		}
+	}
+	writeOK(w, map[string]any{"snapshots": combined, "report_currency": reportCurrency})
+}
@@ -0,0 +1,89 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNetWorthSnapshots(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Card", "opening_date": "2026-01-01", "is_liability": 1,
+	})
+	cardID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Euro", "opening_date": "2026-01-01", "opening_balance_cents": 10000, "currency": "EUR",
+	})
+	resp.Body.Close()
+	res, err := db.Exec("INSERT INTO entry (entry_date, name, amount_cents, src_account_id) VALUES (?, ?, ?, ?)", "2026-02-10", "Groceries", int64(30000), cardID)
+	if err != nil {
+		t.Fatalf("insert entry: %v", err)
+	}
+	entryID, _ := res.LastInsertId()
+	if _, err := db.Exec("INSERT INTO fx_rate (base_currency, quote_currency, rate_date, rate) VALUES ('EUR', 'USD', '2026-01-01', 1.5)"); err != nil {
+		t.Fatalf("insert rate: %v", err)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/networth/snapshot", map[string]any{"snapshot_date": "2099-01-01"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a future snapshot, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	for i := 0; i < 2; i++ {
+		resp = doJSON(t, http.MethodPost, api.URL+"/api/networth/snapshot", map[string]any{"snapshot_date": "2026-03-01"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("snapshot: status %d", resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+
+	// Editing history afterwards does not change the recorded snapshot.
+	if _, err := db.Exec("UPDATE entry SET amount_cents = 1 WHERE id = ?", entryID); err != nil {
+		t.Fatalf("update entry: %v", err)
+	}
+	// Nor does taking the past snapshot again.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/networth/snapshot", map[string]any{"snapshot_date": "2026-03-01"})
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		if snap := mustMap(t, item); snap["currency"] == "USD" && mustInt64(t, snap["net_worth_cents"]) != 70000 {
+			t.Fatalf("expected the recorded snapshot back, got %v", snap)
+		}
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/networth/history?include_accounts=1", nil)
+	snaps := mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["snapshots"])
+	if len(snaps) != 2 {
+		t.Fatalf("expected one snapshot per currency, got %d", len(snaps))
+	}
+	eur, usd := mustMap(t, snaps[0]), mustMap(t, snaps[1])
+	if eur["currency"] != "EUR" || mustInt64(t, eur["net_worth_cents"]) != 10000 {
+		t.Fatalf("unexpected EUR snapshot: %v", eur)
+	}
+	if usd["snapshot_date"] != "2026-03-01" || mustInt64(t, usd["assets_cents"]) != 100000 ||
+		mustInt64(t, usd["liabilities_cents"]) != -30000 || mustInt64(t, usd["net_worth_cents"]) != 70000 ||
+		len(mustList(t, usd["accounts"])) != 2 {
+		t.Fatalf("unexpected USD snapshot: %v", usd)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/networth/history?report_currency=USD&to_date=2026-12-31", nil)
+	snaps = mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["snapshots"])
+	if len(snaps) != 1 || mustInt64(t, mustMap(t, snaps[0])["net_worth_cents"]) != 85000 ||
+		mustInt64(t, mustMap(t, snaps[0])["assets_cents"]) != 115000 {
+		t.Fatalf("unexpected combined history: %v", snaps)
+	}
+
+	if err := SnapshotBalances(db); err != nil {
+		t.Fatalf("SnapshotBalances: %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM balance_snapshot WHERE snapshot_date = ?", today).Scan(&n); err != nil || n != 2 {
+		t.Fatalf("expected today's snapshots for both currencies, got %d (%v)", n, err)
+	}
+}
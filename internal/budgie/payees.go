@@ -0,0 +1,525 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// payeeNoiseTokens are dropped from match keys; they rarely tell payees apart.
+var payeeNoiseTokens = map[string]bool{
+	"www": true, "com": true, "net": true, "org": true,
+	"inc": true, "llc": true, "ltd": true, "co": true,
+}
+
+// payeeMatchKey normalizes a payee name or bank description for matching:
+// lower case, punctuation to spaces, and tokens that contain digits (store
+// numbers, reference codes) or are corporate noise removed.
+// "AMZN Mktp US*2K3" becomes "amzn mktp us"; "Amazon.com" becomes "amazon".
+func payeeMatchKey(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if payeeNoiseTokens[f] || strings.IndexFunc(f, unicode.IsDigit) >= 0 {
+			continue
+		}
+		out = append(out, f)
+	}
+	return strings.Join(out, " ")
+}
+
+// matchPayee finds the payee whose alias matches name, either exactly or as
+// the leading words of it; the longest alias wins.
+func (s *server) matchPayee(ledgerID int64, name string) (*int64, *int64, error) {
+	key := payeeMatchKey(name)
+	if key == "" {
+		return nil, nil, nil
+	}
+	var payeeID int64
+	var defaultCategory sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT p.id, p.default_category_id
+		FROM payee_alias pa
+		JOIN payee p ON p.id = pa.payee_id
+		WHERE pa.ledger_id = ?
+		  AND (pa.match_key = ? OR ? LIKE pa.match_key || ' %')
+		ORDER BY length(pa.match_key) DESC
+		LIMIT 1
+	`, ledgerID, key, key).Scan(&payeeID, &defaultCategory)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if defaultCategory.Valid {
+		return &payeeID, &defaultCategory.Int64, nil
+	}
+	return &payeeID, nil, nil
+}
+
+// resolvePayee links an entry to a payee: the explicit payee_id when given,
+// otherwise the payee matched from the entry name. The payee's default
+// category fills in an unset category on unsplit entries.
+func (s *server) resolvePayee(ledgerID int64, payeeID *int64, name string, categoryID *int64, split bool) (*int64, *int64, *apiErr) {
+	var defaultCategory *int64
+	if payeeID != nil {
+		if e := s.ledgerOwnsRow(ledgerID, "payee", payeeID); e != nil {
+			return nil, nil, e
+		}
+		var dc sql.NullInt64
+		if err := s.db.QueryRow("SELECT default_category_id FROM payee WHERE id = ?", *payeeID).Scan(&dc); err != nil {
+			return nil, nil, serverError("failed to read payee", err)
+		}
+		if dc.Valid {
+			defaultCategory = &dc.Int64
+		}
+	} else {
+		var err error
+		if payeeID, defaultCategory, err = s.matchPayee(ledgerID, name); err != nil {
+			return nil, nil, serverError("failed to match payee", err)
+		}
+	}
+	if categoryID == nil && !split {
+		categoryID = defaultCategory
+	}
+	return payeeID, categoryID, nil
+}
+
+// replacePayeeAliases stores the payee's name and the given aliases as its
+// aliases. Existing aliases are kept unless reset is set.
+func replacePayeeAliases(tx *sql.Tx, ledgerID, payeeID int64, name string, aliases []string, reset bool) *apiErr {
+	if reset {
+		if _, err := tx.Exec("DELETE FROM payee_alias WHERE payee_id = ?", payeeID); err != nil {
+			return serverError("failed to replace aliases", err)
+		}
+	}
+	for _, alias := range append([]string{name}, aliases...) {
+		alias = strings.TrimSpace(alias)
+		key := payeeMatchKey(alias)
+		if key == "" {
+			continue
+		}
+		var owner int64
+		err := tx.QueryRow("SELECT payee_id FROM payee_alias WHERE ledger_id = ? AND match_key = ?", ledgerID, key).Scan(&owner)
+		if err == nil {
+			if owner != payeeID {
+				return badRequest("alias already belongs to another payee", map[string]any{"alias": alias, "payee_id": owner})
+			}
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return serverError("failed to check aliases", err)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO payee_alias (ledger_id, payee_id, alias, match_key) VALUES (?, ?, ?, ?)",
+			ledgerID, payeeID, alias, key,
+		); err != nil {
+			return serverError("failed to save alias", err)
+		}
+	}
+	return nil
+}
+
+type payeeBody struct {
+	Name              string    `json:"name"`
+	DefaultCategoryID *int64    `json:"default_category_id"`
+	Aliases           *[]string `json:"aliases"`
+}
+
+func (s *server) validatePayee(ledgerID int64, b *payeeBody) *apiErr {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return badRequest("name is required", nil)
+	}
+	if payeeMatchKey(b.Name) == "" {
+		return badRequest("name must contain letters", nil)
+	}
+	return s.ledgerOwnsCategory(ledgerID, b.DefaultCategoryID)
+}
+
+// attachPayeeAliases adds an "aliases" list to each payee map.
+func (s *server) attachPayeeAliases(ledgerID int64, payees ...map[string]any) error {
+	byID := make(map[int64]map[string]any, len(payees))
+	for _, p := range payees {
+		p["aliases"] = []string{}
+		if id, ok := p["id"].(int64); ok {
+			byID[id] = p
+		}
+	}
+	rows, err := s.db.Query("SELECT payee_id, alias FROM payee_alias WHERE ledger_id = ? ORDER BY alias", ledgerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var alias string
+		if err := rows.Scan(&id, &alias); err != nil {
+			return err
+		}
+		if p, ok := byID[id]; ok {
+			p["aliases"] = append(p["aliases"].([]string), alias)
+		}
+	}
+	return rows.Err()
+}
+
+func (s *server) payees(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query(`
+			WITH RECURSIVE `+categoryPathCTEDefs()+`
+			SELECT p.*,
+			       cp.path AS default_category_path,
+			       (SELECT COUNT(*) FROM entry e WHERE e.payee_id = p.id) AS entry_count
+			FROM payee p
+			LEFT JOIN category_path cp ON cp.id = p.default_category_id
+			WHERE p.ledger_id = ?
+			ORDER BY p.name
+		`, ledgerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query payees", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read payees", err))
+			return
+		}
+		if err := s.attachPayeeAliases(ledgerID, data...); err != nil {
+			writeErr(w, serverError("failed to read payee aliases", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body payeeBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validatePayee(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(
+			"INSERT INTO payee (ledger_id, name, default_category_id) VALUES (?, ?, ?)",
+			ledgerID, body.Name, body.DefaultCategoryID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create payee (name already used?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		var aliases []string
+		if body.Aliases != nil {
+			aliases = *body.Aliases
+		}
+		if e := replacePayeeAliases(tx, ledgerID, id, body.Name, aliases, false); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to create payee", err))
+			return
+		}
+		created, apiE := scanRowToMap(s.db, "payee", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		if err := s.attachPayeeAliases(ledgerID, created); err != nil {
+			writeErr(w, serverError("failed to read payee aliases", err))
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// payeeByID serves PUT/DELETE /api/payees/{id} and POST /api/payees/{id}/merge.
+func (s *server) payeeByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/payees/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "merge") {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+	if e := s.ledgerOwnsRow(ledgerID, "payee", &id); e != nil {
+		writeErr(w, notFound("payee not found"))
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			IntoID int64 `json:"into_id"`
+		}
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if body.IntoID == 0 || body.IntoID == id {
+			writeErr(w, badRequest("into_id must reference a different payee", nil))
+			return
+		}
+		if e := s.ledgerOwnsRow(ledgerID, "payee", &body.IntoID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.mergePayee(id, body.IntoID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		writeOK(w, map[string]any{"merged": id, "into_id": body.IntoID})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body payeeBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validatePayee(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(
+			"UPDATE payee SET name=?, default_category_id=? WHERE id=? AND ledger_id=?",
+			body.Name, body.DefaultCategoryID, id, ledgerID,
+		); err != nil {
+			writeErr(w, badRequest("could not update payee (name already used?)", nil))
+			return
+		}
+		// Without an aliases list the old name stays as an alias.
+		var aliases []string
+		if body.Aliases != nil {
+			aliases = *body.Aliases
+		}
+		if e := replacePayeeAliases(tx, ledgerID, id, body.Name, aliases, body.Aliases != nil); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to update payee", err))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "payee", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		if err := s.attachPayeeAliases(ledgerID, updated); err != nil {
+			writeErr(w, serverError("failed to read payee aliases", err))
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		if _, err := s.db.Exec("DELETE FROM payee WHERE id = ? AND ledger_id = ?", id, ledgerID); err != nil {
+			writeErr(w, badRequest("could not delete payee", nil))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// mergePayee moves a payee's entries, schedules and aliases to another payee
+// and deletes it, in one transaction.
+func (s *server) mergePayee(id, into int64) *apiErr {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return serverError("failed to open transaction", err)
+	}
+	defer tx.Rollback()
+	for _, q := range []string{
+		"UPDATE entry SET payee_id = ? WHERE payee_id = ?",
+		"UPDATE schedule SET payee_id = ? WHERE payee_id = ?",
+		"UPDATE payee_alias SET payee_id = ? WHERE payee_id = ?",
+	} {
+		if _, err := tx.Exec(q, into, id); err != nil {
+			return serverError("failed to merge payee", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM payee WHERE id = ?", id); err != nil {
+		return serverError("failed to merge payee", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return serverError("failed to merge payee", err)
+	}
+	return nil
+}
+
+type payeeMergeSuggestion struct {
+	PayeeID   int64   `json:"payee_id"`
+	PayeeName string  `json:"payee_name"`
+	IntoID    int64   `json:"into_id"`
+	IntoName  string  `json:"into_name"`
+	Score     float64 `json:"score"`
+}
+
+// payeeMergeSuggestions lists pairs of payees whose names or aliases look
+// alike. The payee with fewer entries is suggested to merge into the other.
+//
+//	GET /api/payees/merge-suggestions[?min_score=0.8]
+func (s *server) payeeMergeSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	minScore := 0.8
+	if v := r.URL.Query().Get("min_score"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			writeErr(w, badRequest("min_score must be in (0, 1]", nil))
+			return
+		}
+		minScore = f
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	type candidate struct {
+		id      int64
+		name    string
+		entries int64
+		keys    []string
+	}
+	rows, err := s.db.Query(`
+		SELECT p.id, p.name, (SELECT COUNT(*) FROM entry e WHERE e.payee_id = p.id), pa.match_key
+		FROM payee p
+		JOIN payee_alias pa ON pa.payee_id = p.id
+		WHERE p.ledger_id = ?
+		ORDER BY p.id
+	`, ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to query payees", err))
+		return
+	}
+	var list []*candidate
+	for rows.Next() {
+		var c candidate
+		var key string
+		if err := rows.Scan(&c.id, &c.name, &c.entries, &key); err != nil {
+			rows.Close()
+			writeErr(w, serverError("failed to read payees", err))
+			return
+		}
+		if n := len(list); n > 0 && list[n-1].id == c.id {
+			list[n-1].keys = append(list[n-1].keys, key)
+			continue
+		}
+		c.keys = []string{key}
+		list = append(list, &c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeErr(w, serverError("failed to read payees", err))
+		return
+	}
+
+	out := []payeeMergeSuggestion{}
+	for i := 0; i < len(list); i++ {
+		for j := i + 1; j < len(list); j++ {
+			a, b := list[i], list[j]
+			var best float64
+			for _, ka := range a.keys {
+				for _, kb := range b.keys {
+					best = math.Max(best, payeeSimilarity(ka, kb))
+				}
+			}
+			best = math.Round(best*1000) / 1000
+			if best < minScore {
+				continue
+			}
+			if a.entries > b.entries {
+				a, b = b, a
+			}
+			out = append(out, payeeMergeSuggestion{
+				PayeeID: a.id, PayeeName: a.name,
+				IntoID: b.id, IntoName: b.name,
+				Score: best,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	writeOK(w, out)
+}
+
+// payeeSimilarity scores two match keys from 0 to 1: one minus the edit
+// distance relative to the longer key. When one key has fewer words, it is
+// also compared against that many leading words of the other, scaled by 0.9,
+// so "starbuck" is close to "starbucks coffee".
+func payeeSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	score := editRatio(a, b)
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+	if len(wa) > 0 && len(wa) < len(wb) {
+		lead := strings.Join(wb[:len(wa)], " ")
+		score = math.Max(score, 0.9*editRatio(strings.Join(wa, " "), lead))
+	}
+	return score
+}
+
+func editRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,120 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPayeeMatchKey(t *testing.T) {
+	cases := map[string]string{
+		"AMZN Mktp US*2K3":        "amzn mktp us",
+		"Amazon.com":              "amazon",
+		"STARBUCKS #1234 SEATTLE": "starbucks seattle",
+		"  Joe's   Diner, Inc. ":  "joe s diner",
+		"#4411":                   "",
+	}
+	for in, want := range cases {
+		if got := payeeMatchKey(in); got != want {
+			t.Fatalf("payeeMatchKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPayeeNormalizationAndMergeSuggestions(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+	res, err = db.Exec("INSERT INTO category (name) VALUES ('Shopping')")
+	if err != nil {
+		t.Fatalf("insert category: %v", err)
+	}
+	shopping, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	createPayee := func(body map[string]any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/payees", body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create payee %v: status %d", body["name"], resp.StatusCode)
+		}
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	amazon := createPayee(map[string]any{"name": "Amazon", "default_category_id": shopping, "aliases": []string{"AMZN Mktp"}})
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/payees", map[string]any{"name": "Amazon.com"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a name that is another payee's alias, got %d", resp.StatusCode)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/entries", map[string]any{
+		"entry_date":     "2026-01-05",
+		"name":           "AMZN Mktp US*2K3",
+		"amount_cents":   2599,
+		"src_account_id": acctID,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create entry: status %d", resp.StatusCode)
+	}
+	entry := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if got := mustInt64(t, entry["payee_id"]); got != amazon {
+		t.Fatalf("expected entry matched to payee %d, got %d", amazon, got)
+	}
+	if got := mustInt64(t, entry["category_id"]); got != shopping {
+		t.Fatalf("expected default category %d, got %d", shopping, got)
+	}
+
+	// Edits that leave the payee out, like the editors', keep it even when
+	// the name matches no alias.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/entries/"+fmtInt64(mustInt64(t, entry["id"])), map[string]any{
+		"entry_date": "2026-01-05", "name": "Birthday gift", "amount_cents": 2599, "src_account_id": acctID,
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["payee_id"] == nil || mustInt64(t, updated["payee_id"]) != amazon {
+		t.Fatalf("expected an entry edit without payee_id to keep it: %v", updated)
+	}
+	schedule := map[string]any{
+		"name": "Subscription", "kind": "E", "amount_cents": 1499, "src_account_id": acctID,
+		"start_date": "2026-02-01", "freq": "M", "interval": 1, "payee_id": amazon,
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", schedule)
+	scheduleID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	delete(schedule, "payee_id")
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(scheduleID), schedule)
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["payee_id"] == nil || mustInt64(t, updated["payee_id"]) != amazon {
+		t.Fatalf("expected a schedule edit without payee_id to keep it: %v", updated)
+	}
+
+	coffee := createPayee(map[string]any{"name": "Starbucks Coffee"})
+	sbux := createPayee(map[string]any{"name": "Starbuck"})
+	sugResp, err := http.Get(api.URL + "/api/payees/merge-suggestions")
+	if err != nil {
+		t.Fatalf("get suggestions: %v", err)
+	}
+	suggestions := mustList(t, decodeAPIResponse(t, sugResp).Data)
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 merge suggestion, got %v", suggestions)
+	}
+	sug := mustMap(t, suggestions[0])
+	if ids := [2]int64{mustInt64(t, sug["payee_id"]), mustInt64(t, sug["into_id"])}; ids != [2]int64{coffee, sbux} && ids != [2]int64{sbux, coffee} {
+		t.Fatalf("unexpected suggestion: %v", sug)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/payees/"+fmtInt64(sbux)+"/merge", map[string]any{"into_id": coffee})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("merge payees: status %d", resp.StatusCode)
+	}
+	var owner int64
+	if err := db.QueryRow("SELECT payee_id FROM payee_alias WHERE match_key = 'starbuck'").Scan(&owner); err != nil {
+		t.Fatalf("read alias: %v", err)
+	}
+	if owner != coffee {
+		t.Fatalf("expected merged alias to belong to %d, got %d", coffee, owner)
+	}
+}
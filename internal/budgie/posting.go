@@ -0,0 +1,231 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// scheduledOccurrence is one row of occurrenceQuery.
+type scheduledOccurrence struct {
+	ScheduleID    int64
+	Date          string
+	Kind          string
+	Name          string
+	AmountCents   int64
+	SrcAccountID  *int64
+	DestAccountID *int64
+	Description   *string
+	CategoryID    *int64
+	CategoryPath  *string
+	DestAmount    int64
+	OriginalDate  string
+	Modified      bool
+	ExceptionID   *int64
+	ExceptionKind *string
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// occurrenceArgs returns the parameters of occurrenceCTEDefs for a scenario
+// (0 for none) and range, working out the interest part of loan payments
+// that stop once the loan is paid off.
+func occurrenceArgs(q querier, ledgerID, scenarioID int64, from, to string) ([]any, error) {
+	interest, err := payoffInterest(q, ledgerID, scenarioID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return occurrenceParams(scenarioID, ledgerID, from, to, interest), nil
+}
+
+// listOccurrences runs occurrenceQuery for a scenario (0 for none) between
+// from and to.
+func (s *server) listOccurrences(ledgerID, scenarioID int64, from, to string) ([]scheduledOccurrence, error) {
+	args, err := occurrenceArgs(s.db, ledgerID, scenarioID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return queryOccurrences(s.db, occurrenceQueryFor(scenarioID != 0), args...)
+}
+
+// queryOccurrences runs an occurrence query and scans its rows.
+func queryOccurrences(q querier, query string, args ...any) ([]scheduledOccurrence, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []scheduledOccurrence
+	for rows.Next() {
+		var o scheduledOccurrence
+		if err := rows.Scan(&o.ScheduleID, &o.Date, &o.Kind, &o.Name, &o.AmountCents, &o.SrcAccountID, &o.DestAccountID, &o.Description, &o.CategoryID, &o.CategoryPath, &o.DestAmount, &o.OriginalDate, &o.Modified, &o.ExceptionID, &o.ExceptionKind); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// findOccurrence returns the unposted occurrence of a schedule that would
+// normally fall on date, with revisions and exceptions applied. It returns nil
+// when there is none, including when the occurrence is skipped or suspended.
+func (s *server) findOccurrence(ledgerID, scheduleID int64, date string) (*scheduledOccurrence, error) {
+	// A moved occurrence is listed on the date it was moved to; otherwise it
+	// may have rolled up to two weeks either way to a business day.
+	var from, to string
+	if err := s.db.QueryRow(`
+		SELECT COALESCE(m.move_to_date, date(?, '-14 days')), COALESCE(m.move_to_date, date(?, '+14 days'))
+		FROM (SELECT (SELECT move_to_date FROM schedule_exception WHERE schedule_id = ? AND occ_date = ? AND kind = 'move') AS move_to_date) m
+	`, date, date, scheduleID, date).Scan(&from, &to); err != nil {
+		return nil, err
+	}
+	occs, err := s.listOccurrences(ledgerID, 0, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range occs {
+		if occs[i].ScheduleID == scheduleID && occs[i].OriginalDate == date {
+			return &occs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// postOccurrence turns a schedule occurrence into a real entry. Amounts come
+// from the schedule and its revisions unless overridden; the entry keeps
+// schedule_id and occurrence_date so projections stop counting the
+// occurrence.
+//
+//	POST /api/occurrences/post
+//	{"schedule_id": 1, "occurrence_date": "2026-03-01", "entry_date": "2026-03-02", "amount_cents": 12345}
+func (s *server) postOccurrence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var body struct {
+		ScheduleID     int64   `json:"schedule_id"`
+		OccurrenceDate string  `json:"occurrence_date"`
+		EntryDate      *string `json:"entry_date"`
+		AmountCents    *int64  `json:"amount_cents"`
+		DestAmount     *int64  `json:"dest_amount_cents"`
+		Name           *string `json:"name"`
+		Description    *string `json:"description"`
+		Status         *string `json:"status"`
+	}
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if _, e := requireDate(body.OccurrenceDate, "occurrence_date"); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if body.EntryDate != nil {
+		if _, e := requireDate(*body.EntryDate, "entry_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+	if body.AmountCents != nil && *body.AmountCents <= 0 {
+		writeErr(w, badRequest("amount_cents must be > 0", nil))
+		return
+	}
+	if body.Name != nil && strings.TrimSpace(*body.Name) == "" {
+		writeErr(w, badRequest("name must not be empty", nil))
+		return
+	}
+	if body.Status != nil {
+		if _, e := requireEntryStatus(*body.Status); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+
+	var payeeID *int64
+	err := s.db.QueryRow("SELECT payee_id FROM schedule WHERE id = ? AND ledger_id = ?", body.ScheduleID, ledgerID).Scan(&payeeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("schedule not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read schedule", err))
+		return
+	}
+	var posted sql.NullInt64
+	if err := s.db.QueryRow(
+		"SELECT id FROM entry WHERE schedule_id = ? AND COALESCE(occurrence_date, entry_date) = ?",
+		body.ScheduleID, body.OccurrenceDate,
+	).Scan(&posted); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, serverError("failed to check posted occurrences", err))
+		return
+	}
+	if posted.Valid {
+		writeErr(w, badRequest("occurrence is already posted", map[string]any{"entry_id": posted.Int64}))
+		return
+	}
+	occ, err := s.findOccurrence(ledgerID, body.ScheduleID, body.OccurrenceDate)
+	if err != nil {
+		writeErr(w, serverError("failed to compute occurrences", err))
+		return
+	}
+	if occ == nil {
+		writeErr(w, badRequest("schedule has no occurrence on occurrence_date", nil))
+		return
+	}
+
+	entryDate := occ.Date
+	if body.EntryDate != nil {
+		entryDate = *body.EntryDate
+	}
+	name := occ.Name
+	if body.Name != nil {
+		name = strings.TrimSpace(*body.Name)
+	}
+	description := occ.Description
+	if body.Description != nil {
+		description = body.Description
+	}
+	amount := occ.AmountCents
+	if body.AmountCents != nil {
+		amount = *body.AmountCents
+	}
+	destAmount := &occ.DestAmount
+	if body.DestAmount != nil {
+		destAmount = body.DestAmount
+	} else if body.AmountCents != nil {
+		destAmount = nil
+	}
+	destAmount, e := s.transferDestAmount(occ.SrcAccountID, occ.DestAccountID, destAmount)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	payeeID, categoryID, e := s.resolvePayee(ledgerID, payeeID, name, occ.CategoryID, false)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description, schedule_id, category_id, dest_amount_cents, payee_id, status, occurrence_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, 'pending'), ?)
+	`, ledgerID, entryDate, name, amount, occ.SrcAccountID, occ.DestAccountID, description, occ.ScheduleID, categoryID, destAmount, payeeID, body.Status, body.OccurrenceDate)
+	if err != nil {
+		writeErr(w, badRequest("could not post occurrence", nil))
+		return
+	}
+	id, _ := res.LastInsertId()
+	created, apiE := scanRowToMap(s.db, "entry", id)
+	if apiE != nil {
+		writeErr(w, apiE)
+		return
+	}
+	writeOK(w, created)
+}
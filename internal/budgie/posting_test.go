@@ -0,0 +1,101 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPostOccurrence(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name":           "Rent",
+		"kind":           "E",
+		"amount_cents":   1000,
+		"src_account_id": acctID,
+		"start_date":     "2026-01-01",
+		"freq":           "M",
+		"interval":       1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	schedID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/revisions", map[string]any{
+		"schedule_id": schedID, "effective_date": "2026-02-01", "amount_cents": 1500,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create revision: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	projected := func() int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-03-31", nil)
+		rows := mustList(t, decodeAPIResponse(t, resp).Data)
+		return mustInt64(t, mustMap(t, rows[0])["projected_balance_cents"])
+	}
+	// Jan 1000 + Feb 1500 + Mar 1500.
+	if got := projected(); got != 96000 {
+		t.Fatalf("expected projected balance 96000 before posting, got %d", got)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-02-01", "entry_date": "2026-02-03",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post occurrence: status %d", resp.StatusCode)
+	}
+	entry := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if mustInt64(t, entry["amount_cents"]) != 1500 || entry["entry_date"] != "2026-02-03" || entry["occurrence_date"] != "2026-02-01" {
+		t.Fatalf("unexpected posted entry: %v", entry)
+	}
+	if got := projected(); got != 96000 {
+		t.Fatalf("posted occurrence must not count twice, got %d", got)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-02-01&to_date=2026-02-28", nil)
+	if got := len(mustList(t, decodeAPIResponse(t, resp).Data)); got != 0 {
+		t.Fatalf("expected posted occurrence to disappear from /api/occurrences, got %d rows", got)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-02-01",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 posting twice, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-02-02",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a date without an occurrence, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-03-01", "amount_cents": 1250, "name": "Rent (partial)",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post with override: status %d", resp.StatusCode)
+	}
+	entry = mustMap(t, decodeAPIResponse(t, resp).Data)
+	if mustInt64(t, entry["amount_cents"]) != 1250 || entry["name"] != "Rent (partial)" || entry["dest_amount_cents"] != nil {
+		t.Fatalf("unexpected overridden entry: %v", entry)
+	}
+	if got := projected(); got != 96250 {
+		t.Fatalf("expected projected balance to use the posted amount, got %d", got)
+	}
+}
@@ -0,0 +1,408 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// requireEntryStatus validates a status set through the entries API.
+// "reconciled" is reserved for finished reconciliations.
+func requireEntryStatus(v string) (string, *apiErr) {
+	switch v {
+	case "pending", "cleared":
+		return v, nil
+	case "reconciled":
+		return "", badRequest("entries become reconciled by finishing a reconciliation", nil)
+	}
+	return "", badRequest("status must be 'pending' or 'cleared'", nil)
+}
+
+// reconciledEntryGuard rejects changes to entries locked by a finished
+// reconciliation.
+func (s *server) reconciledEntryGuard(entryID int64) *apiErr {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM entry WHERE id = ? AND status = 'reconciled'", entryID).Scan(&n); err != nil {
+		return serverError("failed to check entry status", err)
+	}
+	if n > 0 {
+		return badRequest("this entry is reconciled and can no longer be changed", map[string]any{"entry_id": entryID})
+	}
+	return nil
+}
+
+// clearedBalance is the account's opening balance plus every cleared or
+// reconciled movement, regardless of date.
+func clearedBalance(q rowQuerier, accountID int64) (int64, error) {
+	var bal int64
+	err := q.QueryRow(`
+		SELECT a.opening_balance_cents + COALESCE((
+		  SELECT SUM(d.delta_cents)
+		  FROM v_entry_delta d
+		  JOIN entry e ON e.id = d.entry_id
+		  WHERE d.account_id = a.id
+		    AND d.entry_date >= a.opening_date
+		    AND e.status IN ('cleared', 'reconciled')
+		), 0)
+		FROM account a
+		WHERE a.id = ?
+	`, accountID).Scan(&bal)
+	return bal, err
+}
+
+type reconciliationBody struct {
+	AccountID             int64  `json:"account_id"`
+	StatementDate         string `json:"statement_date"`
+	StatementBalanceCents int64  `json:"statement_balance_cents"`
+}
+
+// reconciliations serves GET/POST /api/reconciliations. GET lists the session
+// history, optionally for one account_id; POST starts a session. Only one
+// session per account may be open at a time.
+func (s *server) reconciliations(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := `
+			SELECT rc.*, a.name AS account_name
+			FROM reconciliation rc
+			JOIN account a ON a.id = rc.account_id
+			WHERE rc.ledger_id = ?`
+		args := []any{ledgerID}
+		if v := r.URL.Query().Get("account_id"); v != "" {
+			accountID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				writeErr(w, badRequest("account_id must be an integer", nil))
+				return
+			}
+			q += " AND rc.account_id = ?"
+			args = append(args, accountID)
+		}
+		rows, err := s.db.Query(q+" ORDER BY rc.statement_date DESC, rc.id DESC", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query reconciliations", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read reconciliations", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body reconciliationBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if _, e := requireDate(body.StatementDate, "statement_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsAccounts(ledgerID, &body.AccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		var last sql.NullString
+		if err := s.db.QueryRow(
+			"SELECT MAX(statement_date) FROM reconciliation WHERE account_id = ? AND status = 'finished'",
+			body.AccountID,
+		).Scan(&last); err != nil {
+			writeErr(w, serverError("failed to read reconciliation history", err))
+			return
+		}
+		if last.Valid && body.StatementDate < last.String {
+			writeErr(w, badRequest("statement_date is before the last finished reconciliation", map[string]any{"last_statement_date": last.String}))
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO reconciliation (ledger_id, account_id, statement_date, statement_balance_cents) VALUES (?, ?, ?, ?)",
+			ledgerID, body.AccountID, body.StatementDate, body.StatementBalanceCents,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not start reconciliation (one is already open for this account?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		s.writeReconciliation(w, ledgerID, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// reconciliationByID serves /api/reconciliations/{id}:
+//
+//	GET                 session with its entries and live difference
+//	PUT                 change statement date/balance of an open session
+//	DELETE              cancel an open session
+//	POST /{id}/clear    mark entries cleared or pending: {"entry_ids": [...], "cleared": true}
+//	POST /{id}/finish   lock cleared entries: {"adjust": false}
+//
+// Finishing requires a zero difference unless adjust is set, in which case a
+// "Reconciliation Adjustment" entry makes up the difference.
+func (s *server) reconciliationByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reconciliations/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "clear" && parts[1] != "finish") {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var accountID int64
+	var status string
+	err = s.db.QueryRow(
+		"SELECT account_id, status FROM reconciliation WHERE id = ? AND ledger_id = ?",
+		id, ledgerID,
+	).Scan(&accountID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeErr(w, notFound("reconciliation not found"))
+		return
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to read reconciliation", err))
+		return
+	}
+	if r.Method != http.MethodGet && status != "open" {
+		writeErr(w, badRequest("reconciliation is finished", nil))
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if parts[1] == "clear" {
+			s.clearReconciliationEntries(w, r, ledgerID, id, accountID)
+		} else {
+			s.finishReconciliation(w, r, ledgerID, id, accountID)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeReconciliation(w, ledgerID, id)
+	case http.MethodPut:
+		var body reconciliationBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if _, e := requireDate(body.StatementDate, "statement_date"); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if _, err := s.db.Exec(
+			"UPDATE reconciliation SET statement_date=?, statement_balance_cents=? WHERE id = ?",
+			body.StatementDate, body.StatementBalanceCents, id,
+		); err != nil {
+			writeErr(w, badRequest("could not update reconciliation", nil))
+			return
+		}
+		s.writeReconciliation(w, ledgerID, id)
+	case http.MethodDelete:
+		if _, err := s.db.Exec("DELETE FROM reconciliation WHERE id = ?", id); err != nil {
+			writeErr(w, badRequest("could not delete reconciliation", nil))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// writeReconciliation responds with a session, its entries and the difference
+// between the statement and the cleared balance. Open sessions list the
+// account's unreconciled entries up to the statement date plus anything
+// already cleared; finished sessions list the entries they locked.
+func (s *server) writeReconciliation(w http.ResponseWriter, ledgerID, id int64) {
+	session, apiE := scanRowToMap(s.db, "reconciliation", id)
+	if apiE != nil {
+		writeErr(w, apiE)
+		return
+	}
+	accountID := session["account_id"].(int64)
+	statementBalance := session["statement_balance_cents"].(int64)
+
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	const cols = `
+		SELECT e.id, e.entry_date, e.name, e.description, e.amount_cents, e.status,
+		       e.src_account_id, e.dest_account_id, d.delta_cents
+		FROM entry e
+		JOIN v_entry_delta d ON d.entry_id = e.id AND d.account_id = ?`
+	if session["status"] == "open" {
+		rows, err = s.db.Query(cols+`
+			WHERE e.ledger_id = ?
+			  AND e.status != 'reconciled'
+			  AND (e.entry_date <= ? OR e.status = 'cleared')
+			ORDER BY e.entry_date, e.id
+		`, accountID, ledgerID, session["statement_date"])
+	} else {
+		rows, err = s.db.Query(cols+`
+			WHERE e.reconciliation_id = ?
+			ORDER BY e.entry_date, e.id
+		`, accountID, id)
+	}
+	if err != nil {
+		writeErr(w, serverError("failed to query reconciliation entries", err))
+		return
+	}
+	defer rows.Close()
+	entries, err := rowsToMaps(rows)
+	if err != nil {
+		writeErr(w, serverError("failed to read reconciliation entries", err))
+		return
+	}
+
+	var cleared int64
+	if v, ok := session["cleared_balance_cents"].(int64); ok {
+		cleared = v
+	} else if cleared, err = clearedBalance(s.db, accountID); err != nil {
+		writeErr(w, serverError("failed to compute cleared balance", err))
+		return
+	}
+	session["cleared_balance_cents"] = cleared
+	session["difference_cents"] = statementBalance - cleared
+	session["entries"] = entries
+	writeOK(w, session)
+}
+
+func (s *server) clearReconciliationEntries(w http.ResponseWriter, r *http.Request, ledgerID, id, accountID int64) {
+	var body struct {
+		EntryIDs []int64 `json:"entry_ids"`
+		Cleared  bool    `json:"cleared"`
+	}
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if len(body.EntryIDs) == 0 {
+		writeErr(w, badRequest("entry_ids is required", nil))
+		return
+	}
+	status := "pending"
+	if body.Cleared {
+		status = "cleared"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+	for _, entryID := range body.EntryIDs {
+		res, err := tx.Exec(`
+			UPDATE entry SET status = ?
+			WHERE id = ? AND ledger_id = ?
+			  AND status != 'reconciled'
+			  AND (src_account_id = ? OR dest_account_id = ?)
+		`, status, entryID, ledgerID, accountID, accountID)
+		if err != nil {
+			writeErr(w, serverError("failed to update entry status", err))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, badRequest("entry is not an unreconciled entry of this account", map[string]any{"entry_id": entryID}))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeErr(w, serverError("failed to update entry status", err))
+		return
+	}
+	s.writeReconciliation(w, ledgerID, id)
+}
+
+func (s *server) finishReconciliation(w http.ResponseWriter, r *http.Request, ledgerID, id, accountID int64) {
+	var body struct {
+		Adjust bool `json:"adjust"`
+	}
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+
+	var statementDate string
+	var statementBalance int64
+	if err := tx.QueryRow(
+		"SELECT statement_date, statement_balance_cents FROM reconciliation WHERE id = ?", id,
+	).Scan(&statementDate, &statementBalance); err != nil {
+		writeErr(w, serverError("failed to read reconciliation", err))
+		return
+	}
+	cleared, err := clearedBalance(tx, accountID)
+	if err != nil {
+		writeErr(w, serverError("failed to compute cleared balance", err))
+		return
+	}
+	diff := statementBalance - cleared
+
+	var adjustmentID *int64
+	if diff != 0 {
+		if !body.Adjust {
+			writeErr(w, badRequest("cleared balance does not match the statement", map[string]any{
+				"cleared_balance_cents":   cleared,
+				"statement_balance_cents": statementBalance,
+				"difference_cents":        diff,
+			}))
+			return
+		}
+		var src, dest *int64
+		amount := diff
+		if diff > 0 {
+			dest = &accountID
+		} else {
+			src = &accountID
+			amount = -diff
+		}
+		res, err := tx.Exec(`
+			INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 'cleared')
+		`, ledgerID, statementDate, "Reconciliation Adjustment", amount, src, dest, "Difference found while reconciling")
+		if err != nil {
+			writeErr(w, serverError("failed to create adjustment entry", err))
+			return
+		}
+		entryID, _ := res.LastInsertId()
+		adjustmentID = &entryID
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE entry SET status = 'reconciled', reconciliation_id = ?
+		WHERE ledger_id = ? AND status = 'cleared'
+		  AND (src_account_id = ? OR dest_account_id = ?)
+	`, id, ledgerID, accountID, accountID); err != nil {
+		writeErr(w, serverError("failed to lock entries", err))
+		return
+	}
+	if _, err := tx.Exec(`
+		UPDATE reconciliation
+		SET status = 'finished', finished_at = datetime('now'), cleared_balance_cents = ?, adjustment_entry_id = ?
+		WHERE id = ?
+	`, statementBalance, adjustmentID, id); err != nil {
+		writeErr(w, serverError("failed to finish reconciliation", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeErr(w, serverError("failed to finish reconciliation", err))
+		return
+	}
+	s.writeReconciliation(w, ledgerID, id)
+}
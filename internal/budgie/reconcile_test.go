@@ -0,0 +1,123 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReconciliationWorkflow(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	createEntry := func(date string, amount int64, outflow bool) int64 {
+		t.Helper()
+		body := map[string]any{"entry_date": date, "name": "E " + date, "amount_cents": amount}
+		if outflow {
+			body["src_account_id"] = acctID
+		} else {
+			body["dest_account_id"] = acctID
+		}
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/entries", body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create entry: status %d", resp.StatusCode)
+		}
+		created := mustMap(t, decodeAPIResponse(t, resp).Data)
+		if created["status"] != "pending" {
+			t.Fatalf("expected new entries to be pending, got %v", created["status"])
+		}
+		return mustInt64(t, created["id"])
+	}
+	rent := createEntry("2026-01-03", 50000, true)
+	pay := createEntry("2026-01-15", 20000, false)
+	late := createEntry("2026-02-02", 1000, true)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/reconciliations", map[string]any{
+		"account_id": acctID, "statement_date": "2026-01-31", "statement_balance_cents": 69000,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("start reconciliation: status %d", resp.StatusCode)
+	}
+	session := mustMap(t, decodeAPIResponse(t, resp).Data)
+	sessionURL := api.URL + "/api/reconciliations/" + fmtInt64(mustInt64(t, session["id"]))
+	if got := len(mustList(t, session["entries"])); got != 2 {
+		t.Fatalf("expected 2 entries up to the statement date, got %d", got)
+	}
+	if got := mustInt64(t, session["difference_cents"]); got != -31000 {
+		t.Fatalf("expected difference -31000 before clearing, got %d", got)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/reconciliations", map[string]any{
+		"account_id": acctID, "statement_date": "2026-01-31", "statement_balance_cents": 0,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a second open session, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, sessionURL+"/clear", map[string]any{"entry_ids": []int64{rent, pay}, "cleared": true})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("clear entries: status %d", resp.StatusCode)
+	}
+	session = mustMap(t, decodeAPIResponse(t, resp).Data)
+	if got := mustInt64(t, session["difference_cents"]); got != -1000 {
+		t.Fatalf("expected difference -1000 after clearing, got %d", got)
+	}
+
+	resp = doJSON(t, http.MethodPost, sessionURL+"/finish", map[string]any{})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 finishing with a difference, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodPost, sessionURL+"/finish", map[string]any{"adjust": true})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("finish with adjustment: status %d", resp.StatusCode)
+	}
+	session = mustMap(t, decodeAPIResponse(t, resp).Data)
+	if session["status"] != "finished" || mustInt64(t, session["difference_cents"]) != 0 {
+		t.Fatalf("unexpected finished session: %v", session)
+	}
+	if got := len(mustList(t, session["entries"])); got != 3 {
+		t.Fatalf("expected 2 cleared entries plus the adjustment to be locked, got %d", got)
+	}
+
+	var status string
+	if err := db.QueryRow("SELECT status FROM entry WHERE id = ?", late).Scan(&status); err != nil || status != "pending" {
+		t.Fatalf("entry after the statement should stay pending, got %q (%v)", status, err)
+	}
+
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/entries/"+fmtInt64(rent), nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected reconciled entry to be locked, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodDelete, sessionURL, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected finished session to be kept, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/reconciliations?account_id="+fmtInt64(acctID), nil)
+	history := mustList(t, decodeAPIResponse(t, resp).Data)
+	if len(history) != 1 || mustInt64(t, mustMap(t, history[0])["cleared_balance_cents"]) != 69000 {
+		t.Fatalf("unexpected history: %v", history)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/reconciliations", map[string]any{
+		"account_id": acctID, "statement_date": "2026-01-15", "statement_balance_cents": 0,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a statement before the last reconciliation, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
@@ -20,17 +20,61 @@ func RegisterAPI(mux *http.ServeMux, db *sql.DB, auth *AuthService) {
 
 	requireAuth := srv.requireAuth
 
+	mux.HandleFunc("/api/ledgers", requireAuth(srv.ledgers))
+	mux.HandleFunc("/api/ledgers/", requireAuth(srv.ledgerByID))
 	mux.HandleFunc("/api/accounts", requireAuth(srv.accounts))
 	mux.HandleFunc("/api/accounts/correct-balance", requireAuth(srv.accountCorrectBalance))
 	mux.HandleFunc("/api/accounts/", requireAuth(srv.accountByID))
 	mux.HandleFunc("/api/schedules", requireAuth(srv.schedules))
+	mux.HandleFunc("/api/schedules/preview", requireAuth(srv.schedulePreview))
 	mux.HandleFunc("/api/schedules/", requireAuth(srv.scheduleByID))
 	mux.HandleFunc("/api/revisions", requireAuth(srv.revisions))
 	mux.HandleFunc("/api/revisions/", requireAuth(srv.revisionByID))
+	mux.HandleFunc("/api/schedule-exceptions", requireAuth(srv.scheduleExceptions))
+	mux.HandleFunc("/api/schedule-exceptions/", requireAuth(srv.scheduleExceptionByID))
+	mux.HandleFunc("/api/schedule-versions", requireAuth(srv.scheduleVersions))
+	mux.HandleFunc("/api/schedule-versions/", requireAuth(srv.scheduleVersionByID))
+	mux.HandleFunc("/api/interest/post", requireAuth(srv.interestPost))
+	mux.HandleFunc("/api/networth/snapshot", requireAuth(srv.networthSnapshot))
+	mux.HandleFunc("/api/networth/history", requireAuth(srv.networthHistory))
+	mux.HandleFunc("/api/forecast/alerts", requireAuth(srv.forecastAlerts))
+	mux.HandleFunc("/api/holidays", requireAuth(srv.holidays))
+	mux.HandleFunc("/api/holidays/import", requireAuth(srv.holidaysImport))
+	mux.HandleFunc("/api/holidays/", requireAuth(srv.holidayByID))
 	mux.HandleFunc("/api/entries", requireAuth(srv.entries))
 	mux.HandleFunc("/api/entries/", requireAuth(srv.entryByID))
+	mux.HandleFunc("/api/categories", requireAuth(srv.categories))
+	mux.HandleFunc("/api/categories/rollup", requireAuth(srv.categoryRollups))
+	mux.HandleFunc("/api/categories/", requireAuth(srv.categoryByID))
+	mux.HandleFunc("/api/fx-rates", requireAuth(srv.fxRates))
+	mux.HandleFunc("/api/fx-rates/import", requireAuth(srv.fxRatesImport))
+	mux.HandleFunc("/api/fx-rates/", requireAuth(srv.fxRateByID))
+	mux.HandleFunc("/api/securities", requireAuth(srv.securities))
+	mux.HandleFunc("/api/securities/", requireAuth(srv.securityByID))
+	mux.HandleFunc("/api/security-prices", requireAuth(srv.securityPrices))
+	mux.HandleFunc("/api/security-prices/import", requireAuth(srv.securityPricesImport))
+	mux.HandleFunc("/api/security-prices/", requireAuth(srv.securityPriceByID))
+	mux.HandleFunc("/api/investments", requireAuth(srv.investments))
+	mux.HandleFunc("/api/investments/", requireAuth(srv.investmentByID))
+	mux.HandleFunc("/api/holdings", requireAuth(srv.holdings))
+	mux.HandleFunc("/api/payees", requireAuth(srv.payees))
+	mux.HandleFunc("/api/payees/merge-suggestions", requireAuth(srv.payeeMergeSuggestions))
+	mux.HandleFunc("/api/payees/", requireAuth(srv.payeeByID))
+	mux.HandleFunc("/api/reconciliations", requireAuth(srv.reconciliations))
+	mux.HandleFunc("/api/reconciliations/", requireAuth(srv.reconciliationByID))
+	mux.HandleFunc("/api/attachments", requireAuth(srv.attachments))
+	mux.HandleFunc("/api/attachments/", requireAuth(srv.attachmentByID))
+	mux.HandleFunc("/api/scenarios", requireAuth(srv.scenarios))
+	mux.HandleFunc("/api/scenarios/", requireAuth(srv.scenarioByID))
+	mux.HandleFunc("/api/goals", requireAuth(srv.goals))
+	mux.HandleFunc("/api/goals/", requireAuth(srv.goalByID))
+	mux.HandleFunc("/api/budgets", requireAuth(srv.budgets))
+	mux.HandleFunc("/api/budgets/report", requireAuth(srv.budgetReport))
+	mux.HandleFunc("/api/budgets/", requireAuth(srv.budgetByID))
 	mux.HandleFunc("/api/occurrences", requireAuth(srv.occurrences))
+	mux.HandleFunc("/api/occurrences/post", requireAuth(srv.postOccurrence))
 	mux.HandleFunc("/api/balances", requireAuth(srv.balances))
 	mux.HandleFunc("/api/balances/series", requireAuth(srv.balancesSeries))
+	mux.HandleFunc("/api/balances/series/simulate", requireAuth(srv.balancesSimulate))
 	mux.HandleFunc("/api/dashboard/layout", requireAuth(srv.dashboardLayout))
 }
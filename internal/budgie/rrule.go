@@ -0,0 +1,437 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRULE schedules are expanded in Go and stored in schedule_rrule_date, which
+// the occurrence CTE reads alongside the legacy freq recursion. Expansion stops
+// at the first of COUNT, UNTIL, the schedule's end_date, rruleHorizonYears
+// after start_date, or maxRRuleDates dates.
+const (
+	rruleHorizonYears = 50
+	maxRRuleDates     = 20000
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// rruleDay is one BYDAY value, e.g. "-1FR" (last Friday) or "TU" (every
+// Tuesday, Ordinal 0).
+type rruleDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// rrule is the subset of RFC 5545 recurrence rules that schedules support.
+// Rules are date-only: time parts of UNTIL are ignored.
+type rrule struct {
+	Freq       string
+	Interval   int
+	ByDay      []rruleDay
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	Count      int
+	Until      *time.Time
+	WeekStart  time.Weekday
+}
+
+// parseRRule parses and validates an RRULE value such as
+// "FREQ=MONTHLY;BYDAY=2FR,4FR". A leading "RRULE:" is accepted.
+func parseRRule(s string) (*rrule, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	if s == "" {
+		return nil, errors.New("rrule is empty")
+	}
+	r := &rrule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s is given more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.Freq = val
+			default:
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(val); err != nil || r.Interval < 1 {
+				return nil, errors.New("INTERVAL must be a positive integer")
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(val); err != nil || r.Count < 1 {
+				return nil, errors.New("COUNT must be a positive integer")
+			}
+		case "UNTIL":
+			if len(val) < 8 {
+				return nil, errors.New("UNTIL must be a date (YYYYMMDD)")
+			}
+			t, err := time.Parse("20060102", val[:8])
+			if err != nil || (len(val) > 8 && val[8] != 'T') {
+				return nil, errors.New("UNTIL must be a date (YYYYMMDD)")
+			}
+			r.Until = &t
+		case "WKST":
+			wd, ok := rruleWeekdays[val]
+			if !ok {
+				return nil, errors.New("WKST must be a weekday (MO..SU)")
+			}
+			r.WeekStart = wd
+		case "BYDAY":
+			for _, v := range strings.Split(val, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY value %q", v)
+				}
+				wd, ok := rruleWeekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY value %q", v)
+				}
+				d := rruleDay{Weekday: wd}
+				if n := v[:len(v)-2]; n != "" {
+					if d.Ordinal, err = strconv.Atoi(n); err != nil || d.Ordinal == 0 || d.Ordinal < -53 || d.Ordinal > 53 {
+						return nil, fmt.Errorf("invalid BYDAY value %q", v)
+					}
+				}
+				r.ByDay = append(r.ByDay, d)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseRRuleInts(val, 31, true); err != nil {
+				return nil, fmt.Errorf("BYMONTHDAY: %w", err)
+			}
+		case "BYMONTH":
+			if r.ByMonth, err = parseRRuleInts(val, 12, false); err != nil {
+				return nil, fmt.Errorf("BYMONTH: %w", err)
+			}
+		case "BYSETPOS":
+			if r.BySetPos, err = parseRRuleInts(val, 366, true); err != nil {
+				return nil, fmt.Errorf("BYSETPOS: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("%s is not supported", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	for _, d := range r.ByDay {
+		if d.Ordinal == 0 {
+			continue
+		}
+		if r.Freq != "MONTHLY" && r.Freq != "YEARLY" {
+			return nil, errors.New("BYDAY ordinals are only allowed with FREQ=MONTHLY or FREQ=YEARLY")
+		}
+		if (r.Freq == "MONTHLY" || len(r.ByMonth) > 0) && (d.Ordinal < -5 || d.Ordinal > 5) {
+			return nil, errors.New("BYDAY ordinals within a month must be -5..5")
+		}
+	}
+	if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return nil, errors.New("BYSETPOS requires BYDAY, BYMONTHDAY or BYMONTH")
+	}
+	return r, nil
+}
+
+// parseRRuleInts parses a comma separated list of values in 1..max, or also
+// -max..-1 when negative values are allowed.
+func parseRRuleInts(val string, max int, negative bool) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n == 0 || n > max || n < -max || (!negative && n < 0) {
+			if negative {
+				return nil, fmt.Errorf("values must be 1..%d or -%d..-1", max, max)
+			}
+			return nil, fmt.Errorf("values must be 1..%d", max)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// legacyFreq maps FREQ onto schedule.freq so existing columns stay meaningful.
+func (r *rrule) legacyFreq() string {
+	return map[string]string{"DAILY": "D", "WEEKLY": "W", "MONTHLY": "M", "YEARLY": "Y"}[r.Freq]
+}
+
+// expand returns the rule's dates from start through end (inclusive), at most
+// limit of them. Only dates matching the rule are returned; start itself is
+// not an implicit first occurrence.
+func (r *rrule) expand(start, end time.Time, limit int) []time.Time {
+	if r.Until != nil && r.Until.Before(end) {
+		end = *r.Until
+	}
+	var out []time.Time
+	matched := 0
+	for period := r.firstPeriod(start); !period.After(end); period = r.nextPeriod(period) {
+		for _, d := range r.periodDates(period, start) {
+			if d.Before(start) {
+				continue
+			}
+			if d.After(end) {
+				return out
+			}
+			out = append(out, d)
+			matched++
+			if (r.Count > 0 && matched >= r.Count) || len(out) >= limit {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+func (r *rrule) firstPeriod(start time.Time) time.Time {
+	switch r.Freq {
+	case "WEEKLY":
+		back := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		return start.AddDate(0, 0, -back)
+	case "MONTHLY":
+		return time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "YEARLY":
+		return time.Date(start.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return start
+}
+
+func (r *rrule) nextPeriod(p time.Time) time.Time {
+	switch r.Freq {
+	case "WEEKLY":
+		return p.AddDate(0, 0, 7*r.Interval)
+	case "MONTHLY":
+		return p.AddDate(0, r.Interval, 0)
+	case "YEARLY":
+		return p.AddDate(r.Interval, 0, 0)
+	}
+	return p.AddDate(0, 0, r.Interval)
+}
+
+// periodDates lists the candidate dates of one period in order, after BYSETPOS.
+func (r *rrule) periodDates(p, start time.Time) []time.Time {
+	var set []time.Time
+	switch r.Freq {
+	case "DAILY":
+		if r.matchMonth(p) && r.matchMonthDay(p) && r.matchWeekday(p) {
+			set = append(set, p)
+		}
+	case "WEEKLY":
+		for i := 0; i < 7; i++ {
+			d := p.AddDate(0, 0, i)
+			if !r.matchMonth(d) {
+				continue
+			}
+			if len(r.ByDay) > 0 && r.matchWeekday(d) || len(r.ByDay) == 0 && d.Weekday() == start.Weekday() {
+				set = append(set, d)
+			}
+		}
+	case "MONTHLY":
+		if r.matchMonth(p) {
+			set = r.monthDates(p.Year(), p.Month(), start)
+		}
+	case "YEARLY":
+		switch {
+		case len(r.ByMonth) > 0:
+			months := append([]int(nil), r.ByMonth...)
+			sort.Ints(months)
+			for _, m := range months {
+				set = append(set, r.monthDates(p.Year(), time.Month(m), start)...)
+			}
+		case len(r.ByDay) > 0:
+			for d := p; d.Year() == p.Year(); d = d.AddDate(0, 0, 1) {
+				if r.matchDayInScope(d, p, p.AddDate(1, 0, -1)) && r.matchMonthDay(d) {
+					set = append(set, d)
+				}
+			}
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				set = append(set, r.monthDates(p.Year(), m, start)...)
+			}
+		default:
+			d := time.Date(p.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+			if d.Month() == start.Month() {
+				set = append(set, d)
+			}
+		}
+	}
+	return r.applySetPos(set)
+}
+
+// monthDates lists the days of one month selected by BYMONTHDAY and BYDAY,
+// defaulting to the start date's day of month.
+func (r *rrule) monthDates(year int, month time.Month, start time.Time) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	var out []time.Time
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		switch {
+		case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+			if d.Day() != start.Day() {
+				continue
+			}
+		case len(r.ByDay) > 0 && !r.matchDayInScope(d, first, last):
+			continue
+		case !r.matchMonthDay(d):
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func (r *rrule) matchMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == d.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || md < 0 && daysInMonth+1+md == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, bd := range r.ByDay {
+		if bd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDayInScope reports whether d matches a BYDAY entry, counting ordinals
+// within first..last (a month or a year).
+func (r *rrule) matchDayInScope(d, first, last time.Time) bool {
+	for _, bd := range r.ByDay {
+		if bd.Weekday != d.Weekday() {
+			continue
+		}
+		if bd.Ordinal == 0 {
+			return true
+		}
+		if bd.Ordinal > 0 && int(d.Sub(first).Hours()/24)/7+1 == bd.Ordinal {
+			return true
+		}
+		if bd.Ordinal < 0 && -(int(last.Sub(d).Hours()/24)/7+1) == bd.Ordinal {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) applySetPos(set []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(set) == 0 {
+		return set
+	}
+	picked := map[time.Time]bool{}
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(set) + pos
+		}
+		if i >= 0 && i < len(set) {
+			picked[set[i]] = true
+		}
+	}
+	out := make([]time.Time, 0, len(picked))
+	for d := range picked {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// rruleDates expands a schedule's rule from start_date up to end_date or the
+// expansion horizon.
+func rruleDates(rule *rrule, startDate string, endDate *string) ([]string, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(rruleHorizonYears, 0, 0)
+	if endDate != nil {
+		if e, err := time.Parse("2006-01-02", *endDate); err == nil && e.Before(end) {
+			end = e
+		}
+	}
+	dates := rule.expand(start, end, maxRRuleDates)
+	out := make([]string, len(dates))
+	for i, d := range dates {
+		out[i] = d.Format("2006-01-02")
+	}
+	return out, nil
+}
+
+// refreshRRuleDates rewrites the stored expansion of a schedule's rule. Legacy
+// schedules (no rule) have no stored dates.
+func refreshRRuleDates(tx *sql.Tx, scheduleID int64, rule *string, startDate string, endDate *string) error {
+	if _, err := tx.Exec("DELETE FROM schedule_rrule_date WHERE schedule_id = ?", scheduleID); err != nil {
+		return err
+	}
+	if rule == nil {
+		return nil
+	}
+	parsed, err := parseRRule(*rule)
+	if err != nil {
+		return err
+	}
+	dates, err := rruleDates(parsed, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO schedule_rrule_date (schedule_id, occ_date) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, d := range dates {
+		if _, err := stmt.Exec(scheduleID, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
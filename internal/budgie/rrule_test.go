@@ -0,0 +1,159 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRRuleExpand(t *testing.T) {
+	cases := []struct {
+		rule, start, end string
+		want             []string
+	}{
+		{"FREQ=MONTHLY;BYDAY=2FR,4FR", "2026-01-01", "2026-03-31",
+			[]string{"2026-01-09", "2026-01-23", "2026-02-13", "2026-02-27", "2026-03-13", "2026-03-27"}},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-01", "2026-03-31",
+			[]string{"2026-01-30", "2026-02-27", "2026-03-31"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", "2026-01-10", "2026-03-01",
+			[]string{"2026-01-15", "2026-02-01", "2026-02-15", "2026-03-01"}},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "2026-01-01", "2026-12-31",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31"}},
+		{"FREQ=YEARLY;BYMONTH=3,9", "2026-01-15", "2027-03-31",
+			[]string{"2026-03-15", "2026-09-15", "2027-03-15"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20260131", "2026-01-05", "2026-12-31",
+			[]string{"2026-01-05", "2026-01-08", "2026-01-19", "2026-01-22"}},
+		{"FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO", "2026-01-01", "2027-12-31",
+			[]string{"2026-05-25", "2027-05-31"}},
+		{"FREQ=DAILY;INTERVAL=3;COUNT=4", "2026-01-30", "2026-12-31",
+			[]string{"2026-01-30", "2026-02-02", "2026-02-05", "2026-02-08"}},
+	}
+	for _, c := range cases {
+		rule, err := parseRRule(c.rule)
+		if err != nil {
+			t.Fatalf("parseRRule(%q): %v", c.rule, err)
+		}
+		start, _ := time.Parse("2006-01-02", c.start)
+		end, _ := time.Parse("2006-01-02", c.end)
+		var got []string
+		for _, d := range rule.expand(start, end, 1000) {
+			got = append(got, d.Format("2006-01-02"))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%s from %s: got %v, want %v", c.rule, c.start, got, c.want)
+		}
+	}
+}
+
+func TestParseRRuleInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;BYDAY=2FR",
+		"FREQ=MONTHLY;BYDAY=6FR",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20260101",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=MONTHLY;BYHOUR=9",
+		"FREQ=MONTHLY;FREQ=YEARLY",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+	} {
+		if _, err := parseRRule(rule); err == nil {
+			t.Fatalf("expected error for %q", rule)
+		}
+	}
+}
+
+func TestParseSchedulePayloadRRule(t *testing.T) {
+	req := newJSONRequest(t, `{"name":"Pay","kind":"I","amount_cents":100,"dest_account_id":1,"start_date":"2026-01-01","rrule":"freq=monthly;interval=2;bymonthday=1,15","bymonthday":3}`)
+	p, err := parseSchedulePayload(req)
+	if err != nil {
+		t.Fatalf("expected valid payload, got %v", err)
+	}
+	if p.Freq != "M" || p.Interval != 2 || p.ByMonthDay != nil || *p.RRule != "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=1,15" {
+		t.Fatalf("unexpected payload values: %+v", p)
+	}
+
+	req = newJSONRequest(t, `{"name":"Pay","kind":"I","amount_cents":100,"dest_account_id":1,"start_date":"2026-01-01","rrule":"FREQ=MONTHLY;BYDAY=9MO"}`)
+	if _, err := parseSchedulePayload(req); err == nil {
+		t.Fatalf("expected invalid rrule to be rejected")
+	}
+}
+
+func TestRRuleScheduleOccurrences(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(0),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	payload := map[string]any{
+		"name":            "Paycheck",
+		"kind":            "I",
+		"amount_cents":    1000,
+		"dest_account_id": acctID,
+		"start_date":      "2026-01-01",
+		"rrule":           "FREQ=MONTHLY;BYDAY=2FR,4FR",
+	}
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", payload)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	schedID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	occurrenceDates := func() []string {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-01&to_date=2026-02-28", nil)
+		var dates []string
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			dates = append(dates, mustMap(t, item)["occ_date"].(string))
+		}
+		return dates
+	}
+	if got, want := occurrenceDates(), []string{"2026-01-09", "2026-01-23", "2026-02-13", "2026-02-27"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-02-28", nil)
+	row := mustMap(t, mustList(t, decodeAPIResponse(t, resp).Data)[0])
+	if got := mustInt64(t, row["projected_balance_cents"]); got != 4000 {
+		t.Fatalf("expected projected balance 4000, got %d", got)
+	}
+
+	// An edit that leaves the rule out, like the schedule editor's, keeps it.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(schedID), map[string]any{
+		"name": "Salary", "kind": "I", "amount_cents": 1000, "dest_account_id": acctID,
+		"start_date": "2026-01-01", "freq": "M", "interval": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("rename schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	if got, want := occurrenceDates(), []string{"2026-01-09", "2026-01-23", "2026-02-13", "2026-02-27"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences after rename = %v, want %v", got, want)
+	}
+
+	// Switching back to a legacy frequency drops the stored rule dates.
+	payload["rrule"] = nil
+	payload["freq"] = "M"
+	payload["interval"] = 1
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(schedID), payload)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	if got, want := occurrenceDates(), []string{"2026-01-01", "2026-02-01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences after update = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,375 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Scenarios are what-if overlays on the plan. Their schedules, overrides and
+// entries live in their own tables and only take effect in projections asked
+// for with a scenario_id; see occurrenceCTEDefs.
+
+type scenarioBody struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+// scenarioParam reads the optional scenario_id query parameter, checking the
+// scenario belongs to the ledger. It returns 0 when absent.
+func (s *server) scenarioParam(r *http.Request, ledgerID int64) (int64, *apiErr) {
+	v := strings.TrimSpace(r.URL.Query().Get("scenario_id"))
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequest("scenario_id must be a positive integer", nil)
+	}
+	if e := s.ledgerOwnsRow(ledgerID, "scenario", &id); e != nil {
+		return 0, e
+	}
+	return id, nil
+}
+
+// scenarios serves GET/POST /api/scenarios.
+func (s *server) scenarios(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Query("SELECT * FROM scenario WHERE ledger_id = ? ORDER BY name", ledgerID)
+		if err != nil {
+			writeErr(w, serverError("failed to query scenarios", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read scenarios", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body scenarioBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		res, err := s.db.Exec("INSERT INTO scenario (ledger_id, name, description) VALUES (?, ?, ?)", ledgerID, body.Name, body.Description)
+		if err != nil {
+			writeErr(w, badRequest("could not create scenario", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "scenario", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// scenarioItem describes one kind of overlay row. validate reads and checks
+// a request body and returns the columns to store.
+type scenarioItem struct {
+	table    string
+	validate func(s *server, ledgerID int64, r *http.Request) ([]string, []any, *apiErr)
+}
+
+var scenarioItems = map[string]scenarioItem{
+	"schedules": {"scenario_schedule", validateScenarioSchedule},
+	"overrides": {"scenario_override", validateScenarioOverride},
+	"entries":   {"scenario_entry", validateScenarioEntry},
+}
+
+// scenarioByID serves:
+//
+//	GET/PUT/DELETE /api/scenarios/{id}
+//	POST           /api/scenarios/{id}/{schedules|overrides|entries}
+//	PUT/DELETE     /api/scenarios/{id}/{schedules|overrides|entries}/{item_id}
+//
+// GET returns the scenario with all of its overlay rows.
+func (s *server) scenarioByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/scenarios/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 3 {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM scenario WHERE id = ? AND ledger_id = ?", id, ledgerID).Scan(&n); err != nil {
+		writeErr(w, serverError("failed to read scenario", err))
+		return
+	}
+	if n == 0 {
+		writeErr(w, notFound("scenario not found"))
+		return
+	}
+
+	if len(parts) > 1 {
+		item, ok := scenarioItems[parts[1]]
+		if !ok {
+			writeErr(w, notFound("not found"))
+			return
+		}
+		var itemID int64
+		if len(parts) == 3 {
+			if itemID, err = strconv.ParseInt(parts[2], 10, 64); err != nil || itemID <= 0 {
+				writeErr(w, notFound("not found"))
+				return
+			}
+		}
+		s.scenarioItem(w, r, ledgerID, id, item, itemID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		out, apiE := scanRowToMap(s.db, "scenario", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		for key, item := range scenarioItems {
+			rows, err := s.db.Query("SELECT * FROM "+item.table+" WHERE scenario_id = ? ORDER BY id", id)
+			if err != nil {
+				writeErr(w, serverError("failed to query scenario "+key, err))
+				return
+			}
+			data, err := rowsToMaps(rows)
+			rows.Close()
+			if err != nil {
+				writeErr(w, serverError("failed to read scenario "+key, err))
+				return
+			}
+			out[key] = data
+		}
+		writeOK(w, out)
+	case http.MethodPut:
+		var body scenarioBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" {
+			writeErr(w, badRequest("name is required", nil))
+			return
+		}
+		if _, err := s.db.Exec("UPDATE scenario SET name=?, description=? WHERE id=? AND ledger_id=?", body.Name, body.Description, id, ledgerID); err != nil {
+			writeErr(w, badRequest("could not update scenario", nil))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "scenario", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		if _, err := s.db.Exec("DELETE FROM scenario WHERE id = ? AND ledger_id = ?", id, ledgerID); err != nil {
+			writeErr(w, badRequest("could not delete scenario", nil))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// scenarioItem creates (itemID 0), updates or deletes one overlay row of a
+// scenario already checked to belong to the ledger.
+func (s *server) scenarioItem(w http.ResponseWriter, r *http.Request, ledgerID, scenarioID int64, item scenarioItem, itemID int64) {
+	switch {
+	case itemID == 0 && r.Method == http.MethodPost, itemID != 0 && r.Method == http.MethodPut:
+		cols, vals, e := item.validate(s, ledgerID, r)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		var q string
+		if itemID == 0 {
+			q = "INSERT INTO " + item.table + " (scenario_id, " + strings.Join(cols, ", ") + ") VALUES (?" + strings.Repeat(", ?", len(cols)) + ") RETURNING id"
+			vals = append([]any{scenarioID}, vals...)
+		} else {
+			q = "UPDATE " + item.table + " SET " + strings.Join(cols, "=?, ") + "=? WHERE id = ? AND scenario_id = ? RETURNING id"
+			vals = append(vals, itemID, scenarioID)
+		}
+		err := s.db.QueryRow(q, vals...).Scan(&itemID)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErr(w, notFound("scenario item not found"))
+			return
+		}
+		if err != nil {
+			writeErr(w, badRequest("could not save scenario item", nil))
+			return
+		}
+		saved, apiE := scanRowToMap(s.db, item.table, itemID)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, saved)
+	case itemID != 0 && r.Method == http.MethodDelete:
+		res, err := s.db.Exec("DELETE FROM "+item.table+" WHERE id = ? AND scenario_id = ?", itemID, scenarioID)
+		if err != nil {
+			writeErr(w, badRequest("could not delete scenario item", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("scenario item not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// validateScenarioSchedule takes the same payload as /api/schedules, less
+// rrule, is_active and payee_id.
+func validateScenarioSchedule(s *server, ledgerID int64, r *http.Request) ([]string, []any, *apiErr) {
+	p, e := parseSchedulePayload(r)
+	if e != nil {
+		return nil, nil, e
+	}
+	if p.RRule != nil {
+		return nil, nil, badRequest("rrule is not supported on scenario schedules", nil)
+	}
+	if p.Escalation != "none" {
+		return nil, nil, badRequest("escalation is not supported on scenario schedules", nil)
+	}
+	if p.MaxOcc != nil || p.StopPaidOff != 0 {
+		return nil, nil, badRequest("max_occurrences and stop_when_paid_off are not supported on scenario schedules", nil)
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, p.SrcAccountID, p.DestAccountID); e != nil {
+		return nil, nil, e
+	}
+	if e := s.ledgerOwnsCategory(ledgerID, p.CategoryID); e != nil {
+		return nil, nil, e
+	}
+	if p.DestAmount, e = s.transferDestAmount(p.SrcAccountID, p.DestAccountID, p.DestAmount); e != nil {
+		return nil, nil, e
+	}
+	return []string{
+		"name", "kind", "amount_cents", "src_account_id", "dest_account_id", "dest_amount_cents",
+		"start_date", "end_date", "freq", "interval", "bymonthday", "byweekday",
+		"business_day_roll", "holiday_calendar", "description", "category_id",
+	}, []any{
+		p.Name, p.Kind, p.AmountCents, p.SrcAccountID, p.DestAccountID, p.DestAmount,
+		p.StartDate, p.EndDate, p.Freq, p.Interval, p.ByMonthDay, p.ByWeekday,
+		p.Roll, p.Calendar, p.Description, p.CategoryID,
+	}, nil
+}
+
+// validateScenarioOverride checks a change to a real schedule: disable, or
+// amount with amount_cents (and dest_amount_cents for cross-currency
+// transfers), from effective_date or for every occurrence.
+func validateScenarioOverride(s *server, ledgerID int64, r *http.Request) ([]string, []any, *apiErr) {
+	var b struct {
+		ScheduleID    int64   `json:"schedule_id"`
+		Kind          string  `json:"kind"`
+		EffectiveDate *string `json:"effective_date"`
+		AmountCents   *int64  `json:"amount_cents"`
+		DestAmount    *int64  `json:"dest_amount_cents"`
+	}
+	if e := readJSON(r, &b); e != nil {
+		return nil, nil, e
+	}
+	if b.ScheduleID == 0 {
+		return nil, nil, badRequest("schedule_id is required", nil)
+	}
+	if e := s.ledgerOwnsSchedule(ledgerID, &b.ScheduleID); e != nil {
+		return nil, nil, e
+	}
+	var e *apiErr
+	if b.EffectiveDate, e = optionalDate(b.EffectiveDate, "effective_date"); e != nil {
+		return nil, nil, e
+	}
+	switch b.Kind {
+	case "disable":
+		b.AmountCents, b.DestAmount = nil, nil
+	case "amount":
+		if b.AmountCents == nil || *b.AmountCents <= 0 {
+			return nil, nil, badRequest("amount_cents must be > 0", nil)
+		}
+		// Without an effective date the override starts with the schedule.
+		date := ""
+		if b.EffectiveDate != nil {
+			date = *b.EffectiveDate
+		}
+		src, dest, err := s.scheduleAccountsOn(b.ScheduleID, date)
+		if err != nil {
+			return nil, nil, serverError("failed to read schedule", err)
+		}
+		if src.Valid && dest.Valid {
+			if b.DestAmount, e = s.transferDestAmount(&src.Int64, &dest.Int64, b.DestAmount); e != nil {
+				return nil, nil, e
+			}
+		} else {
+			b.DestAmount = nil
+		}
+	default:
+		return nil, nil, badRequest("kind must be 'disable' or 'amount'", nil)
+	}
+	return []string{"schedule_id", "kind", "effective_date", "amount_cents", "dest_amount_cents"},
+		[]any{b.ScheduleID, b.Kind, b.EffectiveDate, b.AmountCents, b.DestAmount}, nil
+}
+
+// validateScenarioEntry checks a hypothetical one-off entry.
+func validateScenarioEntry(s *server, ledgerID int64, r *http.Request) ([]string, []any, *apiErr) {
+	var b struct {
+		EntryDate     string  `json:"entry_date"`
+		Name          string  `json:"name"`
+		AmountCents   int64   `json:"amount_cents"`
+		SrcAccountID  *int64  `json:"src_account_id"`
+		DestAccountID *int64  `json:"dest_account_id"`
+		DestAmount    *int64  `json:"dest_amount_cents"`
+		Description   *string `json:"description"`
+		CategoryID    *int64  `json:"category_id"`
+	}
+	if e := readJSON(r, &b); e != nil {
+		return nil, nil, e
+	}
+	if _, e := requireDate(b.EntryDate, "entry_date"); e != nil {
+		return nil, nil, e
+	}
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return nil, nil, badRequest("name is required", nil)
+	}
+	if b.AmountCents <= 0 {
+		return nil, nil, badRequest("amount_cents must be > 0", nil)
+	}
+	if b.SrcAccountID == nil && b.DestAccountID == nil {
+		return nil, nil, badRequest("must set src_account_id and/or dest_account_id", nil)
+	}
+	if b.SrcAccountID != nil && b.DestAccountID != nil && *b.SrcAccountID == *b.DestAccountID {
+		return nil, nil, badRequest("src_account_id and dest_account_id must differ", nil)
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, b.SrcAccountID, b.DestAccountID); e != nil {
+		return nil, nil, e
+	}
+	if e := s.ledgerOwnsCategory(ledgerID, b.CategoryID); e != nil {
+		return nil, nil, e
+	}
+	var e *apiErr
+	if b.DestAmount, e = s.transferDestAmount(b.SrcAccountID, b.DestAccountID, b.DestAmount); e != nil {
+		return nil, nil, e
+	}
+	return []string{"entry_date", "name", "amount_cents", "src_account_id", "dest_account_id", "dest_amount_cents", "description", "category_id"},
+		[]any{b.EntryDate, b.Name, b.AmountCents, b.SrcAccountID, b.DestAccountID, b.DestAmount, b.Description, b.CategoryID}, nil
+}
@@ -0,0 +1,111 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestScenarioOverlay(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	schedule := func(body map[string]any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", body)
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	salaryID := schedule(map[string]any{
+		"name": "Salary", "kind": "I", "amount_cents": 300000, "dest_account_id": checkingID,
+		"start_date": "2026-02-01", "freq": "M", "interval": 1,
+	})
+	streamingID := schedule(map[string]any{
+		"name": "Streaming", "kind": "E", "amount_cents": 2000, "src_account_id": checkingID,
+		"start_date": "2026-01-15", "freq": "M", "interval": 1,
+	})
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/scenarios", map[string]any{"name": "New job"})
+	scenarioID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	base := api.URL + "/api/scenarios/" + fmtInt64(scenarioID)
+	for _, item := range []struct {
+		path string
+		body map[string]any
+	}{
+		{"/schedules", map[string]any{
+			"name": "Bonus", "kind": "I", "amount_cents": 50000, "dest_account_id": checkingID,
+			"start_date": "2026-03-01", "freq": "M", "interval": 1,
+		}},
+		{"/overrides", map[string]any{"schedule_id": streamingID, "kind": "disable", "effective_date": "2026-03-01"}},
+		{"/overrides", map[string]any{"schedule_id": salaryID, "kind": "amount", "amount_cents": 350000, "effective_date": "2026-04-01"}},
+		{"/entries", map[string]any{"entry_date": "2026-03-10", "name": "Car", "amount_cents": 200000, "src_account_id": checkingID}},
+	} {
+		resp := doJSON(t, http.MethodPost, base+item.path, item.body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("add %s: status %d", item.path, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+	resp = doJSON(t, http.MethodPost, base+"/overrides", map[string]any{"schedule_id": salaryID, "kind": "amount"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an amount override without amount_cents, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, base, nil)
+	got := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if len(mustList(t, got["schedules"])) != 1 || len(mustList(t, got["overrides"])) != 2 || len(mustList(t, got["entries"])) != 1 {
+		t.Fatalf("unexpected scenario: %v", got)
+	}
+
+	projected := func(query string) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-04-30"+query, nil)
+		return mustInt64(t, mustMap(t, mustList(t, decodeAPIResponse(t, resp).Data)[0])["projected_balance_cents"])
+	}
+	// Baseline: three salaries, four streaming charges.
+	if got := projected(""); got != 100000+3*300000-4*2000 {
+		t.Fatalf("baseline projection = %d", got)
+	}
+	// Scenario: the April salary is raised, two bonuses arrive, streaming
+	// stops after February and the car is bought.
+	want := int64(100000 + 2*300000 + 350000 + 2*50000 - 2*2000 - 200000)
+	if got := projected("&scenario_id=" + fmtInt64(scenarioID)); got != want {
+		t.Fatalf("scenario projection = %d, want %d", got, want)
+	}
+
+	var schedules, entries int
+	if err := db.QueryRow("SELECT (SELECT COUNT(*) FROM schedule), (SELECT COUNT(*) FROM entry)").Scan(&schedules, &entries); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if schedules != 2 || entries != 0 {
+		t.Fatalf("scenario touched real tables: %d schedules, %d entries", schedules, entries)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances/series?from_date=2026-01-01&to_date=2026-04-30&step_days=119&compare=1&scenario_id="+fmtInt64(scenarioID), nil)
+	cmp := mustMap(t, decodeAPIResponse(t, resp).Data)
+	diff := mustList(t, cmp["difference_cents"])
+	baseline := mustList(t, mustMap(t, cmp["baseline"])["total_cents"])
+	if len(diff) != 2 || mustInt64(t, diff[1]) != want-(100000+3*300000-4*2000) || mustInt64(t, baseline[1]) != 100000+3*300000-4*2000 {
+		t.Fatalf("unexpected comparison: %v", cmp)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?as_of=2026-04-30&scenario_id="+fmtInt64(scenarioID), nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a scenario on actual balances, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodDelete, base, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete scenario: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&as_of=2026-04-30&scenario_id="+fmtInt64(scenarioID), nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a deleted scenario, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
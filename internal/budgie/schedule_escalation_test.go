@@ -0,0 +1,117 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestScheduleEscalation(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01",
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	schedule := func(body map[string]any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create schedule: status %d", resp.StatusCode)
+		}
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	// Rent rises 3% every January, rounded to whole dollars.
+	rentID := schedule(map[string]any{
+		"name": "Rent", "kind": "E", "amount_cents": 100000, "src_account_id": checkingID,
+		"start_date": "2027-06-01", "freq": "M", "interval": 6,
+		"escalation_kind": "percent", "escalation_bps": 300, "escalation_anniversary": "01-01", "escalation_round_cents": 100,
+	})
+	// Pay steps up by $50 every second paycheck.
+	payID := schedule(map[string]any{
+		"name": "Pay", "kind": "I", "amount_cents": 200000, "dest_account_id": checkingID,
+		"start_date": "2027-01-15", "freq": "M", "interval": 1,
+		"escalation_kind": "fixed", "escalation_cents": 5000, "escalation_every": 2,
+	})
+	// A revision replaces the escalated rent and later raises build on it.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/revisions", map[string]any{
+		"schedule_id": rentID, "effective_date": "2029-05-01", "amount_cents": 120000,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create revision: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	amounts := func(from, to string) []string {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date="+from+"&to_date="+to, nil)
+		var got []string
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			row := mustMap(t, item)
+			got = append(got, row["name"].(string)+" "+row["occ_date"].(string)+" "+fmtInt64(mustInt64(t, row["amount_cents"])))
+		}
+		return got
+	}
+	if got, want := amounts("2027-01-01", "2027-05-31"), []string{
+		"Pay 2027-01-15 200000",
+		"Pay 2027-02-15 200000",
+		"Pay 2027-03-15 205000",
+		"Pay 2027-04-15 205000",
+		"Pay 2027-05-15 210000",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pay = %v, want %v", got, want)
+	}
+	var rent []string
+	for _, o := range amounts("2027-06-01", "2030-12-31") {
+		if o[:4] == "Rent" {
+			rent = append(rent, o)
+		}
+	}
+	if want := []string{
+		"Rent 2027-06-01 100000",
+		"Rent 2027-12-01 100000",
+		"Rent 2028-06-01 103000",
+		"Rent 2028-12-01 103000",
+		"Rent 2029-06-01 120000",
+		"Rent 2029-12-01 120000",
+		"Rent 2030-06-01 123600",
+		"Rent 2030-12-01 123600",
+	}; !reflect.DeepEqual(rent, want) {
+		t.Fatalf("rent = %v, want %v", rent, want)
+	}
+
+	// An edit that leaves the escalation out, like the schedule editor's, keeps it.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(payID), map[string]any{
+		"name": "Salary", "kind": "I", "amount_cents": 200000, "dest_account_id": checkingID,
+		"start_date": "2027-01-15", "freq": "M", "interval": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit pay: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	if got, want := amounts("2027-04-01", "2027-05-31"), []string{"Salary 2027-04-15 205000", "Salary 2027-05-15 210000"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pay after edit = %v, want %v", got, want)
+	}
+
+	for _, body := range []map[string]any{
+		{"escalation_kind": "percent"},
+		{"escalation_kind": "fixed", "escalation_cents": 100, "escalation_every": 2, "escalation_anniversary": "01-01"},
+		{"escalation_kind": "fixed", "escalation_cents": 100, "escalation_anniversary": "13-01"},
+		{"escalation_kind": "fixed", "escalation_cents": 100, "interval": 13},
+		{"escalation_kind": "compound"},
+	} {
+		payload := map[string]any{
+			"name": "Bad", "kind": "E", "amount_cents": 1000, "src_account_id": checkingID,
+			"start_date": "2027-01-01", "freq": "M", "interval": 1,
+		}
+		for k, v := range body {
+			payload[k] = v
+		}
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", payload)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", body, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
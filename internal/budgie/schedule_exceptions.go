@@ -0,0 +1,212 @@
+package budgie
+
+import (
+	"net/http"
+	"strconv"
+)
+
+type scheduleExceptionBody struct {
+	ScheduleID  int64   `json:"schedule_id"`
+	Kind        string  `json:"kind"`
+	OccDate     string  `json:"occ_date"`
+	EndDate     *string `json:"end_date"`
+	MoveToDate  *string `json:"move_to_date"`
+	AmountCents *int64  `json:"amount_cents"`
+	DestAmount  *int64  `json:"dest_amount_cents"`
+	Note        *string `json:"note"`
+}
+
+// validateScheduleException checks that only the fields used by the kind are
+// set and resolves the destination amount for cross-currency schedules.
+func (s *server) validateScheduleException(ledgerID int64, b *scheduleExceptionBody) *apiErr {
+	if b.ScheduleID == 0 {
+		return badRequest("schedule_id is required", nil)
+	}
+	if e := s.ledgerOwnsSchedule(ledgerID, &b.ScheduleID); e != nil {
+		return e
+	}
+	if _, e := requireDate(b.OccDate, "occ_date"); e != nil {
+		return e
+	}
+	switch b.Kind {
+	case "skip", "move", "amount", "suspend":
+	default:
+		return badRequest("kind must be 'skip', 'move', 'amount' or 'suspend'", nil)
+	}
+
+	if b.Kind == "suspend" {
+		if b.EndDate == nil {
+			return badRequest("end_date is required for suspend", nil)
+		}
+		if _, e := requireDate(*b.EndDate, "end_date"); e != nil {
+			return e
+		}
+		if *b.EndDate < b.OccDate {
+			return badRequest("end_date must be >= occ_date", nil)
+		}
+	} else {
+		b.EndDate = nil
+	}
+
+	if b.Kind == "move" {
+		if b.MoveToDate == nil {
+			return badRequest("move_to_date is required for move", nil)
+		}
+		if _, e := requireDate(*b.MoveToDate, "move_to_date"); e != nil {
+			return e
+		}
+	} else {
+		b.MoveToDate = nil
+	}
+
+	switch b.Kind {
+	case "amount", "move":
+		if b.Kind == "amount" && b.AmountCents == nil {
+			return badRequest("amount_cents is required for amount", nil)
+		}
+		if b.AmountCents == nil {
+			b.DestAmount = nil
+			break
+		}
+		if *b.AmountCents <= 0 {
+			return badRequest("amount_cents must be > 0", nil)
+		}
+		src, dest, err := s.scheduleAccountsOn(b.ScheduleID, b.OccDate)
+		if err != nil {
+			return serverError("failed to read schedule", err)
+		}
+		if src.Valid && dest.Valid {
+			var e *apiErr
+			if b.DestAmount, e = s.transferDestAmount(&src.Int64, &dest.Int64, b.DestAmount); e != nil {
+				return e
+			}
+		} else {
+			b.DestAmount = nil
+		}
+	default:
+		b.AmountCents, b.DestAmount = nil, nil
+	}
+	return nil
+}
+
+// scheduleExceptions serves GET/POST /api/schedule-exceptions. GET accepts an
+// optional schedule_id filter.
+func (s *server) scheduleExceptions(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := `
+			SELECT x.*, s.name AS schedule_name
+			FROM schedule_exception x
+			JOIN schedule s ON s.id = x.schedule_id
+			WHERE s.ledger_id = ?`
+		args := []any{ledgerID}
+		if v := r.URL.Query().Get("schedule_id"); v != "" {
+			scheduleID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				writeErr(w, badRequest("schedule_id must be an integer", nil))
+				return
+			}
+			q += " AND x.schedule_id = ?"
+			args = append(args, scheduleID)
+		}
+		rows, err := s.db.Query(q+" ORDER BY x.schedule_id, x.occ_date", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query schedule exceptions", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read schedule exceptions", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body scheduleExceptionBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateScheduleException(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(
+			"INSERT INTO schedule_exception (schedule_id, kind, occ_date, end_date, move_to_date, amount_cents, dest_amount_cents, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			body.ScheduleID, body.Kind, body.OccDate, body.EndDate, body.MoveToDate, body.AmountCents, body.DestAmount, body.Note,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create schedule exception (one already exists for this occurrence?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "schedule_exception", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) scheduleExceptionByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/schedule-exceptions/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body scheduleExceptionBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateScheduleException(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(`
+			UPDATE schedule_exception
+			SET schedule_id=?, kind=?, occ_date=?, end_date=?, move_to_date=?, amount_cents=?, dest_amount_cents=?, note=?
+			WHERE id = ? AND schedule_id IN (SELECT id FROM schedule WHERE ledger_id = ?)
+		`, body.ScheduleID, body.Kind, body.OccDate, body.EndDate, body.MoveToDate, body.AmountCents, body.DestAmount, body.Note, id, ledgerID)
+		if err != nil {
+			writeErr(w, badRequest("could not update schedule exception (one already exists for this occurrence?)", nil))
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("schedule exception not found"))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "schedule_exception", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec(
+			"DELETE FROM schedule_exception WHERE id = ? AND schedule_id IN (SELECT id FROM schedule WHERE ledger_id = ?)",
+			id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not delete schedule exception", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("schedule exception not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
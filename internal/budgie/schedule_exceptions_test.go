@@ -0,0 +1,126 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestScheduleExceptionsApplyToOccurrences(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name":           "Daycare",
+		"kind":           "E",
+		"amount_cents":   100,
+		"src_account_id": acctID,
+		"start_date":     "2026-01-01",
+		"freq":           "M",
+		"interval":       1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	schedID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	exceptions := []map[string]any{
+		{"kind": "skip", "occ_date": "2026-03-01"},
+		{"kind": "move", "occ_date": "2026-04-01", "move_to_date": "2026-04-02"},
+		{"kind": "amount", "occ_date": "2026-05-01", "amount_cents": 250},
+		{"kind": "suspend", "occ_date": "2026-06-01", "end_date": "2026-08-31"},
+		{"kind": "move", "occ_date": "2026-12-01", "move_to_date": "2026-11-20", "amount_cents": 300},
+	}
+	var skipID int64
+	for _, x := range exceptions {
+		x["schedule_id"] = schedID
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedule-exceptions", x)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create %v exception: status %d", x["kind"], resp.StatusCode)
+		}
+		created := mustMap(t, decodeAPIResponse(t, resp).Data)
+		if x["kind"] == "skip" {
+			skipID = mustInt64(t, created["id"])
+		}
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedule-exceptions", map[string]any{
+		"schedule_id": schedID, "kind": "amount", "occ_date": "2026-03-01", "amount_cents": 1,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a second exception on one occurrence, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedule-exceptions", map[string]any{
+		"schedule_id": schedID, "kind": "suspend", "occ_date": "2026-03-01",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for suspend without end_date, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-01&to_date=2026-11-30", nil)
+	var dates []string
+	var amounts []int64
+	var modified []int64
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		dates = append(dates, row["occ_date"].(string))
+		amounts = append(amounts, mustInt64(t, row["amount_cents"]))
+		modified = append(modified, mustInt64(t, row["modified"]))
+	}
+	wantDates := []string{"2026-01-01", "2026-02-01", "2026-04-02", "2026-05-01", "2026-09-01", "2026-10-01", "2026-11-01", "2026-11-20"}
+	if !reflect.DeepEqual(dates, wantDates) {
+		t.Fatalf("occurrence dates = %v, want %v", dates, wantDates)
+	}
+	if want := []int64{100, 100, 100, 250, 100, 100, 100, 300}; !reflect.DeepEqual(amounts, want) {
+		t.Fatalf("occurrence amounts = %v, want %v", amounts, want)
+	}
+	if want := []int64{0, 0, 1, 1, 0, 0, 0, 1}; !reflect.DeepEqual(modified, want) {
+		t.Fatalf("modified flags = %v, want %v", modified, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-11-30", nil)
+	row := mustMap(t, mustList(t, decodeAPIResponse(t, resp).Data)[0])
+	if got := mustInt64(t, row["projected_balance_cents"]); got != 100000-1150 {
+		t.Fatalf("expected projected balance %d, got %d", 100000-1150, got)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-04-01",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post moved occurrence: status %d", resp.StatusCode)
+	}
+	entry := mustMap(t, decodeAPIResponse(t, resp).Data)
+	if entry["entry_date"] != "2026-04-02" || entry["occurrence_date"] != "2026-04-01" {
+		t.Fatalf("unexpected posted entry: %v", entry)
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": schedID, "occurrence_date": "2026-07-01",
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 posting a suspended occurrence, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/schedule-exceptions/"+fmtInt64(skipID), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete exception: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-03-01&to_date=2026-03-31", nil)
+	if got := len(mustList(t, decodeAPIResponse(t, resp).Data)); got != 1 {
+		t.Fatalf("expected the unskipped occurrence back, got %d rows", got)
+	}
+}
@@ -0,0 +1,268 @@
+package budgie
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPreviewCount = 12
+	maxPreviewCount     = 500
+)
+
+type schedulePreviewBody struct {
+	schedulePayload
+	Revisions []struct {
+		EffectiveDate string `json:"effective_date"`
+		AmountCents   int64  `json:"amount_cents"`
+		DestAmount    *int64 `json:"dest_amount_cents"`
+	} `json:"revisions"`
+	FromDate string `json:"from_date"`
+	Count    int    `json:"count"`
+}
+
+// previewStepDays is the longest gap in days between two occurrences one
+// interval apart.
+var previewStepDays = map[string]int{"D": 1, "W": 7, "M": 31, "Y": 366}
+
+// schedulePreview serves POST /api/schedules/preview. It takes a schedule as
+// the create endpoint does, with optional revisions, and lists its next count
+// occurrences (default 12) from from_date (default today) without saving it.
+// The occurrences come from the same expansion as /api/occurrences, with the
+// schedule staged beside the saved ones (see stagePreviewSchedule). Validation
+// errors are reported under details.fields.
+//
+//	POST /api/schedules/preview
+//	{"name": "Rent", "kind": "E", "amount_cents": 150000, "src_account_id": 1,
+//	 "start_date": "2026-01-01", "freq": "M", "count": 6,
+//	 "revisions": [{"effective_date": "2026-04-01", "amount_cents": 160000}]}
+func (s *server) schedulePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	var body schedulePreviewBody
+	if e := readJSON(r, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+	p := &body.schedulePayload
+	var errs fieldErrors
+	validateSchedulePayload(p, &errs)
+
+	from := time.Now().Format("2006-01-02")
+	if body.FromDate != "" {
+		if _, e := requireDate(body.FromDate, "from_date"); e != nil {
+			errs.add("from_date", e.Message)
+		}
+		from = body.FromDate
+	}
+	if body.Count == 0 {
+		body.Count = defaultPreviewCount
+	}
+	if body.Count < 1 || body.Count > maxPreviewCount {
+		errs.add("count", fmt.Sprintf("count must be 1..%d", maxPreviewCount))
+	}
+
+	// Ownership and currency checks need the accounts to be sound first.
+	checks := []struct {
+		field string
+		check func() *apiErr
+	}{
+		{"src_account_id", func() *apiErr { return s.ledgerOwnsAccounts(ledgerID, p.SrcAccountID) }},
+		{"dest_account_id", func() *apiErr { return s.ledgerOwnsAccounts(ledgerID, p.DestAccountID) }},
+		{"category_id", func() *apiErr { return s.ledgerOwnsCategory(ledgerID, p.CategoryID) }},
+		{"payee_id", func() *apiErr { return s.ledgerOwnsRow(ledgerID, "payee", p.PayeeID) }},
+	}
+	for _, c := range checks {
+		if errs.has(c.field) {
+			continue
+		}
+		if e := errs.check(c.field, c.check()); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+	accountsOK := !errs.has("kind", "src_account_id", "dest_account_id")
+	if accountsOK {
+		var e *apiErr
+		if p.DestAmount, e = s.transferDestAmount(p.SrcAccountID, p.DestAccountID, p.DestAmount); e != nil {
+			if e = errs.check("dest_amount_cents", e); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
+		if e := errs.check("stop_when_paid_off", s.validatePayoffTarget(p.StopPaidOff, p.DestAccountID)); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
+	seen := make(map[string]bool)
+	for i := range body.Revisions {
+		rev := &body.Revisions[i]
+		field := fmt.Sprintf("revisions[%d].", i)
+		if _, e := requireDate(rev.EffectiveDate, "effective_date"); e != nil {
+			errs.add(field+"effective_date", e.Message)
+		} else if seen[rev.EffectiveDate] {
+			errs.add(field+"effective_date", "effective_date is repeated")
+		}
+		seen[rev.EffectiveDate] = true
+		if rev.AmountCents <= 0 {
+			errs.add(field+"amount_cents", "amount_cents must be > 0")
+		}
+		if accountsOK && p.SrcAccountID != nil && p.DestAccountID != nil {
+			var e *apiErr
+			if rev.DestAmount, e = s.transferDestAmount(p.SrcAccountID, p.DestAccountID, rev.DestAmount); e != nil {
+				if e = errs.check(field+"dest_amount_cents", e); e != nil {
+					writeErr(w, e)
+					return
+				}
+			}
+		} else {
+			rev.DestAmount = nil
+		}
+	}
+	if e := errs.err(); e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	start := max(from, p.StartDate)
+	limitT, _ := time.Parse("2006-01-02", p.StartDate)
+	limit := limitT.AddDate(rruleHorizonYears, 0, 0).Format("2006-01-02")
+	if p.EndDate != nil && *p.EndDate < limit {
+		limit = *p.EndDate
+	}
+	if limit < from {
+		writeOK(w, []map[string]any{})
+		return
+	}
+
+	conn, err := s.db.Conn(r.Context())
+	if err != nil {
+		writeErr(w, serverError("failed to open connection", err))
+		return
+	}
+	defer conn.Close()
+	tx, err := conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeErr(w, serverError("failed to open transaction", err))
+		return
+	}
+	defer tx.Rollback()
+	if e := stagePreviewSchedule(tx, ledgerID, &body); e != nil {
+		writeErr(w, e)
+		return
+	}
+
+	// Guess a horizon from the frequency, then widen it until count
+	// occurrences turn up or the schedule can have no more.
+	startT, _ := time.Parse("2006-01-02", start)
+	days := previewStepDays[p.Freq]*int(p.Interval)*body.Count + 31
+	var out []map[string]any
+	for {
+		to := min(startT.AddDate(0, 0, days).Format("2006-01-02"), limit)
+		args, err := occurrenceArgs(tx, ledgerID, 0, from, to)
+		if err != nil {
+			writeErr(w, serverError("failed to compute occurrences", err))
+			return
+		}
+		rows, err := tx.Query(occurrenceQuery(), args...)
+		if err != nil {
+			writeErr(w, serverError("failed to compute occurrences", err))
+			return
+		}
+		data, err := rowsToMaps(rows)
+		rows.Close()
+		if err != nil {
+			writeErr(w, serverError("failed to read occurrences", err))
+			return
+		}
+		out = []map[string]any{}
+		for _, o := range data {
+			if o["schedule_id"] != previewScheduleID || len(out) == body.Count {
+				continue
+			}
+			delete(o, "schedule_id")
+			delete(o, "exception_id")
+			delete(o, "exception_kind")
+			delete(o, "modified")
+			out = append(out, o)
+		}
+		if len(out) == body.Count || to == limit {
+			break
+		}
+		days *= 2
+	}
+	writeOK(w, out)
+}
+
+// previewScheduleID is the id a previewed schedule takes; saved schedules
+// start at 1.
+const previewScheduleID = int64(0)
+
+// stagePreviewSchedule makes the previewed schedule, its revisions and its
+// rule's dates visible to the occurrence query within tx. Temporary views
+// named after schedule, schedule_revision and schedule_rrule_date add the
+// preview's rows to the saved ones, so nothing is written to the ledger and
+// the rollback drops them again. tx must be the only user of its connection.
+func stagePreviewSchedule(tx *sql.Tx, ledgerID int64, body *schedulePreviewBody) *apiErr {
+	for _, table := range []string{"schedule", "schedule_revision", "schedule_rrule_date"} {
+		if _, err := tx.Exec(fmt.Sprintf(
+			`CREATE TEMP TABLE preview_%[1]s AS SELECT * FROM main.%[1]s WHERE 0;
+			 CREATE TEMP VIEW %[1]s AS SELECT * FROM main.%[1]s UNION ALL SELECT * FROM temp.preview_%[1]s`,
+			table,
+		)); err != nil {
+			return serverError("failed to stage preview", err)
+		}
+	}
+
+	p := &body.schedulePayload
+	if _, err := tx.Exec(
+		`INSERT INTO temp.preview_schedule (
+		 id, ledger_id, name, kind, amount_cents, src_account_id, dest_account_id,
+		 start_date, end_date, freq, interval, bymonthday, byweekday,
+		 description, category_id, dest_amount_cents, payee_id, rrule,
+		 business_day_roll, holiday_calendar,
+		 escalation_kind, escalation_bps, escalation_cents, escalation_every, escalation_anniversary, escalation_round_cents,
+		 max_occurrences, stop_when_paid_off, is_active
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		previewScheduleID, ledgerID, p.Name, p.Kind, p.AmountCents, p.SrcAccountID, p.DestAccountID,
+		p.StartDate, p.EndDate, p.Freq, p.Interval, p.ByMonthDay, p.ByWeekday,
+		p.Description, p.CategoryID, p.DestAmount, p.PayeeID, p.RRule,
+		p.Roll, p.Calendar,
+		p.Escalation, p.EscBps, p.EscCents, p.EscEvery, p.EscAnniv, p.EscRound,
+		p.MaxOcc, p.StopPaidOff,
+	); err != nil {
+		return badRequest("could not preview schedule", nil)
+	}
+	// Negative ids keep the revisions apart from saved ones.
+	for i, rev := range body.Revisions {
+		if _, err := tx.Exec(
+			"INSERT INTO temp.preview_schedule_revision (id, schedule_id, effective_date, amount_cents, dest_amount_cents) VALUES (?, ?, ?, ?, ?)",
+			-int64(i+1), previewScheduleID, rev.EffectiveDate, rev.AmountCents, rev.DestAmount,
+		); err != nil {
+			return serverError("failed to preview revisions", err)
+		}
+	}
+	if p.RRule != nil {
+		parsed, err := parseRRule(*p.RRule)
+		if err != nil {
+			return badRequest("invalid rrule", nil)
+		}
+		dates, err := rruleDates(parsed, p.StartDate, p.EndDate)
+		if err != nil {
+			return serverError("failed to expand rrule", err)
+		}
+		for _, d := range dates {
+			if _, err := tx.Exec("INSERT INTO temp.preview_schedule_rrule_date (schedule_id, occ_date) VALUES (?, ?)", previewScheduleID, d); err != nil {
+				return serverError("failed to expand rrule", err)
+			}
+		}
+	}
+	return nil
+}
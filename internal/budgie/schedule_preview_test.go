@@ -0,0 +1,78 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSchedulePreview(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01",
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// Rent rises in March; nothing before from_date is listed.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules/preview", map[string]any{
+		"name": "Rent", "kind": "E", "amount_cents": 150000, "src_account_id": checkingID,
+		"start_date": "2026-01-15", "freq": "M", "interval": 1,
+		"from_date": "2026-02-01", "count": 4,
+		"revisions": []map[string]any{{"effective_date": "2026-03-01", "amount_cents": 160000}},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("preview: status %d", resp.StatusCode)
+	}
+	var got []string
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		got = append(got, row["occ_date"].(string)+" "+fmtInt64(mustInt64(t, row["amount_cents"])))
+	}
+	if want := []string{
+		"2026-02-15 150000",
+		"2026-03-15 160000",
+		"2026-04-15 160000",
+		"2026-05-15 160000",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("preview = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/schedules", nil)
+	if n := len(mustList(t, decodeAPIResponse(t, resp).Data)); n != 0 {
+		t.Fatalf("preview saved %d schedules", n)
+	}
+
+	// A monthly rule that only fires in December still lists count dates.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules/preview", map[string]any{
+		"name": "Insurance", "kind": "E", "amount_cents": 90000, "src_account_id": checkingID,
+		"start_date": "2026-01-01", "rrule": "FREQ=MONTHLY;BYMONTH=12;BYMONTHDAY=1",
+		"from_date": "2026-02-01", "count": 3,
+	})
+	got = nil
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		got = append(got, mustMap(t, item)["occ_date"].(string))
+	}
+	if want := []string{"2026-12-01", "2027-12-01", "2028-12-01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rrule preview = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules/preview", map[string]any{
+		"name": "Broken", "kind": "E", "amount_cents": 0, "src_account_id": checkingID,
+		"start_date": "2026-01-15", "freq": "X",
+		"revisions": []map[string]any{{"effective_date": "soon", "amount_cents": 100}},
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid preview, got %d", resp.StatusCode)
+	}
+	var fields []string
+	for f := range mustMap(t, mustMap(t, decodeAPIResponse(t, resp).Details)["fields"]) {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	if want := []string{"amount_cents", "freq", "revisions[0].effective_date"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("invalid fields = %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,206 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleTermination(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	account := func(body map[string]any) int64 {
+		t.Helper()
+		body["opening_date"] = "2026-01-01"
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", body)
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	checkingID := account(map[string]any{"name": "Checking", "opening_balance_cents": 100000})
+	loanID := account(map[string]any{"name": "Loan", "opening_balance_cents": -25000, "is_liability": 1})
+
+	schedule := func(body map[string]any) *http.Response {
+		t.Helper()
+		return doJSON(t, http.MethodPost, api.URL+"/api/schedules", body)
+	}
+	resp := schedule(map[string]any{
+		"name": "Loan payment", "kind": "T", "amount_cents": 10000, "src_account_id": checkingID, "dest_account_id": loanID,
+		"start_date": "2026-02-01", "freq": "M", "interval": 1, "stop_when_paid_off": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create loan payment: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = schedule(map[string]any{
+		"name": "Gym", "kind": "E", "amount_cents": 5000, "src_account_id": checkingID,
+		"start_date": "2026-01-10", "freq": "M", "interval": 1, "max_occurrences": 3,
+	})
+	gymID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// An extra payment shortens the loan; a posted gym visit counts towards its three.
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/entries", map[string]any{
+		"entry_date": "2026-02-15", "name": "Extra payment", "amount_cents": 2000,
+		"src_account_id": checkingID, "dest_account_id": loanID,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create entry: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/occurrences/post", map[string]any{
+		"schedule_id": gymID, "occurrence_date": "2026-01-10",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post occurrence: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// An edit that leaves the limit out, like the schedule editor's, keeps it.
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(gymID), map[string]any{
+		"name": "Gym", "kind": "E", "amount_cents": 5000, "src_account_id": checkingID,
+		"start_date": "2026-01-10", "freq": "M", "interval": 1, "description": "Intro offer",
+	})
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["max_occurrences"] == nil || mustInt64(t, updated["max_occurrences"]) != 3 {
+		t.Fatalf("max_occurrences after edit = %v", updated["max_occurrences"])
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-01&to_date=2026-12-31", nil)
+	var got []string
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		got = append(got, row["name"].(string)+" "+row["occ_date"].(string)+" "+fmtInt64(mustInt64(t, row["amount_cents"])))
+	}
+	if want := []string{
+		"Loan payment 2026-02-01 10000",
+		"Gym 2026-02-10 5000",
+		"Loan payment 2026-03-01 10000",
+		"Gym 2026-03-10 5000",
+		"Loan payment 2026-04-01 3000",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-01&as_of=2026-12-31", nil)
+	balances := map[int64]int64{}
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		balances[mustInt64(t, row["id"])] = mustInt64(t, row["projected_balance_cents"])
+	}
+	if balances[loanID] != 0 || balances[checkingID] != 100000-5000-10000-2000-5000-10000-5000-3000 {
+		t.Fatalf("projected balances = %v", balances)
+	}
+
+	for _, body := range []map[string]any{
+		{"name": "Savings", "kind": "T", "amount_cents": 100, "src_account_id": loanID, "dest_account_id": checkingID,
+			"start_date": "2026-02-01", "freq": "M", "stop_when_paid_off": 1},
+		{"name": "Never", "kind": "E", "amount_cents": 100, "src_account_id": checkingID,
+			"start_date": "2026-02-01", "freq": "M", "max_occurrences": 0},
+	} {
+		resp = schedule(body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", body["name"], resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestPayoffCapFromProjectionStart(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	account := func(body map[string]any) int64 {
+		t.Helper()
+		body["opening_date"] = "2025-01-01"
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", body)
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	checkingID := account(map[string]any{"name": "Checking", "opening_balance_cents": 500000})
+	loanID := account(map[string]any{"name": "Loan", "opening_balance_cents": -100000, "is_liability": 1})
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Loan payment", "kind": "T", "amount_cents": 10000, "src_account_id": checkingID, "dest_account_id": loanID,
+		"start_date": "2025-01-15", "freq": "M", "interval": 1, "stop_when_paid_off": 1,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create loan payment: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	// Past payments were recorded by hand, not as posted occurrences.
+	for month := 2; month <= 13; month++ {
+		resp = doJSON(t, http.MethodPost, api.URL+"/api/entries", map[string]any{
+			"entry_date": time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), "name": "Payment",
+			"amount_cents": 5000, "src_account_id": checkingID, "dest_account_id": loanID,
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create entry: status %d", resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+
+	from := time.Now().Format("2006-01-02")
+	to := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date="+from+"&to_date="+to, nil)
+	var got []int64
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		got = append(got, mustInt64(t, mustMap(t, item)["amount_cents"]))
+	}
+	if want := []int64{10000, 10000, 10000, 10000}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("upcoming payments = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date="+from+"&as_of="+to, nil)
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		if mustInt64(t, row["id"]) == loanID && mustInt64(t, row["projected_balance_cents"]) != 0 {
+			t.Fatalf("projected loan balance = %d, want 0", mustInt64(t, row["projected_balance_cents"]))
+		}
+	}
+}
+
+func TestPayoffCapCountsLoanInterest(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-15", "opening_balance_cents": 2000000,
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	loan := map[string]any{
+		"name": "Car loan", "opening_date": "2026-01-15", "opening_balance_cents": -1200000, "is_liability": 1,
+		"is_interest_bearing": 1, "interest_apr_bps": 1200, "interest_compound": "M",
+		"loan_principal_cents": 1200000, "loan_term_months": 12,
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/accounts", loan)
+	loanID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", map[string]any{
+		"name": "Car payment", "kind": "T", "amount_cents": 106619, "src_account_id": checkingID, "dest_account_id": loanID,
+		"start_date": "2026-02-15", "freq": "M", "interval": 1, "stop_when_paid_off": 1,
+	})
+	loan["loan_payment_schedule_id"] = mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/accounts/"+fmtInt64(loanID), loan)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set payment schedule: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// The last payment covers the principal left and its interest.
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/accounts/"+fmtInt64(loanID)+"/amortization", nil)
+	rows := mustList(t, mustMap(t, decodeAPIResponse(t, resp).Data)["payments"])
+	last := mustInt64(t, mustMap(t, rows[len(rows)-1])["payment_cents"])
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2026-01-15&to_date=2027-06-30", nil)
+	var got []int64
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		got = append(got, mustInt64(t, mustMap(t, item)["amount_cents"]))
+	}
+	if len(got) != 12 || got[10] != 106619 || got[11] != last || last >= 106619 {
+		t.Fatalf("payments = %v, want 11 of 106619 and a last one of %d", got, last)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances?mode=projected&from_date=2026-01-15&as_of=2027-06-30", nil)
+	for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+		row := mustMap(t, item)
+		if mustInt64(t, row["id"]) == loanID && mustInt64(t, row["projected_balance_cents"]) != 0 {
+			t.Fatalf("projected loan balance = %d, want 0", mustInt64(t, row["projected_balance_cents"]))
+		}
+	}
+}
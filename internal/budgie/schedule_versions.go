@@ -0,0 +1,359 @@
+package budgie
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type scheduleVersionBody struct {
+	ScheduleID    int64   `json:"schedule_id"`
+	EffectiveDate string  `json:"effective_date"`
+	AmountCents   *int64  `json:"amount_cents"`
+	DestAmount    *int64  `json:"dest_amount_cents"`
+	SrcAccountID  *int64  `json:"src_account_id"`
+	DestAccountID *int64  `json:"dest_account_id"`
+	Freq          *string `json:"freq"`
+	Interval      *int64  `json:"interval"`
+	ByMonthDay    *int64  `json:"bymonthday"`
+	ByWeekday     *int64  `json:"byweekday"`
+	Description   *string `json:"description"`
+}
+
+// scheduleFieldsBefore returns the accounts and recurrence of a schedule as
+// they stand just before date: the latest version effective earlier, else the
+// schedule itself.
+func (s *server) scheduleFieldsBefore(scheduleID int64, date string) (scheduleVersionBody, error) {
+	var v scheduleVersionBody
+	var freq string
+	err := s.db.QueryRow(`
+		SELECT src_account_id, dest_account_id, freq, interval, bymonthday, byweekday
+		FROM (
+			SELECT '' AS effective_date, src_account_id, dest_account_id, freq, interval, bymonthday, byweekday
+			FROM schedule WHERE id = ?
+
+			UNION ALL
+
+			SELECT effective_date, src_account_id, dest_account_id, freq, interval, bymonthday, byweekday
+			FROM schedule_version WHERE schedule_id = ? AND effective_date < ?
+		)
+		ORDER BY effective_date DESC
+		LIMIT 1
+	`, scheduleID, scheduleID, date).Scan(&v.SrcAccountID, &v.DestAccountID, &freq, &v.Interval, &v.ByMonthDay, &v.ByWeekday)
+	v.Freq = &freq
+	return v, err
+}
+
+// scheduleAccountsOn returns the accounts of a schedule in force on date.
+func (s *server) scheduleAccountsOn(scheduleID int64, date string) (src, dest sql.NullInt64, err error) {
+	err = s.db.QueryRow(`
+		SELECT src_account_id, dest_account_id
+		FROM (
+			SELECT '' AS effective_date, src_account_id, dest_account_id FROM schedule WHERE id = ?
+			UNION ALL
+			SELECT effective_date, src_account_id, dest_account_id FROM schedule_version WHERE schedule_id = ? AND effective_date <= ?
+		)
+		ORDER BY effective_date DESC
+		LIMIT 1
+	`, scheduleID, scheduleID, date).Scan(&src, &dest)
+	return src, dest, err
+}
+
+// validateScheduleVersion fills the fields a version leaves out from the one
+// in force before it and checks the result like a schedule. Leaving out freq
+// keeps bymonthday and byweekday too; leaving out amount_cents keeps the
+// amount in force.
+func (s *server) validateScheduleVersion(ledgerID int64, b *scheduleVersionBody) *apiErr {
+	if b.ScheduleID == 0 {
+		return badRequest("schedule_id is required", nil)
+	}
+	if e := s.ledgerOwnsSchedule(ledgerID, &b.ScheduleID); e != nil {
+		return e
+	}
+	if _, e := requireDate(b.EffectiveDate, "effective_date"); e != nil {
+		return e
+	}
+	var kind, startDate string
+	var rrule sql.NullString
+	var stopPaidOff int64
+	if err := s.db.QueryRow(
+		"SELECT kind, start_date, rrule, stop_when_paid_off FROM schedule WHERE id = ?", b.ScheduleID,
+	).Scan(&kind, &startDate, &rrule, &stopPaidOff); err != nil {
+		return serverError("failed to read schedule", err)
+	}
+	if rrule.Valid {
+		return badRequest("rrule schedules cannot be versioned", nil)
+	}
+	if b.EffectiveDate <= startDate {
+		return badRequest("effective_date must be after the schedule's start_date", nil)
+	}
+
+	prev, err := s.scheduleFieldsBefore(b.ScheduleID, b.EffectiveDate)
+	if err != nil {
+		return serverError("failed to read schedule", err)
+	}
+	if b.SrcAccountID == nil && b.DestAccountID == nil {
+		b.SrcAccountID, b.DestAccountID = prev.SrcAccountID, prev.DestAccountID
+	}
+	if b.Freq == nil {
+		b.Freq, b.ByMonthDay, b.ByWeekday = prev.Freq, prev.ByMonthDay, prev.ByWeekday
+		if b.Interval == nil {
+			b.Interval = prev.Interval
+		}
+	}
+	if b.Interval == nil {
+		v := int64(1)
+		b.Interval = &v
+	}
+
+	switch *b.Freq {
+	case "D", "W", "M", "Y":
+	default:
+		return badRequest("freq must be one of D, W, M, Y", nil)
+	}
+	if *b.Interval < 1 {
+		return badRequest("interval must be >= 1", nil)
+	}
+	if b.ByMonthDay != nil && (*b.ByMonthDay < 1 || *b.ByMonthDay > 31) {
+		return badRequest("bymonthday must be 1..31", nil)
+	}
+	if b.ByWeekday != nil && (*b.ByWeekday < 0 || *b.ByWeekday > 6) {
+		return badRequest("byweekday must be 0..6", nil)
+	}
+	if b.AmountCents != nil && *b.AmountCents <= 0 {
+		return badRequest("amount_cents must be > 0", nil)
+	}
+
+	src, dest := b.SrcAccountID, b.DestAccountID
+	if e := scheduleKindAccounts(kind, src, dest); e != nil {
+		return e
+	}
+	if e := s.ledgerOwnsAccounts(ledgerID, src, dest); e != nil {
+		return e
+	}
+	var e *apiErr
+	if b.DestAmount, e = s.transferDestAmount(src, dest, b.DestAmount); e != nil {
+		return e
+	}
+	if e := s.validatePayoffTarget(stopPaidOff, dest); e != nil {
+		return e
+	}
+	if b.DestAmount != nil && b.AmountCents == nil {
+		return badRequest("amount_cents is required with dest_amount_cents", nil)
+	}
+	// The dest amount in force belongs to the old pair of currencies.
+	if b.AmountCents == nil && prev.SrcAccountID != nil && prev.DestAccountID != nil &&
+		(*prev.SrcAccountID != *src || *prev.DestAccountID != *dest) {
+		var cross bool
+		if err := s.db.QueryRow(
+			"SELECT (SELECT currency FROM account WHERE id = ?) != (SELECT currency FROM account WHERE id = ?)",
+			*prev.SrcAccountID, *prev.DestAccountID,
+		).Scan(&cross); err != nil {
+			return serverError("failed to read account currencies", err)
+		}
+		if cross {
+			return badRequest("amount_cents is required when changing the accounts of a transfer between currencies", nil)
+		}
+	}
+	return nil
+}
+
+// scheduleKindAccounts checks that a schedule of kind can move money between
+// src and dest.
+func scheduleKindAccounts(kind string, src, dest *int64) *apiErr {
+	switch kind {
+	case "I":
+		if dest == nil || src != nil {
+			return badRequest("Income schedules require dest_account_id and must not set src_account_id", nil)
+		}
+	case "E":
+		if src == nil || dest != nil {
+			return badRequest("Expense schedules require src_account_id and must not set dest_account_id", nil)
+		}
+	case "T":
+		if src == nil || dest == nil || *src == *dest {
+			return badRequest("Transfer schedules require distinct src_account_id and dest_account_id", nil)
+		}
+	}
+	return nil
+}
+
+// validateVersionsOfUpdate checks a schedule update against the schedule's
+// versions: the accounts of each must still suit the kind and payoff setting,
+// and a versioned schedule cannot take an rrule.
+func (s *server) validateVersionsOfUpdate(ledgerID, scheduleID int64, p *schedulePayload) *apiErr {
+	rows, err := s.db.Query(`
+		SELECT v.effective_date, v.src_account_id, v.dest_account_id
+		FROM schedule_version v
+		JOIN schedule s ON s.id = v.schedule_id
+		WHERE v.schedule_id = ? AND s.ledger_id = ?
+		ORDER BY v.effective_date
+	`, scheduleID, ledgerID)
+	if err != nil {
+		return serverError("failed to read schedule versions", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date string
+		var src, dest *int64
+		if err := rows.Scan(&date, &src, &dest); err != nil {
+			return serverError("failed to read schedule versions", err)
+		}
+		if p.RRule != nil {
+			return badRequest("rrule cannot be set on a schedule with versions", nil)
+		}
+		e := scheduleKindAccounts(p.Kind, src, dest)
+		if e == nil {
+			e = s.validatePayoffTarget(p.StopPaidOff, dest)
+		}
+		if e != nil {
+			return badRequest("version of "+date+": "+e.Message, nil)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return serverError("failed to read schedule versions", err)
+	}
+	return nil
+}
+
+// scheduleVersions serves GET/POST /api/schedule-versions. GET accepts an
+// optional schedule_id filter.
+//
+//	POST /api/schedule-versions
+//	{"schedule_id": 3, "effective_date": "2026-07-01", "freq": "W", "interval": 2, "byweekday": 5}
+func (s *server) scheduleVersions(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		q := `
+			SELECT v.*, s.name AS schedule_name
+			FROM schedule_version v
+			JOIN schedule s ON s.id = v.schedule_id
+			WHERE s.ledger_id = ?`
+		args := []any{ledgerID}
+		if v := r.URL.Query().Get("schedule_id"); v != "" {
+			scheduleID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				writeErr(w, badRequest("schedule_id must be an integer", nil))
+				return
+			}
+			q += " AND v.schedule_id = ?"
+			args = append(args, scheduleID)
+		}
+		rows, err := s.db.Query(q+" ORDER BY v.schedule_id, v.effective_date", args...)
+		if err != nil {
+			writeErr(w, serverError("failed to query schedule versions", err))
+			return
+		}
+		defer rows.Close()
+		data, err := rowsToMaps(rows)
+		if err != nil {
+			writeErr(w, serverError("failed to read schedule versions", err))
+			return
+		}
+		writeOK(w, data)
+	case http.MethodPost:
+		var body scheduleVersionBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateScheduleVersion(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		res, err := s.db.Exec(`
+			INSERT INTO schedule_version (
+			 schedule_id, effective_date, amount_cents, dest_amount_cents, src_account_id, dest_account_id,
+			 freq, interval, bymonthday, byweekday, description
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			body.ScheduleID, body.EffectiveDate, body.AmountCents, body.DestAmount, body.SrcAccountID, body.DestAccountID,
+			body.Freq, body.Interval, body.ByMonthDay, body.ByWeekday, body.Description,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not create schedule version (one already exists on this date?)", nil))
+			return
+		}
+		id, _ := res.LastInsertId()
+		created, apiE := scanRowToMap(s.db, "schedule_version", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, created)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) scheduleVersionByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath("/api/schedule-versions/", r.URL.Path)
+	if !ok {
+		writeErr(w, notFound("not found"))
+		return
+	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodPut:
+		var body scheduleVersionBody
+		if e := readJSON(r, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		var current int64
+		err := s.db.QueryRow(
+			"SELECT schedule_id FROM schedule_version WHERE id = ? AND schedule_id IN (SELECT id FROM schedule WHERE ledger_id = ?)", id, ledgerID,
+		).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErr(w, notFound("schedule version not found"))
+			return
+		}
+		if err != nil {
+			writeErr(w, serverError("failed to read schedule version", err))
+			return
+		}
+		if body.ScheduleID == 0 {
+			body.ScheduleID = current
+		}
+		if e := s.validateScheduleVersion(ledgerID, &body); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if _, err := s.db.Exec(`
+			UPDATE schedule_version
+			SET schedule_id=?, effective_date=?, amount_cents=?, dest_amount_cents=?, src_account_id=?, dest_account_id=?,
+			    freq=?, interval=?, bymonthday=?, byweekday=?, description=?
+			WHERE id = ?
+		`, body.ScheduleID, body.EffectiveDate, body.AmountCents, body.DestAmount, body.SrcAccountID, body.DestAccountID,
+			body.Freq, body.Interval, body.ByMonthDay, body.ByWeekday, body.Description, id); err != nil {
+			writeErr(w, badRequest("could not update schedule version (one already exists on this date?)", nil))
+			return
+		}
+		updated, apiE := scanRowToMap(s.db, "schedule_version", id)
+		if apiE != nil {
+			writeErr(w, apiE)
+			return
+		}
+		writeOK(w, updated)
+	case http.MethodDelete:
+		res, err := s.db.Exec(
+			"DELETE FROM schedule_version WHERE id = ? AND schedule_id IN (SELECT id FROM schedule WHERE ledger_id = ?)",
+			id, ledgerID,
+		)
+		if err != nil {
+			writeErr(w, badRequest("could not delete schedule version", nil))
+			return
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			writeErr(w, notFound("schedule version not found"))
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,145 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestScheduleVersions(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	account := func(name string) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{"name": name, "opening_date": "2026-01-01"})
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	checkingID, savingsID := account("Checking"), account("Savings")
+	schedule := func(body map[string]any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedules", body)
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	billID := schedule(map[string]any{
+		"name": "Bill", "kind": "E", "amount_cents": 10000, "src_account_id": checkingID,
+		"start_date": "2027-01-10", "freq": "M", "interval": 1,
+	})
+	payID := schedule(map[string]any{
+		"name": "Pay", "kind": "I", "amount_cents": 200000, "dest_account_id": checkingID,
+		"start_date": "2027-01-01", "freq": "W", "interval": 2, "byweekday": 5,
+	})
+	version := func(body map[string]any) int64 {
+		t.Helper()
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedule-versions", body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("create version %v: status %d", body, resp.StatusCode)
+		}
+		return mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+	}
+	// The bill moves to savings, keeping its dates.
+	version(map[string]any{"schedule_id": billID, "effective_date": "2027-03-01", "src_account_id": savingsID})
+	aprilID := version(map[string]any{"schedule_id": billID, "effective_date": "2027-04-01", "amount_cents": 12000})
+	// A revision after a version wins, and a later version wins over it.
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/revisions", map[string]any{
+		"schedule_id": billID, "effective_date": "2027-05-01", "amount_cents": 15000,
+	})
+	resp.Body.Close()
+	version(map[string]any{"schedule_id": billID, "effective_date": "2027-06-01", "amount_cents": 11000})
+	// Pay goes from biweekly to monthly on the 20th.
+	version(map[string]any{
+		"schedule_id": payID, "effective_date": "2027-02-15", "freq": "M", "bymonthday": 20, "amount_cents": 400000,
+	})
+
+	occurrences := func() []string {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/occurrences?from_date=2027-01-01&to_date=2027-06-30", nil)
+		var got []string
+		for _, item := range mustList(t, decodeAPIResponse(t, resp).Data) {
+			row := mustMap(t, item)
+			accountID := row["src_account_id"]
+			if accountID == nil {
+				accountID = row["dest_account_id"]
+			}
+			name := "checking"
+			if mustInt64(t, accountID) == savingsID {
+				name = "savings"
+			}
+			got = append(got, row["name"].(string)+" "+row["occ_date"].(string)+" "+fmtInt64(mustInt64(t, row["amount_cents"]))+" "+name)
+		}
+		return got
+	}
+	want := []string{
+		"Pay 2027-01-01 200000 checking",
+		"Bill 2027-01-10 10000 checking",
+		"Pay 2027-01-15 200000 checking",
+		"Pay 2027-01-29 200000 checking",
+		"Bill 2027-02-10 10000 checking",
+		"Pay 2027-02-12 200000 checking",
+		"Pay 2027-02-20 400000 checking",
+		"Bill 2027-03-10 10000 savings",
+		"Pay 2027-03-20 400000 checking",
+		"Bill 2027-04-10 12000 savings",
+		"Pay 2027-04-20 400000 checking",
+		"Bill 2027-05-10 15000 savings",
+		"Pay 2027-05-20 400000 checking",
+		"Bill 2027-06-10 11000 savings",
+		"Pay 2027-06-20 400000 checking",
+	}
+	if got := occurrences(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodDelete, api.URL+"/api/schedule-versions/"+fmtInt64(aprilID), nil)
+	resp.Body.Close()
+	want[9] = "Bill 2027-04-10 10000 savings"
+	if got := occurrences(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after delete occurrences = %v, want %v", got, want)
+	}
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/schedule-versions?schedule_id="+fmtInt64(billID), nil)
+	if got := mustList(t, decodeAPIResponse(t, resp).Data); len(got) != 2 {
+		t.Fatalf("expected 2 bill versions, got %d", len(got))
+	}
+
+	for _, body := range []map[string]any{
+		{"schedule_id": billID, "effective_date": "2027-01-10"},
+		{"schedule_id": billID, "effective_date": "2027-03-01"},
+		{"schedule_id": payID, "effective_date": "2027-08-01", "src_account_id": savingsID, "dest_account_id": checkingID},
+		{"schedule_id": billID, "effective_date": "2027-08-01", "freq": "Q"},
+	} {
+		resp := doJSON(t, http.MethodPost, api.URL+"/api/schedule-versions", body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", body, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+
+	// Edits must leave the versions' accounts valid and cannot switch to an rrule.
+	bill := map[string]any{
+		"name": "Bill", "kind": "E", "amount_cents": 10000, "src_account_id": checkingID,
+		"start_date": "2027-01-10", "freq": "M", "interval": 1, "description": "Power",
+	}
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(billID), bill)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("edit bill: status %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	for _, edit := range []map[string]any{
+		{"kind": "I", "src_account_id": nil, "dest_account_id": checkingID},
+		{"rrule": "FREQ=MONTHLY;BYMONTHDAY=10"},
+	} {
+		body := map[string]any{}
+		for k, v := range bill {
+			body[k] = v
+		}
+		for k, v := range edit {
+			body[k] = v
+		}
+		resp := doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(billID), body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for edit %v, got %d", edit, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,16 +19,24 @@ func (s *server) meta(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, map[string]any{
 		"ok": true,
 		"enums": map[string]any{
-			"schedule_kind": []string{"I", "E", "T"},
-			"schedule_freq": []string{"D", "W", "M", "Y"},
+			"schedule_kind":     []string{"I", "E", "T"},
+			"schedule_freq":     []string{"D", "W", "M", "Y"},
+			"business_day_roll": []string{"none", "following", "preceding", "modified_following"},
+			"entry_status":      []string{"pending", "cleared", "reconciled"},
+			"card_autopay":      []string{"none", "full", "minimum"},
+			"interest_posting":  []string{"none", "monthly", "statement"},
+			"variance_model":    []string{"fixed", "percent", "history"},
+			"escalation_kind":   []string{"none", "percent", "fixed"},
 		},
 	})
 }
 
 func (s *server) accounts(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
 	switch r.Method {
 	case http.MethodGet:
-		rows, err := s.db.Query("SELECT * FROM account ORDER BY archived_at IS NOT NULL, name")
+		rows, err := s.db.Query("SELECT * FROM account WHERE ledger_id = ? ORDER BY archived_at IS NOT NULL, name", ledgerID)
 		if err != nil {
 			writeErr(w, serverError("failed to query accounts", err))
 			return
@@ -52,6 +61,11 @@ func (s *server) accounts(w http.ResponseWriter, r *http.Request) {
 			InterestAprBps       *int64 `json:"interest_apr_bps"`
 			InterestCompound     string `json:"interest_compound"`
 			ExcludeFromDashboard int64  `json:"exclude_from_dashboard"`
+			Currency             string `json:"currency"`
+			MinBalanceCents      *int64 `json:"min_balance_cents"`
+			loanFields
+			cardFields
+			interestPostingFields
 		}
 		if e := readJSON(r, &body); e != nil {
 			writeErr(w, e)
@@ -71,6 +85,14 @@ func (s *server) accounts(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, e)
 			return
 		}
+		if strings.TrimSpace(body.Currency) == "" {
+			body.Currency = "USD"
+		}
+		currency, e := requireCurrency(body.Currency, "currency")
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
 
 		if body.IsLiability != 0 {
 			body.IsLiability = 1
@@ -99,10 +121,31 @@ func (s *server) accounts(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if e := s.validateLoanFields(ledgerID, 0, body.IsLiability, &body.loanFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateCardFields(ledgerID, 0, body.IsLiability, currency, &body.loanFields, &body.cardFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateInterestPosting(body.IsInterestBearing, &body.cardFields, &body.interestPostingFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+
 		res, err := s.db.Exec(
-			"INSERT INTO account (name, opening_date, opening_balance_cents, description, archived_at, is_liability, is_interest_bearing, interest_apr_bps, interest_compound, exclude_from_dashboard) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
-			strings.TrimSpace(body.Name), od, body.OpeningBalanceCents, body.Description, archivedAt,
-			body.IsLiability, body.IsInterestBearing, body.InterestAprBps, body.InterestCompound, body.ExcludeFromDashboard,
+			`INSERT INTO account (
+				ledger_id, name, opening_date, opening_balance_cents, description, archived_at, is_liability, is_interest_bearing, interest_apr_bps, interest_compound, exclude_from_dashboard, currency,
+				loan_principal_cents, loan_term_months,
+				card_closing_day, card_grace_days, card_due_offset_days, card_min_payment_cents, card_min_payment_bps, card_min_payment_plus_interest, card_autopay, card_autopay_account_id,
+				interest_posting, interest_posting_day, min_balance_cents
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ledgerID, strings.TrimSpace(body.Name), od, body.OpeningBalanceCents, body.Description, archivedAt,
+			body.IsLiability, body.IsInterestBearing, body.InterestAprBps, body.InterestCompound, body.ExcludeFromDashboard, currency,
+			body.PrincipalCents, body.TermMonths,
+			body.ClosingDay, body.GraceDays, body.DueOffsetDays, body.MinPaymentCents, body.MinPaymentBps, body.MinPaymentPlusInt, body.Autopay, body.AutopayAccountID,
+			body.Posting, body.PostingDay, body.MinBalanceCents,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not create account", nil))
@@ -121,11 +164,24 @@ func (s *server) accounts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) accountByID(w http.ResponseWriter, r *http.Request) {
-	id, ok := parseIDFromPath("/api/accounts/", r.URL.Path)
-	if !ok {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/accounts/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 {
 		writeErr(w, notFound("not found"))
 		return
 	}
+	ledgerID := ledgerFromContext(r.Context())
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "amortization":
+			s.accountAmortization(w, r, ledgerID, id)
+		case "statements":
+			s.accountStatements(w, r, ledgerID, id)
+		default:
+			writeErr(w, notFound("not found"))
+		}
+		return
+	}
 
 	switch r.Method {
 	case http.MethodPut:
@@ -141,11 +197,42 @@ func (s *server) accountByID(w http.ResponseWriter, r *http.Request) {
 			InterestAprBps       *int64 `json:"interest_apr_bps"`
 			InterestCompound     string `json:"interest_compound"`
 			ExcludeFromDashboard int64  `json:"exclude_from_dashboard"`
+			Currency             string `json:"currency"`
+			MinBalanceCents      *int64 `json:"min_balance_cents"`
+			loanFields
+			cardFields
+			interestPostingFields
 		}
-		if e := readJSON(r, &body); e != nil {
+		present, e := readJSONFields(r, &body)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		// The account editor does not know the loan, card, interest posting or
+		// minimum balance settings; keep them.
+		if e := s.keepOmitted("account", ledgerID, id, present, map[string]any{
+			"loan_principal_cents":           &body.PrincipalCents,
+			"loan_term_months":               &body.TermMonths,
+			"loan_payment_schedule_id":       &body.PaymentScheduleID,
+			"card_closing_day":               &body.ClosingDay,
+			"card_grace_days":                &body.GraceDays,
+			"card_due_offset_days":           &body.DueOffsetDays,
+			"card_min_payment_cents":         &body.MinPaymentCents,
+			"card_min_payment_bps":           &body.MinPaymentBps,
+			"card_min_payment_plus_interest": &body.MinPaymentPlusInt,
+			"card_autopay":                   &body.Autopay,
+			"card_autopay_account_id":        &body.AutopayAccountID,
+			"interest_posting":               &body.Posting,
+			"interest_posting_day":           &body.PostingDay,
+			"min_balance_cents":              &body.MinBalanceCents,
+		}); e != nil {
 			writeErr(w, e)
 			return
 		}
+		// Turning interest off without naming a posting mode stops posting.
+		if !present["interest_posting"] && body.IsInterestBearing == 0 {
+			body.Posting, body.PostingDay = "none", nil
+		}
 		if strings.TrimSpace(body.Name) == "" {
 			writeErr(w, badRequest("name is required", nil))
 			return
@@ -160,6 +247,14 @@ func (s *server) accountByID(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, e)
 			return
 		}
+		// An omitted currency keeps the account's current one.
+		currency := ""
+		if strings.TrimSpace(body.Currency) != "" {
+			if currency, e = requireCurrency(body.Currency, "currency"); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
 
 		if body.IsLiability != 0 {
 			body.IsLiability = 1
@@ -188,16 +283,41 @@ func (s *server) accountByID(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		_, err := s.db.Exec(
-			"UPDATE account SET name=?, opening_date=?, opening_balance_cents=?, description=?, archived_at=?, is_liability=?, is_interest_bearing=?, interest_apr_bps=?, interest_compound=?, exclude_from_dashboard=? WHERE id=?",
+		if e := s.validateLoanFields(ledgerID, id, body.IsLiability, &body.loanFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateCardFields(ledgerID, id, body.IsLiability, currency, &body.loanFields, &body.cardFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := validateInterestPosting(body.IsInterestBearing, &body.cardFields, &body.interestPostingFields); e != nil {
+			writeErr(w, e)
+			return
+		}
+
+		res, err := s.db.Exec(
+			`UPDATE account SET
+				name=?, opening_date=?, opening_balance_cents=?, description=?, archived_at=?, is_liability=?, is_interest_bearing=?, interest_apr_bps=?, interest_compound=?, exclude_from_dashboard=?, currency=COALESCE(NULLIF(?, ''), currency),
+				loan_principal_cents=?, loan_term_months=?, loan_payment_schedule_id=?,
+				card_closing_day=?, card_grace_days=?, card_due_offset_days=?, card_min_payment_cents=?, card_min_payment_bps=?, card_min_payment_plus_interest=?, card_autopay=?, card_autopay_account_id=?,
+				interest_posting=?, interest_posting_day=?, min_balance_cents=?
+			WHERE id=? AND ledger_id=?`,
 			strings.TrimSpace(body.Name), od, body.OpeningBalanceCents, body.Description, archivedAt,
-			body.IsLiability, body.IsInterestBearing, body.InterestAprBps, body.InterestCompound, body.ExcludeFromDashboard,
-			id,
+			body.IsLiability, body.IsInterestBearing, body.InterestAprBps, body.InterestCompound, body.ExcludeFromDashboard, currency,
+			body.PrincipalCents, body.TermMonths, body.PaymentScheduleID,
+			body.ClosingDay, body.GraceDays, body.DueOffsetDays, body.MinPaymentCents, body.MinPaymentBps, body.MinPaymentPlusInt, body.Autopay, body.AutopayAccountID,
+			body.Posting, body.PostingDay, body.MinBalanceCents,
+			id, ledgerID,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not update account", nil))
 			return
 		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("account not found"))
+			return
+		}
 		updated, apiE := scanRowToMap(s.db, "account", id)
 		if apiE != nil {
 			writeErr(w, apiE)
@@ -205,7 +325,7 @@ func (s *server) accountByID(w http.ResponseWriter, r *http.Request) {
 		}
 		writeOK(w, updated)
 	case http.MethodDelete:
-		res, err := s.db.Exec("DELETE FROM account WHERE id = ?", id)
+		res, err := s.db.Exec("DELETE FROM account WHERE id = ? AND ledger_id = ?", id, ledgerID)
 		if err != nil {
 			writeErr(w, badRequest("could not delete account (likely referenced)", nil))
 			return
@@ -241,6 +361,7 @@ func (s *server) accountCorrectBalance(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, err)
 		return
 	}
+	ledgerID := ledgerFromContext(r.Context())
 
 	var currentBalance int64
 	err := s.db.QueryRow(`
@@ -253,8 +374,8 @@ func (s *server) accountCorrectBalance(w http.ResponseWriter, r *http.Request) {
 		      AND d.entry_date >= a.opening_date
 		  ), 0)
 		FROM account a
-		WHERE a.id = ?
-	`, payload.Date, payload.AccountID).Scan(&currentBalance)
+		WHERE a.id = ? AND a.ledger_id = ?
+	`, payload.Date, payload.AccountID, ledgerID).Scan(&currentBalance)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -283,9 +404,9 @@ func (s *server) accountCorrectBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO entry (entry_date, name, amount_cents, src_account_id, dest_account_id, description)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, payload.Date, "Balance Correction", amount, src, dest, "Manual balance correction")
+		INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, ledgerID, payload.Date, "Balance Correction", amount, src, dest, "Manual balance correction")
 
 	if err != nil {
 		writeErr(w, serverError("failed to create correction entry", err))
@@ -296,9 +417,11 @@ func (s *server) accountCorrectBalance(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) schedules(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
 	switch r.Method {
 	case http.MethodGet:
-		rows, err := s.db.Query("SELECT * FROM schedule ORDER BY is_active DESC, start_date DESC, name")
+		rows, err := s.db.Query("SELECT * FROM schedule WHERE ledger_id = ? ORDER BY is_active DESC, start_date DESC, name", ledgerID)
 		if err != nil {
 			writeErr(w, serverError("failed to query schedules", err))
 			return
@@ -316,25 +439,65 @@ func (s *server) schedules(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, e)
 			return
 		}
+		if e := s.ledgerOwnsAccounts(ledgerID, payload.SrcAccountID, payload.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, payload.CategoryID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if payload.DestAmount, e = s.transferDestAmount(payload.SrcAccountID, payload.DestAccountID, payload.DestAmount); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validatePayoffTarget(payload.StopPaidOff, payload.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsRow(ledgerID, "payee", payload.PayeeID); e != nil {
+			writeErr(w, e)
+			return
+		}
 		isActive := int64(1)
 		if payload.IsActive != nil {
 			isActive = *payload.IsActive
 		}
-		res, err := s.db.Exec(
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(
 			`INSERT INTO schedule (
-			 name, kind, amount_cents, src_account_id, dest_account_id,
+			 ledger_id, name, kind, amount_cents, src_account_id, dest_account_id,
 			 start_date, end_date, freq, interval, bymonthday, byweekday,
-			 description, is_active
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			payload.Name, payload.Kind, payload.AmountCents, payload.SrcAccountID, payload.DestAccountID,
+			 description, is_active, category_id, dest_amount_cents, payee_id, rrule,
+			 business_day_roll, holiday_calendar, variance_model, variance_bps,
+			 escalation_kind, escalation_bps, escalation_cents, escalation_every, escalation_anniversary, escalation_round_cents,
+			 max_occurrences, stop_when_paid_off
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ledgerID, payload.Name, payload.Kind, payload.AmountCents, payload.SrcAccountID, payload.DestAccountID,
 			payload.StartDate, payload.EndDate, payload.Freq, payload.Interval, payload.ByMonthDay, payload.ByWeekday,
-			payload.Description, isActive,
+			payload.Description, isActive, payload.CategoryID, payload.DestAmount, payload.PayeeID, payload.RRule,
+			payload.Roll, payload.Calendar, payload.Variance, payload.VarianceBps,
+			payload.Escalation, payload.EscBps, payload.EscCents, payload.EscEvery, payload.EscAnniv, payload.EscRound,
+			payload.MaxOcc, payload.StopPaidOff,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not create schedule", nil))
 			return
 		}
 		id, _ := res.LastInsertId()
+		if err := refreshRRuleDates(tx, id, payload.RRule, payload.StartDate, payload.EndDate); err != nil {
+			writeErr(w, serverError("failed to expand rrule", err))
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to create schedule", err))
+			return
+		}
 		created, apiE := scanRowToMap(s.db, "schedule", id)
 		if apiE != nil {
 			writeErr(w, apiE)
@@ -352,32 +515,81 @@ func (s *server) scheduleByID(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, notFound("not found"))
 		return
 	}
+	ledgerID := ledgerFromContext(r.Context())
 
 	switch r.Method {
 	case http.MethodPut:
-		payload, e := parseSchedulePayload(r)
+		payload, e := s.parseScheduleUpdate(r, ledgerID, id)
 		if e != nil {
 			writeErr(w, e)
 			return
 		}
+		if e := s.ledgerOwnsAccounts(ledgerID, payload.SrcAccountID, payload.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, payload.CategoryID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if payload.DestAmount, e = s.transferDestAmount(payload.SrcAccountID, payload.DestAccountID, payload.DestAmount); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validatePayoffTarget(payload.StopPaidOff, payload.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateVersionsOfUpdate(ledgerID, id, payload); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsRow(ledgerID, "payee", payload.PayeeID); e != nil {
+			writeErr(w, e)
+			return
+		}
 		isActive := int64(1)
 		if payload.IsActive != nil {
 			isActive = *payload.IsActive
 		}
-		_, err := s.db.Exec(
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(
 			`UPDATE schedule
 			SET name=?, kind=?, amount_cents=?, src_account_id=?, dest_account_id=?,
 			    start_date=?, end_date=?, freq=?, interval=?, bymonthday=?, byweekday=?,
-			    description=?, is_active=?
-			WHERE id=?`,
+			    description=?, is_active=?, category_id=?, dest_amount_cents=?, payee_id=?, rrule=?,
+			    business_day_roll=?, holiday_calendar=?, variance_model=?, variance_bps=?,
+			    escalation_kind=?, escalation_bps=?, escalation_cents=?, escalation_every=?, escalation_anniversary=?, escalation_round_cents=?,
+			    max_occurrences=?, stop_when_paid_off=?
+			WHERE id=? AND ledger_id=?`,
 			payload.Name, payload.Kind, payload.AmountCents, payload.SrcAccountID, payload.DestAccountID,
 			payload.StartDate, payload.EndDate, payload.Freq, payload.Interval, payload.ByMonthDay, payload.ByWeekday,
-			payload.Description, isActive, id,
+			payload.Description, isActive, payload.CategoryID, payload.DestAmount, payload.PayeeID, payload.RRule,
+			payload.Roll, payload.Calendar, payload.Variance, payload.VarianceBps,
+			payload.Escalation, payload.EscBps, payload.EscCents, payload.EscEvery, payload.EscAnniv, payload.EscRound,
+			payload.MaxOcc, payload.StopPaidOff, id, ledgerID,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not update schedule", nil))
 			return
 		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("schedule not found"))
+			return
+		}
+		if err := refreshRRuleDates(tx, id, payload.RRule, payload.StartDate, payload.EndDate); err != nil {
+			writeErr(w, serverError("failed to expand rrule", err))
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to update schedule", err))
+			return
+		}
 		updated, apiE := scanRowToMap(s.db, "schedule", id)
 		if apiE != nil {
 			writeErr(w, apiE)
@@ -385,7 +597,7 @@ func (s *server) scheduleByID(w http.ResponseWriter, r *http.Request) {
 		}
 		writeOK(w, updated)
 	case http.MethodDelete:
-		res, err := s.db.Exec("DELETE FROM schedule WHERE id = ?", id)
+		res, err := s.db.Exec("DELETE FROM schedule WHERE id = ? AND ledger_id = ?", id, ledgerID)
 		if err != nil {
 			writeErr(w, badRequest("could not delete schedule", nil))
 			return
@@ -402,14 +614,17 @@ func (s *server) scheduleByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) revisions(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
 	switch r.Method {
 	case http.MethodGet:
 		rows, err := s.db.Query(`
 			SELECT sr.*, s.name AS schedule_name
 			FROM schedule_revision sr
 			JOIN schedule s ON s.id = sr.schedule_id
+			WHERE s.ledger_id = ?
 			ORDER BY sr.schedule_id, sr.effective_date
-		`)
+		`, ledgerID)
 		if err != nil {
 			writeErr(w, serverError("failed to query revisions", err))
 			return
@@ -426,6 +641,7 @@ func (s *server) revisions(w http.ResponseWriter, r *http.Request) {
 			ScheduleID    int64   `json:"schedule_id"`
 			EffectiveDate string  `json:"effective_date"`
 			AmountCents   int64   `json:"amount_cents"`
+			DestAmount    *int64  `json:"dest_amount_cents"`
 			Description   *string `json:"description"`
 		}
 		if e := readJSON(r, &body); e != nil {
@@ -436,6 +652,10 @@ func (s *server) revisions(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("schedule_id is required", nil))
 			return
 		}
+		if e := s.ledgerOwnsSchedule(ledgerID, &body.ScheduleID); e != nil {
+			writeErr(w, e)
+			return
+		}
 		ed, e := requireDate(body.EffectiveDate, "effective_date")
 		if e != nil {
 			writeErr(w, e)
@@ -445,9 +665,22 @@ func (s *server) revisions(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("amount_cents must be > 0", nil))
 			return
 		}
+		src, dest, err := s.scheduleAccountsOn(body.ScheduleID, ed)
+		if err != nil {
+			writeErr(w, serverError("failed to read schedule", err))
+			return
+		}
+		if src.Valid && dest.Valid {
+			if body.DestAmount, e = s.transferDestAmount(&src.Int64, &dest.Int64, body.DestAmount); e != nil {
+				writeErr(w, e)
+				return
+			}
+		} else {
+			body.DestAmount = nil
+		}
 		res, err := s.db.Exec(
-			"INSERT INTO schedule_revision (schedule_id, effective_date, amount_cents, description) VALUES (?, ?, ?, ?)",
-			body.ScheduleID, ed, body.AmountCents, body.Description,
+			"INSERT INTO schedule_revision (schedule_id, effective_date, amount_cents, dest_amount_cents, description) VALUES (?, ?, ?, ?, ?)",
+			body.ScheduleID, ed, body.AmountCents, body.DestAmount, body.Description,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not create revision", nil))
@@ -475,7 +708,10 @@ func (s *server) revisionByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	res, err := s.db.Exec("DELETE FROM schedule_revision WHERE id = ?", id)
+	res, err := s.db.Exec(
+		"DELETE FROM schedule_revision WHERE id = ? AND schedule_id IN (SELECT id FROM schedule WHERE ledger_id = ?)",
+		id, ledgerFromContext(r.Context()),
+	)
 	if err != nil {
 		writeErr(w, badRequest("could not delete revision", nil))
 		return
@@ -489,19 +725,27 @@ func (s *server) revisionByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) entries(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+
 	switch r.Method {
 	case http.MethodGet:
 		rows, err := s.db.Query(`
+			WITH RECURSIVE `+categoryPathCTEDefs()+`
 			SELECT e.*,
 			       sa.name AS src_account_name,
 			       da.name AS dest_account_name,
-			       s.name  AS schedule_name
+			       s.name  AS schedule_name,
+			       cp.path AS category_path,
+			       p.name  AS payee_name
 			FROM entry e
 			LEFT JOIN account sa ON sa.id = e.src_account_id
 			LEFT JOIN account da ON da.id = e.dest_account_id
 			LEFT JOIN schedule s ON s.id = e.schedule_id
+			LEFT JOIN category_path cp ON cp.id = e.category_id
+			LEFT JOIN payee p ON p.id = e.payee_id
+			WHERE e.ledger_id = ?
 			ORDER BY e.entry_date DESC, e.id DESC
-		`)
+		`, ledgerID)
 		if err != nil {
 			writeErr(w, serverError("failed to query entries", err))
 			return
@@ -512,16 +756,25 @@ func (s *server) entries(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, serverError("failed to read entries", err))
 			return
 		}
+		if err := s.attachEntrySplits(ledgerID, data...); err != nil {
+			writeErr(w, serverError("failed to read entry splits", err))
+			return
+		}
 		writeOK(w, data)
 	case http.MethodPost:
 		var body struct {
-			EntryDate     string  `json:"entry_date"`
-			Name          string  `json:"name"`
-			AmountCents   int64   `json:"amount_cents"`
-			SrcAccountID  *int64  `json:"src_account_id"`
-			DestAccountID *int64  `json:"dest_account_id"`
-			ScheduleID    *int64  `json:"schedule_id"`
-			Description   *string `json:"description"`
+			EntryDate     string        `json:"entry_date"`
+			Name          string        `json:"name"`
+			AmountCents   int64         `json:"amount_cents"`
+			SrcAccountID  *int64        `json:"src_account_id"`
+			DestAccountID *int64        `json:"dest_account_id"`
+			ScheduleID    *int64        `json:"schedule_id"`
+			CategoryID    *int64        `json:"category_id"`
+			Description   *string       `json:"description"`
+			Splits        *[]entrySplit `json:"splits"`
+			DestAmount    *int64        `json:"dest_amount_cents"`
+			PayeeID       *int64        `json:"payee_id"`
+			Status        *string       `json:"status"`
 		}
 		if e := readJSON(r, &body); e != nil {
 			writeErr(w, e)
@@ -540,6 +793,12 @@ func (s *server) entries(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("amount_cents must be > 0", nil))
 			return
 		}
+		if body.Status != nil {
+			if _, e := requireEntryStatus(*body.Status); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
 		if body.SrcAccountID == nil && body.DestAccountID == nil {
 			writeErr(w, badRequest("must set src_account_id and/or dest_account_id", nil))
 			return
@@ -548,21 +807,69 @@ func (s *server) entries(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("src_account_id and dest_account_id must differ", nil))
 			return
 		}
+		if e := s.ledgerOwnsAccounts(ledgerID, body.SrcAccountID, body.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsSchedule(ledgerID, body.ScheduleID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, body.CategoryID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		destAmount, e := s.transferDestAmount(body.SrcAccountID, body.DestAccountID, body.DestAmount)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		var splits []entrySplit
+		if body.Splits != nil {
+			splits = *body.Splits
+		}
+		payeeID, categoryID, e := s.resolvePayee(ledgerID, body.PayeeID, body.Name, body.CategoryID, len(splits) > 0)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.validateSplits(ledgerID, body.AmountCents, splits); e != nil {
+			writeErr(w, e)
+			return
+		}
 
-		res, err := s.db.Exec(
-			"INSERT INTO entry (entry_date, name, amount_cents, src_account_id, dest_account_id, description, schedule_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
-			ed, strings.TrimSpace(body.Name), body.AmountCents, body.SrcAccountID, body.DestAccountID, body.Description, body.ScheduleID,
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(
+			"INSERT INTO entry (ledger_id, entry_date, name, amount_cents, src_account_id, dest_account_id, description, schedule_id, category_id, dest_amount_cents, payee_id, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, 'pending'))",
+			ledgerID, ed, strings.TrimSpace(body.Name), body.AmountCents, body.SrcAccountID, body.DestAccountID, body.Description, body.ScheduleID, categoryID, destAmount, payeeID, body.Status,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not create entry", nil))
 			return
 		}
 		id, _ := res.LastInsertId()
+		if err := replaceEntrySplits(tx, id, splits); err != nil {
+			writeErr(w, serverError("failed to save entry splits", err))
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to create entry", err))
+			return
+		}
 		created, apiE := scanRowToMap(s.db, "entry", id)
 		if apiE != nil {
 			writeErr(w, apiE)
 			return
 		}
+		if err := s.attachEntrySplits(ledgerID, created); err != nil {
+			writeErr(w, serverError("failed to read entry splits", err))
+			return
+		}
 		writeOK(w, created)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -575,19 +882,47 @@ func (s *server) entryByID(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, notFound("not found"))
 		return
 	}
+	ledgerID := ledgerFromContext(r.Context())
+
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		if e := s.investmentEntryGuard(id); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.reconciledEntryGuard(id); e != nil {
+			writeErr(w, e)
+			return
+		}
+	}
 
 	switch r.Method {
 	case http.MethodPut:
 		var body struct {
-			EntryDate     string  `json:"entry_date"`
-			Name          string  `json:"name"`
-			AmountCents   int64   `json:"amount_cents"`
-			SrcAccountID  *int64  `json:"src_account_id"`
-			DestAccountID *int64  `json:"dest_account_id"`
-			ScheduleID    *int64  `json:"schedule_id"`
-			Description   *string `json:"description"`
+			EntryDate     string        `json:"entry_date"`
+			Name          string        `json:"name"`
+			AmountCents   int64         `json:"amount_cents"`
+			SrcAccountID  *int64        `json:"src_account_id"`
+			DestAccountID *int64        `json:"dest_account_id"`
+			ScheduleID    *int64        `json:"schedule_id"`
+			CategoryID    *int64        `json:"category_id"`
+			Description   *string       `json:"description"`
+			Splits        *[]entrySplit `json:"splits"`
+			DestAmount    *int64        `json:"dest_amount_cents"`
+			PayeeID       *int64        `json:"payee_id"`
+			Status        *string       `json:"status"`
+		}
+		present, e := readJSONFields(r, &body)
+		if e != nil {
+			writeErr(w, e)
+			return
 		}
-		if e := readJSON(r, &body); e != nil {
+		// The entry editor does not send a category, payee or received amount;
+		// keep them.
+		if e := s.keepOmitted("entry", ledgerID, id, present, map[string]any{
+			"category_id":       &body.CategoryID,
+			"payee_id":          &body.PayeeID,
+			"dest_amount_cents": &body.DestAmount,
+		}); e != nil {
 			writeErr(w, e)
 			return
 		}
@@ -604,6 +939,12 @@ func (s *server) entryByID(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("amount_cents must be > 0", nil))
 			return
 		}
+		if body.Status != nil {
+			if _, e := requireEntryStatus(*body.Status); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
 		if body.SrcAccountID == nil && body.DestAccountID == nil {
 			writeErr(w, badRequest("must set src_account_id and/or dest_account_id", nil))
 			return
@@ -612,23 +953,86 @@ func (s *server) entryByID(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, badRequest("src_account_id and dest_account_id must differ", nil))
 			return
 		}
+		if e := s.ledgerOwnsAccounts(ledgerID, body.SrcAccountID, body.DestAccountID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsSchedule(ledgerID, body.ScheduleID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, body.CategoryID); e != nil {
+			writeErr(w, e)
+			return
+		}
+		destAmount, e := s.transferDestAmount(body.SrcAccountID, body.DestAccountID, body.DestAmount)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		payeeID, categoryID, e := s.resolvePayee(ledgerID, body.PayeeID, body.Name, body.CategoryID, body.Splits != nil && len(*body.Splits) > 0)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		if body.Splits != nil {
+			if e := s.validateSplits(ledgerID, body.AmountCents, *body.Splits); e != nil {
+				writeErr(w, e)
+				return
+			}
+		}
 
-		_, err := s.db.Exec(
-			"UPDATE entry SET entry_date=?, name=?, amount_cents=?, src_account_id=?, dest_account_id=?, description=?, schedule_id=? WHERE id = ?",
-			ed, strings.TrimSpace(body.Name), body.AmountCents, body.SrcAccountID, body.DestAccountID, body.Description, body.ScheduleID, id,
+		tx, err := s.db.Begin()
+		if err != nil {
+			writeErr(w, serverError("failed to open transaction", err))
+			return
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec(
+			"UPDATE entry SET entry_date=?, name=?, amount_cents=?, src_account_id=?, dest_account_id=?, description=?, schedule_id=?, category_id=?, dest_amount_cents=?, payee_id=?, status=COALESCE(?, status), occurrence_date=CASE WHEN schedule_id IS ? THEN occurrence_date END WHERE id = ? AND ledger_id = ?",
+			ed, strings.TrimSpace(body.Name), body.AmountCents, body.SrcAccountID, body.DestAccountID, body.Description, body.ScheduleID, categoryID, destAmount, payeeID, body.Status, body.ScheduleID, id, ledgerID,
 		)
 		if err != nil {
 			writeErr(w, badRequest("could not update entry", nil))
 			return
 		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			writeErr(w, notFound("entry not found"))
+			return
+		}
+		if body.Splits != nil {
+			if err := replaceEntrySplits(tx, id, *body.Splits); err != nil {
+				writeErr(w, serverError("failed to save entry splits", err))
+				return
+			}
+		} else {
+			// Splits left out of the body are kept, so they must still add up.
+			sum, n, err := splitsTotal(tx, id)
+			if err != nil {
+				writeErr(w, serverError("failed to read entry splits", err))
+				return
+			}
+			if n > 0 && sum != body.AmountCents {
+				writeErr(w, badRequest("splits must sum to amount_cents", map[string]any{"amount_cents": body.AmountCents, "splits_total_cents": sum}))
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			writeErr(w, serverError("failed to update entry", err))
+			return
+		}
 		updated, apiE := scanRowToMap(s.db, "entry", id)
 		if apiE != nil {
 			writeErr(w, apiE)
 			return
 		}
+		if err := s.attachEntrySplits(ledgerID, updated); err != nil {
+			writeErr(w, serverError("failed to read entry splits", err))
+			return
+		}
 		writeOK(w, updated)
 	case http.MethodDelete:
-		res, err := s.db.Exec("DELETE FROM entry WHERE id = ?", id)
+		res, err := s.db.Exec("DELETE FROM entry WHERE id = ? AND ledger_id = ?", id, ledgerID)
 		if err != nil {
 			writeErr(w, badRequest("could not delete entry", nil))
 			return
@@ -656,8 +1060,13 @@ func (s *server) occurrences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	q := occurrenceQuery()
-	rows, err := s.db.Query(q, to, from, to)
+	ledgerID := ledgerFromContext(r.Context())
+	args, err := occurrenceArgs(s.db, ledgerID, 0, from, to)
+	if err != nil {
+		writeErr(w, serverError("failed to compute occurrences", err))
+		return
+	}
+	rows, err := s.db.Query(occurrenceQuery(), args...)
 	if err != nil {
 		writeErr(w, serverError("failed to compute occurrences", err))
 		return
@@ -668,6 +1077,49 @@ func (s *server) occurrences(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, serverError("failed to read occurrences", err))
 		return
 	}
+
+	// Credit card autopay statement payments are listed alongside schedule
+	// occurrences; they have no schedule and cannot be posted.
+	start := projectionStartDate(from, to)
+	payments, err := s.cardAutopayments(ledgerID, start, to, s.projectionOccurrences(ledgerID, 0, start, to))
+	if err != nil {
+		writeErr(w, serverError("failed to compute statement payments", err))
+		return
+	}
+	added := false
+	for _, p := range payments {
+		if p.Date < from {
+			continue
+		}
+		data = append(data, map[string]any{
+			"schedule_id":       nil,
+			"occ_date":          p.Date,
+			"kind":              "T",
+			"name":              p.CardName + " statement payment",
+			"amount_cents":      p.AmountCents,
+			"src_account_id":    p.FundingID,
+			"dest_account_id":   p.CardID,
+			"description":       "Statement closing " + p.ClosingDate,
+			"category_id":       nil,
+			"category_path":     nil,
+			"dest_amount_cents": p.AmountCents,
+			"original_date":     p.Date,
+			"modified":          int64(0),
+			"exception_id":      nil,
+			"exception_kind":    nil,
+			"statement_payment": int64(1),
+		})
+		added = true
+	}
+	if added {
+		sort.SliceStable(data, func(i, j int) bool {
+			di, dj := data[i]["occ_date"].(string), data[j]["occ_date"].(string)
+			if di != dj {
+				return di < dj
+			}
+			return data[i]["name"].(string) < data[j]["name"].(string)
+		})
+	}
 	writeOK(w, data)
 }
 
@@ -675,6 +1127,7 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 	asOf := r.URL.Query().Get("as_of")
 	mode := r.URL.Query().Get("mode")
 	from := r.URL.Query().Get("from_date")
+	ledgerID := ledgerFromContext(r.Context())
 	if mode == "" {
 		mode = "actual"
 	}
@@ -686,6 +1139,20 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, badRequest("mode must be 'actual' or 'projected'", nil))
 		return
 	}
+	reportCurrency, e := reportCurrencyParam(r)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	scenarioID, e := s.scenarioParam(r, ledgerID)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	if scenarioID != 0 && mode != "projected" {
+		writeErr(w, badRequest("scenario_id is only supported for mode=projected", nil))
+		return
+	}
 
 	if mode == "actual" {
 		rows, err := s.db.Query(`
@@ -708,12 +1175,14 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 			  a.is_interest_bearing,
 			  a.interest_apr_bps,
 			  a.interest_compound,
-			  a.exclude_from_dashboard
+			  a.exclude_from_dashboard,
+			  a.currency
 			FROM account a
 			LEFT JOIN deltas d ON d.account_id = a.id
 			WHERE a.archived_at IS NULL
+			  AND a.ledger_id = ?
 			ORDER BY a.name
-		`, asOf)
+		`, asOf, ledgerID)
 		if err != nil {
 			writeErr(w, serverError("failed to compute balances", err))
 			return
@@ -724,6 +1193,14 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, serverError("failed to read balances", err))
 			return
 		}
+		if e := s.addHoldingsToRows(ledgerID, data, "balance_cents", asOf); e != nil {
+			writeErr(w, e)
+			return
+		}
+		if e := s.convertBalanceRows(ledgerID, data, "balance_cents", reportCurrency, asOf); e != nil {
+			writeErr(w, e)
+			return
+		}
 		writeOK(w, data)
 		return
 	}
@@ -736,9 +1213,13 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	q := projectedBalanceQuery()
 	start := projectionStartDate(from, asOf)
-	rows, err := s.db.Query(q, asOf, start, asOf, asOf)
+	q, args, err := projectedBalanceQueryArgs(s.db, scenarioID, ledgerID, start, asOf)
+	if err != nil {
+		writeErr(w, serverError("failed to compute projected balances", err))
+		return
+	}
+	rows, err := s.db.Query(q, args...)
 	if err != nil {
 		writeErr(w, serverError("failed to compute projected balances", err))
 		return
@@ -749,13 +1230,58 @@ func (s *server) balances(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, serverError("failed to read projected balances", err))
 		return
 	}
+	adj, err := s.projectionAdjustments(ledgerID, start, asOf, s.projectionOccurrences(ledgerID, scenarioID, start, asOf))
+	if err != nil {
+		writeErr(w, serverError("failed to compute projected payments", err))
+		return
+	}
+	for _, row := range data {
+		if id, _ := row["id"].(int64); adj[id] != 0 {
+			row["delta_cents"] = row["delta_cents"].(int64) + adj[id]
+			row["projected_balance_cents"] = row["projected_balance_cents"].(int64) + adj[id]
+		}
+	}
+	if e := s.addHoldingsToRows(ledgerID, data, "projected_balance_cents", asOf); e != nil {
+		writeErr(w, e)
+		return
+	}
+	if e := s.convertBalanceRows(ledgerID, data, "projected_balance_cents", reportCurrency, asOf); e != nil {
+		writeErr(w, e)
+		return
+	}
 	writeOK(w, data)
 }
 
+// convertBalanceRows rewrites each row's balance column into reportCurrency
+// using the rates in effect on asOf, keeping the original amount as
+// native_balance_cents. It does nothing when reportCurrency is empty.
+func (s *server) convertBalanceRows(ledgerID int64, rows []map[string]any, col string, reportCurrency string, asOf string) *apiErr {
+	if reportCurrency == "" {
+		return nil
+	}
+	fx, err := s.loadFXTable(ledgerID)
+	if err != nil {
+		return serverError("failed to read fx rates", err)
+	}
+	for _, row := range rows {
+		native, _ := row[col].(int64)
+		currency, _ := row["currency"].(string)
+		converted, err := fx.convert(native, currency, reportCurrency, asOf)
+		if err != nil {
+			return badRequest(err.Error(), nil)
+		}
+		row["native_balance_cents"] = native
+		row[col] = converted
+		row["report_currency"] = reportCurrency
+	}
+	return nil
+}
+
 type balancePoint struct {
 	ID           int64
 	Name         string
 	BalanceCents int64
+	Currency     string
 }
 
 type accountMeta struct {
@@ -767,20 +1293,30 @@ type accountMeta struct {
 	InterestAprBps       int64
 	InterestCompound     string
 	ExcludeFromDashboard int64
+	// LoanScheduled is set for loans with a payment schedule, whose projected
+	// balances already account for interest.
+	LoanScheduled bool
+	// InterestPostedThrough is the end of the last posted interest period;
+	// interest up to it is already in the balances.
+	InterestPostedThrough string
 }
 
-func (s *server) activeAccountMeta() (map[int64]accountMeta, error) {
+func (s *server) activeAccountMeta(ledgerID int64) (map[int64]accountMeta, error) {
 	rows, err := s.db.Query(`
 		SELECT id, name, opening_date,
 		       COALESCE(is_liability, 0) AS is_liability,
 		       COALESCE(is_interest_bearing, 0) AS is_interest_bearing,
 		       COALESCE(interest_apr_bps, 0) AS interest_apr_bps,
 		       COALESCE(interest_compound, 'D') AS interest_compound,
-		       COALESCE(exclude_from_dashboard, 0) AS exclude_from_dashboard
+		       COALESCE(exclude_from_dashboard, 0) AS exclude_from_dashboard,
+		       loan_principal_cents IS NOT NULL AND loan_payment_schedule_id IS NOT NULL AS loan_scheduled,
+		       COALESCE((SELECT MAX(e.interest_period_end) FROM entry e
+		                 WHERE e.interest_period_end IS NOT NULL AND (e.src_account_id = account.id OR e.dest_account_id = account.id)), '') AS interest_posted_through
 		FROM account
 		WHERE archived_at IS NULL
+		  AND ledger_id = ?
 		ORDER BY name
-	`)
+	`, ledgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -789,7 +1325,7 @@ func (s *server) activeAccountMeta() (map[int64]accountMeta, error) {
 	out := make(map[int64]accountMeta)
 	for rows.Next() {
 		var m accountMeta
-		if err := rows.Scan(&m.ID, &m.Name, &m.OpeningDate, &m.IsLiability, &m.IsInterestBearing, &m.InterestAprBps, &m.InterestCompound, &m.ExcludeFromDashboard); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.OpeningDate, &m.IsLiability, &m.IsInterestBearing, &m.InterestAprBps, &m.InterestCompound, &m.ExcludeFromDashboard, &m.LoanScheduled, &m.InterestPostedThrough); err != nil {
 			return nil, err
 		}
 		out[m.ID] = m
@@ -864,7 +1400,7 @@ func CleanupExpiredSessions(db *sql.DB) {
 	_, _ = db.Exec(`DELETE FROM oidc_state WHERE created_at < strftime('%s','now') - 600`)
 }
 
-func (s *server) actualBalancesAsOf(asOf string) ([]balancePoint, error) {
+func (s *server) actualBalancesAsOf(ledgerID int64, asOf string) ([]balancePoint, error) {
 	rows, err := s.db.Query(`
 		WITH deltas AS (
 		  SELECT d.account_id, SUM(d.delta_cents) AS delta_cents
@@ -877,12 +1413,14 @@ func (s *server) actualBalancesAsOf(asOf string) ([]balancePoint, error) {
 		SELECT
 		  a.id,
 		  a.name,
-		  a.opening_balance_cents + COALESCE(d.delta_cents, 0) AS balance_cents
+		  a.opening_balance_cents + COALESCE(d.delta_cents, 0) AS balance_cents,
+		  a.currency
 		FROM account a
 		LEFT JOIN deltas d ON d.account_id = a.id
 		WHERE a.archived_at IS NULL
+		  AND a.ledger_id = ?
 		ORDER BY a.name
-	`, asOf)
+	`, asOf, ledgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -890,12 +1428,18 @@ func (s *server) actualBalancesAsOf(asOf string) ([]balancePoint, error) {
 	var out []balancePoint
 	for rows.Next() {
 		var p balancePoint
-		if err := rows.Scan(&p.ID, &p.Name, &p.BalanceCents); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.BalanceCents, &p.Currency); err != nil {
 			return nil, err
 		}
 		out = append(out, p)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.addHoldingsToPoints(ledgerID, out, asOf); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func projectionStartDate(fromDate string, asOf string) string {
@@ -912,10 +1456,73 @@ func projectionStartDate(fromDate string, asOf string) string {
 	return start
 }
 
-func (s *server) projectedBalancesAsOf(fromDate string, asOf string) ([]balancePoint, error) {
-	q := projectedBalanceQuery()
+// projectionOccurrences lists a projection's occurrences from start through
+// to on first use. Adjustments at several dates of one request share the
+// list; occurrences do not change with the end of the range they are listed
+// for.
+type projectionOccurrences struct {
+	s                    *server
+	ledgerID, scenarioID int64
+	start, to            string
+	list                 []scheduledOccurrence
+	err                  error
+	loaded               bool
+}
+
+func (s *server) projectionOccurrences(ledgerID, scenarioID int64, start, to string) *projectionOccurrences {
+	return &projectionOccurrences{s: s, ledgerID: ledgerID, scenarioID: scenarioID, start: start, to: to}
+}
+
+// through returns the occurrences dated up to asOf, which must not be after to.
+func (p *projectionOccurrences) through(asOf string) ([]scheduledOccurrence, error) {
+	if !p.loaded {
+		p.list, p.err = p.s.listOccurrences(p.ledgerID, p.scenarioID, p.start, p.to)
+		p.loaded = true
+	}
+	n := sort.Search(len(p.list), func(i int) bool { return p.list[i].Date > asOf })
+	return p.list[:n], p.err
+}
+
+// projectionAdjustments returns per-account corrections to
+// projectedBalanceQuery for what it cannot express in SQL: the interest part
+// of loan payments and generated credit card statement payments. occs lists
+// the projection's occurrences from start, with the scenario's overlay if any.
+func (s *server) projectionAdjustments(ledgerID int64, start, asOf string, occs *projectionOccurrences) (map[int64]int64, error) {
+	adj, err := s.loanProjectionAdjustments(ledgerID, asOf, occs)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.cardAutopayments(ledgerID, start, asOf, occs)
+	if err != nil {
+		return nil, err
+	}
+	if adj == nil {
+		adj = make(map[int64]int64)
+	}
+	for _, p := range payments {
+		adj[p.CardID] += p.AmountCents
+		adj[p.FundingID] -= p.AmountCents
+	}
+	return adj, nil
+}
+
+func (s *server) projectedBalancesAsOf(ledgerID int64, fromDate string, asOf string) ([]balancePoint, error) {
+	return s.scenarioBalancesAsOf(ledgerID, 0, fromDate, asOf, nil)
+}
+
+// scenarioBalancesAsOf is projectedBalancesAsOf with a scenario's overlay
+// applied; scenario 0 is the plan itself. occs may carry occurrences already
+// listed for the same projection start; nil lists them for this call.
+func (s *server) scenarioBalancesAsOf(ledgerID, scenarioID int64, fromDate string, asOf string, occs *projectionOccurrences) ([]balancePoint, error) {
 	start := projectionStartDate(fromDate, asOf)
-	rows, err := s.db.Query(q, asOf, start, asOf, asOf)
+	if occs == nil {
+		occs = s.projectionOccurrences(ledgerID, scenarioID, start, asOf)
+	}
+	q, args, err := projectedBalanceQueryArgs(s.db, scenarioID, ledgerID, start, asOf)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -936,6 +1543,7 @@ func (s *server) projectedBalancesAsOf(fromDate string, asOf string) ([]balanceP
 			interestAprBps       any
 			interestCompound     any
 			excludeFromDashboard any
+			currency             string
 		)
 		if err := rows.Scan(
 			&id,
@@ -949,15 +1557,30 @@ func (s *server) projectedBalancesAsOf(fromDate string, asOf string) ([]balanceP
 			&interestAprBps,
 			&interestCompound,
 			&excludeFromDashboard,
+			&currency,
 		); err != nil {
 			return nil, err
 		}
-		out = append(out, balancePoint{ID: id, Name: name, BalanceCents: projected})
+		out = append(out, balancePoint{ID: id, Name: name, BalanceCents: projected, Currency: currency})
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	adj, err := s.projectionAdjustments(ledgerID, start, asOf, occs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].BalanceCents += adj[out[i].ID]
+	}
+	if err := s.addHoldingsToPoints(ledgerID, out, asOf); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
+// parseSeriesParams reads and checks the query parameters of a balance series.
+func (s *server) parseSeriesParams(r *http.Request, ledgerID int64) (seriesParams, *apiErr) {
 	mode := r.URL.Query().Get("mode")
 	from := r.URL.Query().Get("from_date")
 	to := r.URL.Query().Get("to_date")
@@ -967,55 +1590,57 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 		mode = "projected"
 	}
 	if mode != "actual" && mode != "projected" {
-		writeErr(w, badRequest("mode must be 'actual' or 'projected'", nil))
-		return
+		return seriesParams{}, badRequest("mode must be 'actual' or 'projected'", nil)
+	}
+	reportCurrency, e := reportCurrencyParam(r)
+	if e != nil {
+		return seriesParams{}, e
 	}
 	if _, e := requireDate(from, "from_date"); e != nil {
-		writeErr(w, e)
-		return
+		return seriesParams{}, e
 	}
 	if _, e := requireDate(to, "to_date"); e != nil {
-		writeErr(w, e)
-		return
+		return seriesParams{}, e
 	}
 
 	stepDays := 7
 	if strings.TrimSpace(stepDaysStr) != "" {
 		n, err := strconv.Atoi(stepDaysStr)
 		if err != nil {
-			writeErr(w, badRequest("step_days must be an integer", nil))
-			return
+			return seriesParams{}, badRequest("step_days must be an integer", nil)
 		}
 		stepDays = n
 	}
 	if stepDays < 1 || stepDays > 366 {
-		writeErr(w, badRequest("step_days must be 1..366", nil))
-		return
+		return seriesParams{}, badRequest("step_days must be 1..366", nil)
 	}
 
 	includeInterest := false
 	if strings.TrimSpace(includeInterestStr) != "" {
-		s := strings.ToLower(strings.TrimSpace(includeInterestStr))
-		includeInterest = s == "1" || s == "true" || s == "yes" || s == "on"
+		v := strings.ToLower(strings.TrimSpace(includeInterestStr))
+		includeInterest = v == "1" || v == "true" || v == "yes" || v == "on"
 	}
 	if includeInterest && mode != "projected" {
-		writeErr(w, badRequest("include_interest is only supported for mode=projected", nil))
-		return
+		return seriesParams{}, badRequest("include_interest is only supported for mode=projected", nil)
+	}
+	scenarioID, e := s.scenarioParam(r, ledgerID)
+	if e != nil {
+		return seriesParams{}, e
+	}
+	if scenarioID != 0 && mode != "projected" {
+		return seriesParams{}, badRequest("scenario_id is only supported for mode=projected", nil)
 	}
 
 	fromT, err := time.Parse("2006-01-02", from)
 	if err != nil {
-		writeErr(w, badRequest("from_date must be YYYY-MM-DD", nil))
-		return
+		return seriesParams{}, badRequest("from_date must be YYYY-MM-DD", nil)
 	}
 	toT, err := time.Parse("2006-01-02", to)
 	if err != nil {
-		writeErr(w, badRequest("to_date must be YYYY-MM-DD", nil))
-		return
+		return seriesParams{}, badRequest("to_date must be YYYY-MM-DD", nil)
 	}
 	if toT.Before(fromT) {
-		writeErr(w, badRequest("to_date must be >= from_date", nil))
-		return
+		return seriesParams{}, badRequest("to_date must be >= from_date", nil)
 	}
 
 	const maxPoints = 420
@@ -1023,20 +1648,96 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 	days := int(toT.Sub(fromT).Hours() / 24)
 	points := days/stepDays + 1
 	if points > maxPoints {
-		writeErr(w, badRequest("requested series is too long", map[string]any{"max_points": maxPoints, "points": points}))
+		return seriesParams{}, badRequest("requested series is too long", map[string]any{"max_points": maxPoints, "points": points})
+	}
+
+	return seriesParams{
+		Mode:            mode,
+		From:            fromT,
+		To:              toT,
+		StepDays:        stepDays,
+		IncludeInterest: includeInterest,
+		ReportCurrency:  reportCurrency,
+		ScenarioID:      scenarioID,
+	}, nil
+}
+
+func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+	params, e := s.parseSeriesParams(r, ledgerID)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	scenarioID := params.ScenarioID
+	compare := r.URL.Query().Get("compare") == "1"
+	if compare && scenarioID == 0 {
+		writeErr(w, badRequest("compare requires scenario_id", nil))
 		return
 	}
 
-	type accountSeries struct {
-		ID                   int64   `json:"id"`
-		Name                 string  `json:"name"`
-		IsLiability          int64   `json:"is_liability"`
-		IsInterestBearing    int64   `json:"is_interest_bearing"`
-		InterestAprBps       int64   `json:"interest_apr_bps"`
-		InterestCompound     string  `json:"interest_compound"`
-		ExcludeFromDashboard int64   `json:"exclude_from_dashboard"`
-		BalanceCents         []int64 `json:"balance_cents"`
+	out, e := s.balanceSeries(ledgerID, params)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	if !compare {
+		writeOK(w, out)
+		return
+	}
+	params.ScenarioID = 0
+	baseline, e := s.balanceSeries(ledgerID, params)
+	if e != nil {
+		writeErr(w, e)
+		return
 	}
+	baseTotals, _ := baseline["total_cents"].([]int64)
+	scenarioTotals, _ := out["total_cents"].([]int64)
+	diff := make([]int64, len(scenarioTotals))
+	for i := range diff {
+		diff[i] = scenarioTotals[i] - baseTotals[i]
+	}
+	writeOK(w, map[string]any{
+		"scenario_id":      scenarioID,
+		"dates":            out["dates"],
+		"baseline":         baseline,
+		"scenario":         out,
+		"difference_cents": diff,
+	})
+}
+
+// seriesParams describes a balance series request.
+type seriesParams struct {
+	Mode            string
+	From, To        time.Time
+	StepDays        int
+	IncludeInterest bool
+	ReportCurrency  string
+	// ScenarioID projects with a scenario's overlay; 0 is the plan itself.
+	ScenarioID int64
+	// fetch, when set, replaces the projected balance lookup at each point.
+	fetch func(fromDate, asOf string) ([]balancePoint, error)
+}
+
+type accountSeries struct {
+	ID                   int64   `json:"id"`
+	Name                 string  `json:"name"`
+	IsLiability          int64   `json:"is_liability"`
+	IsInterestBearing    int64   `json:"is_interest_bearing"`
+	InterestAprBps       int64   `json:"interest_apr_bps"`
+	InterestCompound     string  `json:"interest_compound"`
+	ExcludeFromDashboard int64   `json:"exclude_from_dashboard"`
+	Currency             string  `json:"currency"`
+	BalanceCents         []int64 `json:"balance_cents"`
+}
+
+// balanceSeries steps balances from p.From to p.To every p.StepDays days.
+// Projected series can accrue synthetic interest between points.
+func (s *server) balanceSeries(ledgerID int64, p seriesParams) (map[string]any, *apiErr) {
+	mode, from, to := p.Mode, p.From.Format("2006-01-02"), p.To.Format("2006-01-02")
+	fromT, toT, stepDays := p.From, p.To, p.StepDays
+	includeInterest, reportCurrency, scenarioID, fetch := p.IncludeInterest, p.ReportCurrency, p.ScenarioID, p.fetch
+	points := int(toT.Sub(fromT).Hours()/24)/stepDays + 1
 
 	var (
 		dates      []string
@@ -1046,10 +1747,16 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 	)
 
 	acctIndex = make(map[int64]int)
-	metaByID, err := s.activeAccountMeta()
+	metaByID, err := s.activeAccountMeta(ledgerID)
 	if err != nil {
-		writeErr(w, serverError("failed to read account metadata", err))
-		return
+		return nil, serverError("failed to read account metadata", err)
+	}
+
+	var fx *fxTable
+	if reportCurrency != "" {
+		if fx, err = s.loadFXTable(ledgerID); err != nil {
+			return nil, serverError("failed to read fx rates", err)
+		}
 	}
 
 	basePrev := make(map[int64]int64)
@@ -1099,14 +1806,19 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 	var prevDate time.Time
 	hasPrev := false
 
+	// Every point projects from the same start, so they share one listing of
+	// occurrences.
+	occs := s.projectionOccurrences(ledgerID, scenarioID, projectionStartDate(projFromDate, to), to)
 	processPoint := func(cur time.Time, record bool) *apiErr {
 		asOf := cur.Format("2006-01-02")
 		var bal []balancePoint
 		var err error
 		if mode == "actual" {
-			bal, err = s.actualBalancesAsOf(asOf)
+			bal, err = s.actualBalancesAsOf(ledgerID, asOf)
+		} else if fetch != nil {
+			bal, err = fetch(projFromDate, asOf)
 		} else {
-			bal, err = s.projectedBalancesAsOf(projFromDate, asOf)
+			bal, err = s.scenarioBalancesAsOf(ledgerID, scenarioID, projFromDate, asOf, occs)
 		}
 		if err != nil {
 			return serverError("failed to compute balances series", err)
@@ -1124,6 +1836,7 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 					InterestAprBps:       m.InterestAprBps,
 					InterestCompound:     m.InterestCompound,
 					ExcludeFromDashboard: m.ExcludeFromDashboard,
+					Currency:             p.Currency,
 					BalanceCents:         make([]int64, 0, points),
 				})
 				basePrev[p.ID] = p.BalanceCents
@@ -1139,7 +1852,7 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 				// Accounts should be stable across the series; if a new one appears, append it.
 				acctIndex[p.ID] = len(accounts)
 				m := metaByID[p.ID]
-				accounts = append(accounts, accountSeries{ID: p.ID, Name: p.Name, IsLiability: m.IsLiability, IsInterestBearing: m.IsInterestBearing, InterestAprBps: m.InterestAprBps, InterestCompound: m.InterestCompound, ExcludeFromDashboard: m.ExcludeFromDashboard})
+				accounts = append(accounts, accountSeries{ID: p.ID, Name: p.Name, IsLiability: m.IsLiability, IsInterestBearing: m.IsInterestBearing, InterestAprBps: m.InterestAprBps, InterestCompound: m.InterestCompound, ExcludeFromDashboard: m.ExcludeFromDashboard, Currency: p.Currency})
 				idx = len(accounts) - 1
 				if record {
 					// backfill missing earlier points with zeros
@@ -1156,14 +1869,18 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 				bp := basePrev[p.ID]
 				delta := p.BalanceCents - bp
 				ap := adjPrev[p.ID]
-				applyInterest := m.IsInterestBearing == 1 && m.InterestAprBps > 0
+				applyInterest := m.IsInterestBearing == 1 && m.InterestAprBps > 0 && !m.LoanScheduled
 				if applyInterest {
 					if od, ok := openByID[p.ID]; ok && cur.Before(od) {
 						applyInterest = false
 					}
 				}
-				if applyInterest {
-					interest := interestForPeriod(ap, m.InterestAprBps, m.InterestCompound, prevDate, cur)
+				accrueFrom := prevDate
+				if pt, err := time.Parse("2006-01-02", m.InterestPostedThrough); err == nil && pt.After(accrueFrom) {
+					accrueFrom = pt
+				}
+				if applyInterest && cur.After(accrueFrom) {
+					interest := interestForPeriod(ap, m.InterestAprBps, m.InterestCompound, accrueFrom, cur)
 					carry := interestCarry[p.ID] + interest
 					interestCents := int64(math.Round(carry))
 					interestCarry[p.ID] = carry - float64(interestCents)
@@ -1178,6 +1895,12 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 				adjPrev[p.ID] = p.BalanceCents
 			}
 
+			if fx != nil {
+				// Interest runs on native balances; only the reported value is converted.
+				if val, err = fx.convert(val, p.Currency, reportCurrency, asOf); err != nil {
+					return badRequest(err.Error(), nil)
+				}
+			}
 			sum += val
 			if record {
 				accounts[idx].BalanceCents = append(accounts[idx].BalanceCents, val)
@@ -1201,20 +1924,18 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 	if doWarmup {
 		for cur := warmStart; cur.Before(fromT); cur = cur.AddDate(0, 0, warmStepDays) {
 			if e := processPoint(cur, false); e != nil {
-				writeErr(w, e)
-				return
+				return nil, e
 			}
 		}
 	}
 
 	for cur := fromT; !cur.After(toT); cur = cur.AddDate(0, 0, stepDays) {
 		if e := processPoint(cur, true); e != nil {
-			writeErr(w, e)
-			return
+			return nil, e
 		}
 	}
 
-	writeOK(w, map[string]any{
+	out := map[string]any{
 		"mode":        mode,
 		"from_date":   from,
 		"to_date":     to,
@@ -1222,7 +1943,11 @@ func (s *server) balancesSeries(w http.ResponseWriter, r *http.Request) {
 		"dates":       dates,
 		"total_cents": totalCents,
 		"accounts":    accounts,
-	})
+	}
+	if reportCurrency != "" {
+		out["report_currency"] = reportCurrency
+	}
+	return out, nil
 }
 
 // --- Schedule payload parsing ---
@@ -1241,6 +1966,22 @@ type schedulePayload struct {
 	ByWeekday     *int64  `json:"byweekday"`
 	Description   *string `json:"description"`
 	IsActive      *int64  `json:"is_active"`
+	CategoryID    *int64  `json:"category_id"`
+	DestAmount    *int64  `json:"dest_amount_cents"`
+	PayeeID       *int64  `json:"payee_id"`
+	RRule         *string `json:"rrule"`
+	Roll          string  `json:"business_day_roll"`
+	Calendar      *string `json:"holiday_calendar"`
+	Variance      string  `json:"variance_model"`
+	VarianceBps   *int64  `json:"variance_bps"`
+	Escalation    string  `json:"escalation_kind"`
+	EscBps        *int64  `json:"escalation_bps"`
+	EscCents      *int64  `json:"escalation_cents"`
+	EscEvery      *int64  `json:"escalation_every"`
+	EscAnniv      *string `json:"escalation_anniversary"`
+	EscRound      *int64  `json:"escalation_round_cents"`
+	MaxOcc        *int64  `json:"max_occurrences"`
+	StopPaidOff   int64   `json:"stop_when_paid_off"`
 }
 
 func parseSchedulePayload(r *http.Request) (*schedulePayload, *apiErr) {
@@ -1248,39 +1989,126 @@ func parseSchedulePayload(r *http.Request) (*schedulePayload, *apiErr) {
 	if e := readJSON(r, &p); e != nil {
 		return nil, e
 	}
+	var errs fieldErrors
+	validateSchedulePayload(&p, &errs)
+	if e := errs.err(); e != nil {
+		return nil, e
+	}
+	return &p, nil
+}
+
+// parseScheduleUpdate is parseSchedulePayload for a PUT: fields the editor
+// does not know and the body leaves out keep their stored values.
+func (s *server) parseScheduleUpdate(r *http.Request, ledgerID, id int64) (*schedulePayload, *apiErr) {
+	var p schedulePayload
+	present, e := readJSONFields(r, &p)
+	if e != nil {
+		return nil, e
+	}
+	if e := s.keepOmitted("schedule", ledgerID, id, present, map[string]any{
+		"rrule":                  &p.RRule,
+		"category_id":            &p.CategoryID,
+		"payee_id":               &p.PayeeID,
+		"dest_amount_cents":      &p.DestAmount,
+		"business_day_roll":      &p.Roll,
+		"holiday_calendar":       &p.Calendar,
+		"variance_model":         &p.Variance,
+		"variance_bps":           &p.VarianceBps,
+		"escalation_kind":        &p.Escalation,
+		"escalation_bps":         &p.EscBps,
+		"escalation_cents":       &p.EscCents,
+		"escalation_every":       &p.EscEvery,
+		"escalation_anniversary": &p.EscAnniv,
+		"escalation_round_cents": &p.EscRound,
+		"max_occurrences":        &p.MaxOcc,
+		"stop_when_paid_off":     &p.StopPaidOff,
+	}); e != nil {
+		return nil, e
+	}
+	var errs fieldErrors
+	validateSchedulePayload(&p, &errs)
+	if e := errs.err(); e != nil {
+		return nil, e
+	}
+	return &p, nil
+}
+
+// validateSchedulePayload normalizes a schedule payload and records what is
+// wrong with it, field by field.
+func validateSchedulePayload(p *schedulePayload, errs *fieldErrors) {
 	p.Name = strings.TrimSpace(p.Name)
 	if p.Name == "" {
-		return nil, badRequest("name is required", nil)
+		errs.add("name", "name is required")
 	}
 	if p.Kind != "I" && p.Kind != "E" && p.Kind != "T" {
-		return nil, badRequest("kind must be one of I, E, T", nil)
+		errs.add("kind", "kind must be one of I, E, T")
+	}
+	if p.RRule != nil && strings.TrimSpace(*p.RRule) == "" {
+		p.RRule = nil
+	}
+	if p.RRule != nil {
+		if rule, err := parseRRule(*p.RRule); err != nil {
+			errs.add("rrule", "invalid rrule: "+err.Error())
+		} else {
+			normalized := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(*p.RRule)), "RRULE:")
+			p.RRule = &normalized
+			p.Freq = rule.legacyFreq()
+			p.Interval = int64(rule.Interval)
+			p.ByMonthDay, p.ByWeekday = nil, nil
+		}
 	}
 	if p.Freq != "D" && p.Freq != "W" && p.Freq != "M" && p.Freq != "Y" {
-		return nil, badRequest("freq must be one of D, W, M, Y", nil)
+		errs.add("freq", "freq must be one of D, W, M, Y")
 	}
 	if p.AmountCents <= 0 {
-		return nil, badRequest("amount_cents must be > 0", nil)
+		errs.add("amount_cents", "amount_cents must be > 0")
 	}
 	if _, e := requireDate(p.StartDate, "start_date"); e != nil {
-		return nil, e
+		errs.add("start_date", e.Message)
 	}
-	end, e := optionalDate(p.EndDate, "end_date")
-	if e != nil {
-		return nil, e
+	if end, e := optionalDate(p.EndDate, "end_date"); e != nil {
+		errs.add("end_date", e.Message)
+	} else {
+		p.EndDate = end
 	}
-	p.EndDate = end
 	if p.Interval < 1 {
 		p.Interval = 1
 	}
-	if p.ByMonthDay != nil {
-		if *p.ByMonthDay < 1 || *p.ByMonthDay > 31 {
-			return nil, badRequest("bymonthday must be 1..31", nil)
-		}
+	if p.ByMonthDay != nil && (*p.ByMonthDay < 1 || *p.ByMonthDay > 31) {
+		errs.add("bymonthday", "bymonthday must be 1..31")
+	}
+	if p.ByWeekday != nil && (*p.ByWeekday < 0 || *p.ByWeekday > 6) {
+		errs.add("byweekday", "byweekday must be 0..6")
 	}
-	if p.ByWeekday != nil {
-		if *p.ByWeekday < 0 || *p.ByWeekday > 6 {
-			return nil, badRequest("byweekday must be 0..6", nil)
+	switch p.Roll {
+	case "":
+		p.Roll = "none"
+	case "none", "following", "preceding", "modified_following":
+	default:
+		errs.add("business_day_roll", "business_day_roll must be one of none, following, preceding, modified_following")
+	}
+	if p.Calendar != nil && strings.TrimSpace(*p.Calendar) == "" {
+		p.Calendar = nil
+	}
+	switch p.Variance {
+	case "", "fixed", "history":
+		if p.Variance == "" {
+			p.Variance = "fixed"
+		}
+		p.VarianceBps = nil
+	case "percent":
+		if p.VarianceBps == nil || *p.VarianceBps < 1 || *p.VarianceBps > 10000 {
+			errs.add("variance_bps", "variance_bps must be 1..10000 for variance_model 'percent'")
 		}
+	default:
+		errs.add("variance_model", "variance_model must be one of fixed, percent, history")
+	}
+	validateEscalation(p, errs)
+	if p.MaxOcc != nil && *p.MaxOcc < 1 {
+		errs.add("max_occurrences", "max_occurrences must be >= 1")
+	}
+	if p.StopPaidOff != 0 {
+		p.StopPaidOff = 1
 	}
 	if p.IsActive == nil {
 		v := int64(1)
@@ -1295,21 +2123,104 @@ func parseSchedulePayload(r *http.Request) (*schedulePayload, *apiErr) {
 
 	src := p.SrcAccountID
 	dest := p.DestAccountID
-	if p.Kind == "I" {
-		if dest == nil || src != nil {
-			return nil, badRequest("Income schedules require dest_account_id and must not set src_account_id", nil)
+	switch p.Kind {
+	case "I":
+		const msg = "Income schedules require dest_account_id and must not set src_account_id"
+		if dest == nil {
+			errs.add("dest_account_id", msg)
+		}
+		if src != nil {
+			errs.add("src_account_id", msg)
+		}
+	case "E":
+		const msg = "Expense schedules require src_account_id and must not set dest_account_id"
+		if src == nil {
+			errs.add("src_account_id", msg)
+		}
+		if dest != nil {
+			errs.add("dest_account_id", msg)
+		}
+	case "T":
+		const msg = "Transfer schedules require distinct src_account_id and dest_account_id"
+		if src == nil {
+			errs.add("src_account_id", msg)
+		}
+		if dest == nil || (src != nil && *src == *dest) {
+			errs.add("dest_account_id", msg)
 		}
 	}
-	if p.Kind == "E" {
-		if src == nil || dest != nil {
-			return nil, badRequest("Expense schedules require src_account_id and must not set dest_account_id", nil)
+}
+
+// validateEscalation checks a schedule's escalation rule and clears the
+// fields that do not apply to its kind. Without escalation_every the steps
+// fall on escalation_anniversary, by default the start date's month and day.
+func validateEscalation(p *schedulePayload, errs *fieldErrors) {
+	switch p.Escalation {
+	case "", "none":
+		p.Escalation = "none"
+		p.EscBps, p.EscCents, p.EscEvery, p.EscAnniv, p.EscRound = nil, nil, nil, nil, nil
+		return
+	case "percent":
+		if p.EscBps == nil || *p.EscBps == 0 || *p.EscBps <= -10000 {
+			errs.add("escalation_bps", "escalation_bps must be non-zero and greater than -10000 for escalation_kind 'percent'")
+		}
+		p.EscCents = nil
+	case "fixed":
+		if p.EscCents == nil || *p.EscCents == 0 {
+			errs.add("escalation_cents", "escalation_cents must be non-zero for escalation_kind 'fixed'")
 		}
+		p.EscBps = nil
+	default:
+		errs.add("escalation_kind", "escalation_kind must be one of none, percent, fixed")
+		return
+	}
+	if p.RRule != nil {
+		errs.add("escalation_kind", "escalation is not supported on rrule schedules")
+		return
 	}
-	if p.Kind == "T" {
-		if src == nil || dest == nil || *src == *dest {
-			return nil, badRequest("Transfer schedules require distinct src_account_id and dest_account_id", nil)
+	if p.EscAnniv != nil && strings.TrimSpace(*p.EscAnniv) == "" {
+		p.EscAnniv = nil
+	}
+	if p.EscEvery != nil {
+		if p.EscAnniv != nil {
+			errs.add("escalation_every", "set escalation_every or escalation_anniversary, not both")
+		} else if *p.EscEvery < 1 {
+			errs.add("escalation_every", "escalation_every must be >= 1")
+		}
+	} else if !errs.has("start_date", "freq") {
+		if p.EscAnniv == nil {
+			anniv := p.StartDate[5:]
+			p.EscAnniv = &anniv
+		}
+		if _, err := time.Parse("2006-01-02", "2000-"+*p.EscAnniv); err != nil {
+			errs.add("escalation_anniversary", "escalation_anniversary must be MM-DD")
 		}
+		// Occurrences at most a year apart cross at most one anniversary.
+		limit := map[string]int64{"D": 365, "W": 52, "M": 12, "Y": 1}[p.Freq]
+		if p.Interval > limit {
+			errs.add("escalation_anniversary", "escalation_anniversary needs a schedule that occurs at least once a year")
+		}
+	}
+	if p.EscRound != nil && *p.EscRound < 1 {
+		errs.add("escalation_round_cents", "escalation_round_cents must be >= 1")
 	}
+}
 
-	return &p, nil
+// validatePayoffTarget checks that a schedule stopping once its destination is
+// paid off pays into a liability.
+func (s *server) validatePayoffTarget(stop int64, dest *int64) *apiErr {
+	if stop == 0 {
+		return nil
+	}
+	if dest == nil {
+		return badRequest("stop_when_paid_off requires dest_account_id", nil)
+	}
+	var isLiability int64
+	if err := s.db.QueryRow("SELECT is_liability FROM account WHERE id = ?", *dest).Scan(&isLiability); err != nil {
+		return serverError("failed to read account", err)
+	}
+	if isLiability == 0 {
+		return badRequest("stop_when_paid_off requires a liability dest_account_id", nil)
+	}
+	return nil
 }
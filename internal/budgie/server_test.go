@@ -20,7 +20,7 @@ func TestProjectedBalancesAsOf_ScanMatchesQuery(t *testing.T) {
 	}
 
 	srv := &server{db: db}
-	pts, err := srv.projectedBalancesAsOf("2026-01-01", "2026-01-10")
+	pts, err := srv.projectedBalancesAsOf(defaultLedgerID, "2026-01-01", "2026-01-10")
 	if err != nil {
 		t.Fatalf("projectedBalancesAsOf: %v", err)
 	}
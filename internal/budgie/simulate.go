@@ -0,0 +1,329 @@
+package budgie
+
+import (
+	"database/sql"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Simulated projections rerun the balance series many times, each with
+// schedule amounts drawn from their variance_model, and report percentile
+// bands. Run i uses seed+i, so a request is reproducible.
+
+const (
+	defaultSimulationRuns = 100
+	maxSimulationRuns     = 1000
+	// maxSimulationPoints bounds runs times series points.
+	maxSimulationPoints = 50000
+)
+
+// varianceModel draws a multiplier for one occurrence's amount.
+type varianceModel struct {
+	Model  string
+	Bps    int64
+	Ratios []float64 // history: linked entry amounts over their mean
+}
+
+func (v varianceModel) sample(rng *rand.Rand) float64 {
+	switch v.Model {
+	case "percent":
+		return 1 + (rng.Float64()*2-1)*float64(v.Bps)/10000
+	case "history":
+		if len(v.Ratios) > 0 {
+			return v.Ratios[rng.Intn(len(v.Ratios))]
+		}
+	}
+	return 1
+}
+
+// loadVarianceModels returns the variance of every schedule that has one.
+// History needs at least two linked entries; with fewer the schedule is fixed.
+func (s *server) loadVarianceModels(ledgerID int64) (map[int64]varianceModel, error) {
+	rows, err := s.db.Query(`
+		SELECT id, variance_model, variance_bps
+		FROM schedule
+		WHERE ledger_id = ? AND variance_model != 'fixed'
+	`, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	models := make(map[int64]varianceModel)
+	for rows.Next() {
+		var id int64
+		var v varianceModel
+		var bps sql.NullInt64
+		if err := rows.Scan(&id, &v.Model, &bps); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		v.Bps = bps.Int64
+		models[id] = v
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`
+		SELECT e.schedule_id, e.amount_cents
+		FROM entry e
+		JOIN schedule s ON s.id = e.schedule_id
+		WHERE s.ledger_id = ? AND s.variance_model = 'history'
+		ORDER BY e.schedule_id, e.entry_date
+	`, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	amounts := make(map[int64][]float64)
+	for rows.Next() {
+		var id, cents int64
+		if err := rows.Scan(&id, &cents); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		amounts[id] = append(amounts[id], float64(cents))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for id, a := range amounts {
+		if len(a) < 2 {
+			continue
+		}
+		var mean float64
+		for _, x := range a {
+			mean += x
+		}
+		mean /= float64(len(a))
+		v := models[id]
+		for _, x := range a {
+			v.Ratios = append(v.Ratios, x/mean)
+		}
+		models[id] = v
+	}
+	return models, nil
+}
+
+// balanceShift is a change to one account's balance from a simulated
+// occurrence amount.
+type balanceShift struct {
+	Date      string
+	AccountID int64
+	Cents     int64
+}
+
+// simulateShifts draws every occurrence's amount once and returns the
+// resulting balance changes in date order.
+func simulateShifts(occs []scheduledOccurrence, models map[int64]varianceModel, rng *rand.Rand) []balanceShift {
+	var out []balanceShift
+	for _, o := range occs {
+		model, ok := models[o.ScheduleID]
+		if !ok {
+			continue
+		}
+		m := model.sample(rng)
+		if o.SrcAccountID != nil {
+			if d := int64(math.Round(float64(o.AmountCents)*m)) - o.AmountCents; d != 0 {
+				out = append(out, balanceShift{o.Date, *o.SrcAccountID, -d})
+			}
+		}
+		if o.DestAccountID != nil {
+			if d := int64(math.Round(float64(o.DestAmount)*m)) - o.DestAmount; d != 0 {
+				out = append(out, balanceShift{o.Date, *o.DestAccountID, d})
+			}
+		}
+	}
+	return out
+}
+
+// percentile returns the nearest-rank q-th percentile of sorted values.
+func percentile(sorted []int64, q float64) int64 {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+type percentileBands struct {
+	P10 []int64 `json:"p10"`
+	P50 []int64 `json:"p50"`
+	P90 []int64 `json:"p90"`
+}
+
+// bandsOf turns runs[run][point] into per-point percentile bands.
+func bandsOf(runs [][]int64, points int) percentileBands {
+	b := percentileBands{P10: make([]int64, points), P50: make([]int64, points), P90: make([]int64, points)}
+	vals := make([]int64, len(runs))
+	for i := 0; i < points; i++ {
+		for r := range runs {
+			vals[r] = 0
+			if i < len(runs[r]) {
+				vals[r] = runs[r][i]
+			}
+		}
+		sort.Slice(vals, func(a, c int) bool { return vals[a] < vals[c] })
+		b.P10[i], b.P50[i], b.P90[i] = percentile(vals, 0.1), percentile(vals, 0.5), percentile(vals, 0.9)
+	}
+	return b
+}
+
+// balancesSimulate serves GET /api/balances/series/simulate. It takes the
+// parameters of /api/balances/series (projected mode only) plus runs (default
+// 100) and seed (default 1), and returns p10/p50/p90 bands per date for the
+// total and for each account.
+//
+//	GET /api/balances/series/simulate?from_date=2026-01-01&to_date=2026-12-31&runs=200&seed=7
+func (s *server) balancesSimulate(w http.ResponseWriter, r *http.Request) {
+	ledgerID := ledgerFromContext(r.Context())
+	params, e := s.parseSeriesParams(r, ledgerID)
+	if e != nil {
+		writeErr(w, e)
+		return
+	}
+	if params.Mode != "projected" {
+		writeErr(w, badRequest("simulations require mode=projected", nil))
+		return
+	}
+	if params.ScenarioID != 0 {
+		writeErr(w, badRequest("scenario_id is not supported for simulations", nil))
+		return
+	}
+	runs := defaultSimulationRuns
+	if v := strings.TrimSpace(r.URL.Query().Get("runs")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSimulationRuns {
+			writeErr(w, badRequest("runs must be 1.."+strconv.Itoa(maxSimulationRuns), nil))
+			return
+		}
+		runs = n
+	}
+	seed := int64(1)
+	if v := strings.TrimSpace(r.URL.Query().Get("seed")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeErr(w, badRequest("seed must be an integer", nil))
+			return
+		}
+		seed = n
+	}
+	points := int(params.To.Sub(params.From).Hours()/24)/params.StepDays + 1
+	if runs*points > maxSimulationPoints {
+		writeErr(w, badRequest("requested simulation is too large", map[string]any{"max_points": maxSimulationPoints, "points": runs * points}))
+		return
+	}
+
+	models, err := s.loadVarianceModels(ledgerID)
+	if err != nil {
+		writeErr(w, serverError("failed to read schedule variance", err))
+		return
+	}
+
+	// Projections are the same in every run; only the shifts differ.
+	projections := make(map[string][]balancePoint)
+	var occs []scheduledOccurrence
+	occsFrom := ""
+	loadOccurrences := func(fromDate string) error {
+		if occsFrom == fromDate {
+			return nil
+		}
+		start := min(fromDate, time.Now().Format("2006-01-02"))
+		to := params.To.Format("2006-01-02")
+		list, err := s.listOccurrences(ledgerID, 0, start, to)
+		if err != nil {
+			return err
+		}
+		occs = list
+		occsFrom = fromDate
+		return nil
+	}
+
+	var (
+		dates    []string
+		totals   [][]int64
+		accounts []accountSeries
+		byID     = make(map[int64][][]int64)
+	)
+	for run := 0; run < runs; run++ {
+		rng := rand.New(rand.NewSource(seed + int64(run)))
+		var shifts []balanceShift
+		drawn := false
+		params.fetch = func(fromDate, asOf string) ([]balancePoint, error) {
+			if err := loadOccurrences(fromDate); err != nil {
+				return nil, err
+			}
+			if !drawn {
+				shifts, drawn = simulateShifts(occs, models, rng), true
+			}
+			key := fromDate + "|" + asOf
+			base, ok := projections[key]
+			if !ok {
+				var err error
+				if base, err = s.projectedBalancesAsOf(ledgerID, fromDate, asOf); err != nil {
+					return nil, err
+				}
+				projections[key] = base
+			}
+			out := append([]balancePoint(nil), base...)
+			idx := make(map[int64]int, len(out))
+			for i, p := range out {
+				idx[p.ID] = i
+			}
+			start := projectionStartDate(fromDate, asOf)
+			for _, sh := range shifts {
+				if sh.Date > asOf {
+					break
+				}
+				if i, ok := idx[sh.AccountID]; ok && sh.Date >= start {
+					out[i].BalanceCents += sh.Cents
+				}
+			}
+			return out, nil
+		}
+		res, e := s.balanceSeries(ledgerID, params)
+		if e != nil {
+			writeErr(w, e)
+			return
+		}
+		dates, _ = res["dates"].([]string)
+		total, _ := res["total_cents"].([]int64)
+		totals = append(totals, total)
+		accounts, _ = res["accounts"].([]accountSeries)
+		for _, a := range accounts {
+			byID[a.ID] = append(byID[a.ID], a.BalanceCents)
+		}
+	}
+
+	type accountBands struct {
+		ID          int64  `json:"id"`
+		Name        string `json:"name"`
+		IsLiability int64  `json:"is_liability"`
+		Currency    string `json:"currency"`
+		percentileBands
+	}
+	outAccounts := make([]accountBands, 0, len(accounts))
+	for _, a := range accounts {
+		outAccounts = append(outAccounts, accountBands{
+			ID: a.ID, Name: a.Name, IsLiability: a.IsLiability, Currency: a.Currency,
+			percentileBands: bandsOf(byID[a.ID], len(dates)),
+		})
+	}
+	out := map[string]any{
+		"mode":      params.Mode,
+		"from_date": params.From.Format("2006-01-02"),
+		"to_date":   params.To.Format("2006-01-02"),
+		"step_days": params.StepDays,
+		"runs":      runs,
+		"seed":      seed,
+		"dates":     dates,
+		"total":     bandsOf(totals, len(dates)),
+		"accounts":  outAccounts,
+	}
+	if params.ReportCurrency != "" {
+		out["report_currency"] = params.ReportCurrency
+	}
+	writeOK(w, out)
+}
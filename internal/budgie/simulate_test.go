@@ -0,0 +1,109 @@
+package budgie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBalancesSimulate(t *testing.T) {
+	db := newTestDB(t)
+	api := newTestAPIServer(t, db)
+
+	resp := doJSON(t, http.MethodPost, api.URL+"/api/accounts", map[string]any{
+		"name": "Checking", "opening_date": "2026-01-01", "opening_balance_cents": 100000,
+	})
+	checkingID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	groceries := map[string]any{
+		"name": "Groceries", "kind": "E", "amount_cents": 10000, "src_account_id": checkingID,
+		"start_date": "2026-01-05", "freq": "M", "interval": 1, "variance_model": "percent",
+	}
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", groceries)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for percent variance without variance_bps, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+	groceries["variance_bps"] = 2000
+	resp = doJSON(t, http.MethodPost, api.URL+"/api/schedules", groceries)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create schedule: status %d", resp.StatusCode)
+	}
+	groceriesID := mustInt64(t, mustMap(t, decodeAPIResponse(t, resp).Data)["id"])
+
+	// An edit that leaves the variance out keeps it.
+	delete(groceries, "variance_model")
+	delete(groceries, "variance_bps")
+	groceries["description"] = "Weekly shop"
+	resp = doJSON(t, http.MethodPut, api.URL+"/api/schedules/"+fmtInt64(groceriesID), groceries)
+	if updated := mustMap(t, decodeAPIResponse(t, resp).Data); updated["variance_model"] != "percent" || updated["variance_bps"] == nil || mustInt64(t, updated["variance_bps"]) != 2000 {
+		t.Fatalf("expected an edit without variance to keep it: %v", updated)
+	}
+
+	simulate := func(query string) map[string]any {
+		t.Helper()
+		resp := doJSON(t, http.MethodGet, api.URL+"/api/balances/series/simulate?from_date=2026-01-01&to_date=2026-06-30&step_days=30&runs=200&seed=3"+query, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("simulate: status %d", resp.StatusCode)
+		}
+		return mustMap(t, decodeAPIResponse(t, resp).Data)
+	}
+	out := simulate("")
+	total := mustMap(t, out["total"])
+	p10, p50, p90 := mustList(t, total["p10"]), mustList(t, total["p50"]), mustList(t, total["p90"])
+	if len(p50) != 7 {
+		t.Fatalf("expected 7 points, got %d", len(p50))
+	}
+	if mustInt64(t, p10[0]) != 100000 || mustInt64(t, p90[0]) != 100000 {
+		t.Fatalf("expected no spread before the first occurrence: %v %v", p10[0], p90[0])
+	}
+	// Six charges of 10000 +/- 20% by the end.
+	last := len(p50) - 1
+	lo, mid, hi := mustInt64(t, p10[last]), mustInt64(t, p50[last]), mustInt64(t, p90[last])
+	if !(lo < mid && mid < hi) || lo < 100000-72000 || hi > 100000-48000 {
+		t.Fatalf("unexpected final bands: p10=%d p50=%d p90=%d", lo, mid, hi)
+	}
+	account := mustMap(t, mustList(t, out["accounts"])[0])
+	if !reflect.DeepEqual(account["p50"], total["p50"]) {
+		t.Fatalf("single account bands should match the total: %v vs %v", account["p50"], total["p50"])
+	}
+
+	if again := simulate(""); !reflect.DeepEqual(again["total"], out["total"]) {
+		t.Fatalf("expected the same seed to reproduce the bands")
+	}
+	simulate("&include_interest=1")
+
+	resp = doJSON(t, http.MethodGet, api.URL+"/api/balances/series/simulate?mode=actual&from_date=2026-01-01&to_date=2026-06-30", nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an actual-mode simulation, got %d", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestHistoryVarianceModel(t *testing.T) {
+	db := newTestDB(t)
+	srv := &server{db: db}
+	res, err := db.Exec("INSERT INTO account (name, opening_date) VALUES ('Checking', '2026-01-01')")
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+	res, err = db.Exec(`INSERT INTO schedule (name, kind, amount_cents, src_account_id, start_date, freq, variance_model)
+		VALUES ('Power', 'E', 10000, ?, '2026-01-01', 'M', 'history')`, acctID)
+	if err != nil {
+		t.Fatalf("insert schedule: %v", err)
+	}
+	schedID, _ := res.LastInsertId()
+	for _, amount := range []int64{8000, 12000} {
+		if _, err := db.Exec("INSERT INTO entry (entry_date, name, amount_cents, src_account_id, schedule_id) VALUES ('2026-01-01', 'Power', ?, ?, ?)", amount, acctID, schedID); err != nil {
+			t.Fatalf("insert entry: %v", err)
+		}
+	}
+	models, err := srv.loadVarianceModels(defaultLedgerID)
+	if err != nil {
+		t.Fatalf("loadVarianceModels: %v", err)
+	}
+	if got := models[schedID].Ratios; !reflect.DeepEqual(got, []float64{0.8, 1.2}) {
+		t.Fatalf("ratios = %v, want [0.8 1.2]", got)
+	}
+}
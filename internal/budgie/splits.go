@@ -0,0 +1,104 @@
+package budgie
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// entrySplit is one line of a split entry as sent by the client.
+type entrySplit struct {
+	CategoryID  *int64  `json:"category_id"`
+	AmountCents int64   `json:"amount_cents"`
+	Memo        *string `json:"memo"`
+}
+
+// validateSplits checks that split lines are positive, reference the ledger's
+// categories and add up to the parent amount. An empty list is valid and
+// means the entry is not split.
+func (s *server) validateSplits(ledgerID int64, amountCents int64, splits []entrySplit) *apiErr {
+	if len(splits) == 0 {
+		return nil
+	}
+	var sum int64
+	for i := range splits {
+		if splits[i].AmountCents <= 0 {
+			return badRequest("split amount_cents must be > 0", map[string]any{"index": i})
+		}
+		if e := s.ledgerOwnsCategory(ledgerID, splits[i].CategoryID); e != nil {
+			return e
+		}
+		sum += splits[i].AmountCents
+	}
+	if sum != amountCents {
+		return badRequest("splits must sum to amount_cents", map[string]any{"amount_cents": amountCents, "splits_total_cents": sum})
+	}
+	return nil
+}
+
+// replaceEntrySplits swaps an entry's split lines for the given ones.
+func replaceEntrySplits(tx *sql.Tx, entryID int64, splits []entrySplit) error {
+	if _, err := tx.Exec("DELETE FROM entry_split WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for i, sp := range splits {
+		var memo *string
+		if sp.Memo != nil && strings.TrimSpace(*sp.Memo) != "" {
+			m := strings.TrimSpace(*sp.Memo)
+			memo = &m
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO entry_split (entry_id, category_id, amount_cents, memo, position) VALUES (?, ?, ?, ?, ?)",
+			entryID, sp.CategoryID, sp.AmountCents, memo, i,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitsTotal returns the sum and count of an entry's existing split lines.
+func splitsTotal(tx *sql.Tx, entryID int64) (int64, int, error) {
+	var sum int64
+	var n int
+	err := tx.QueryRow("SELECT COALESCE(SUM(amount_cents), 0), COUNT(*) FROM entry_split WHERE entry_id = ?", entryID).Scan(&sum, &n)
+	return sum, n, err
+}
+
+// attachEntrySplits adds a "splits" list (with category paths) to each of a
+// ledger's entry maps. Unsplit entries get an empty list.
+func (s *server) attachEntrySplits(ledgerID int64, entries ...map[string]any) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	byID := make(map[int64]map[string]any, len(entries))
+	for _, e := range entries {
+		e["splits"] = []map[string]any{}
+		if id, ok := e["id"].(int64); ok {
+			byID[id] = e
+		}
+	}
+	rows, err := s.db.Query(`
+		WITH RECURSIVE `+categoryPathCTEDefs()+`
+		SELECT sp.*, cp.path AS category_path
+		FROM entry_split sp
+		JOIN entry e ON e.id = sp.entry_id
+		LEFT JOIN category_path cp ON cp.id = sp.category_id
+		WHERE e.ledger_id = ?
+		ORDER BY sp.entry_id, sp.position, sp.id
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	lines, err := rowsToMaps(rows)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		entryID, _ := line["entry_id"].(int64)
+		if e, ok := byID[entryID]; ok {
+			e["splits"] = append(e["splits"].([]map[string]any), line)
+		}
+	}
+	return nil
+}
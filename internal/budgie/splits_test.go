@@ -0,0 +1,97 @@
+package budgie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSplitEntriesFeedCategoryReports(t *testing.T) {
+	db := newTestDB(t)
+
+	res, err := db.Exec(
+		"INSERT INTO account (name, opening_date, opening_balance_cents) VALUES (?, ?, ?)",
+		"Checking", "2026-01-01", int64(100000),
+	)
+	if err != nil {
+		t.Fatalf("insert account: %v", err)
+	}
+	acctID, _ := res.LastInsertId()
+	var cats [2]int64
+	for i, name := range []string{"Food", "Household"} {
+		res, err := db.Exec("INSERT INTO category (name) VALUES (?)", name)
+		if err != nil {
+			t.Fatalf("insert category: %v", err)
+		}
+		cats[i], _ = res.LastInsertId()
+	}
+
+	server := newTestAPIServer(t, db)
+
+	entry := map[string]any{
+		"entry_date":     "2026-01-05",
+		"name":           "Supermarket",
+		"amount_cents":   10000,
+		"src_account_id": acctID,
+		"splits": []map[string]any{
+			{"category_id": cats[0], "amount_cents": 6000, "memo": "groceries"},
+			{"category_id": cats[1], "amount_cents": 3000},
+		},
+	}
+	resp := doJSON(t, http.MethodPost, server.URL+"/api/entries", entry)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for splits not summing to amount, got %d", resp.StatusCode)
+	}
+
+	entry["splits"].([]map[string]any)[1]["amount_cents"] = 4000
+	resp = doJSON(t, http.MethodPost, server.URL+"/api/entries", entry)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create split entry: status %d", resp.StatusCode)
+	}
+	created := mustMap(t, decodeAPIResponse(t, resp).Data)
+	entryID := mustInt64(t, created["id"])
+	if n := len(mustList(t, created["splits"])); n != 2 {
+		t.Fatalf("expected 2 split lines, got %d", n)
+	}
+
+	// The parent stays a single money movement.
+	var rows, delta int64
+	if err := db.QueryRow("SELECT COUNT(*), SUM(delta_cents) FROM v_entry_delta WHERE entry_id = ?", entryID).Scan(&rows, &delta); err != nil {
+		t.Fatalf("query deltas: %v", err)
+	}
+	if rows != 1 || delta != -10000 {
+		t.Fatalf("expected one -10000 delta, got %d rows totalling %d", rows, delta)
+	}
+
+	rollResp, err := http.Get(server.URL + "/api/categories/rollup?from_date=2026-01-01&to_date=2026-01-31")
+	if err != nil {
+		t.Fatalf("get rollup: %v", err)
+	}
+	totals := map[string]int64{}
+	for _, item := range mustList(t, mustMap(t, decodeAPIResponse(t, rollResp).Data)["categories"]) {
+		row := mustMap(t, item)
+		totals[row["path"].(string)] = mustInt64(t, row["outflow_cents"])
+	}
+	if totals["Food"] != 6000 || totals["Household"] != 4000 || totals["Uncategorized"] != 0 {
+		t.Fatalf("unexpected rollup totals: %v", totals)
+	}
+
+	// Changing the amount without new splits would break the sum.
+	delete(entry, "splits")
+	entry["amount_cents"] = 12000
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/entries/"+fmtInt64(entryID), entry)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 updating amount under existing splits, got %d", resp.StatusCode)
+	}
+	entry["splits"] = []map[string]any{}
+	resp = doJSON(t, http.MethodPut, server.URL+"/api/entries/"+fmtInt64(entryID), entry)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 clearing splits, got %d", resp.StatusCode)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entry_split WHERE entry_id = ?", entryID).Scan(&n); err != nil {
+		t.Fatalf("count splits: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected splits cleared, got %d", n)
+	}
+}
@@ -1,25 +1,106 @@
 package budgie
 
-func occurrenceCTEDefs() string {
+// occurrenceCTEDefs expands active schedules into the occurrence CTE: one row
+// per unposted occurrence with schedule exceptions, versions and revisions
+// applied.
+// Parameters: ledger id, range start, range end and the interest part of loan
+// payments that stop once paid off (see payoffCTEDefs); only occurrences in
+// the range are listed. With scenario set, the scenario id comes first and its
+// overlay is applied: scenario schedules join in with negated ids and
+// overrides disable or re-price occurrences.
+func occurrenceCTEDefs(scenario bool) string {
+	param := "NULL"
+	if scenario {
+		param = "?"
+	}
 	return `
+scenario_param(id) AS (SELECT ` + param + `),
+occurrence_param(ledger_id, start_date, end_date, payoff_interest) AS (SELECT ?, ?, ?, ?),
+schedule_source AS (
+	SELECT *
+	FROM (
+		SELECT id, ledger_id, name, kind, amount_cents, src_account_id, dest_account_id, dest_amount_cents,
+			description, category_id, freq, interval, start_date, end_date, bymonthday, byweekday,
+			rrule, business_day_roll, holiday_calendar,
+			escalation_kind, escalation_bps, escalation_cents, escalation_every, escalation_anniversary, escalation_round_cents,
+			max_occurrences, stop_when_paid_off
+		FROM schedule
+		WHERE is_active = 1
+
+		UNION ALL
+
+		SELECT -ss.id, sc.ledger_id, ss.name, ss.kind, ss.amount_cents, ss.src_account_id, ss.dest_account_id, ss.dest_amount_cents,
+			ss.description, ss.category_id, ss.freq, ss.interval, ss.start_date, ss.end_date, ss.bymonthday, ss.byweekday,
+			NULL, ss.business_day_roll, ss.holiday_calendar,
+			'none', NULL, NULL, NULL, NULL, NULL,
+			NULL, 0
+		FROM scenario_schedule ss
+		JOIN scenario sc ON sc.id = ss.scenario_id
+		WHERE ss.scenario_id = (SELECT id FROM scenario_param)
+	) s
+	WHERE s.ledger_id = (SELECT ledger_id FROM occurrence_param)
+),
+-- one row per run of the same recurrence: the schedule, then each version
+-- that changes freq, interval, bymonthday or byweekday
+schedule_segment AS (
+	SELECT g.*, LEAD(g.start_date) OVER (PARTITION BY g.id ORDER BY g.start_date) AS next_start
+	FROM (
+		SELECT p.*,
+			LAG(p.freq) OVER w AS prev_freq,
+			LAG(p.interval) OVER w AS prev_interval,
+			LAG(p.bymonthday) OVER w AS prev_bymonthday,
+			LAG(p.byweekday) OVER w AS prev_byweekday
+		FROM (
+			SELECT id, start_date, freq, interval, bymonthday, byweekday, 0 AS is_version
+			FROM schedule_source
+
+			UNION ALL
+
+			SELECT v.schedule_id, v.effective_date, v.freq, v.interval, v.bymonthday, v.byweekday, 1
+			FROM schedule_version v
+			JOIN schedule_source src ON src.id = v.schedule_id
+			WHERE v.effective_date > src.start_date AND src.rrule IS NULL
+		) p
+		WINDOW w AS (PARTITION BY p.id ORDER BY p.start_date)
+	) g
+	WHERE g.is_version = 0
+		OR g.freq IS NOT g.prev_freq
+		OR g.interval IS NOT g.prev_interval
+		OR g.bymonthday IS NOT g.prev_bymonthday
+		OR g.byweekday IS NOT g.prev_byweekday
+),
 schedule_anchor AS (
 	SELECT
 		s.*,
 		CASE
-			WHEN s.freq != 'W' OR s.byweekday IS NULL THEN s.start_date
-			ELSE date(
+			WHEN s.freq = 'W' AND s.byweekday IS NOT NULL THEN date(
 				s.start_date,
 				printf(
 					'+%d days',
 					( (s.byweekday - CAST(strftime('%w', s.start_date) AS INTEGER) + 7) % 7 )
 				)
 			)
+			-- a version's monthly recurrence starts on its first bymonthday
+			WHEN s.freq = 'M' AND s.bymonthday IS NOT NULL AND s.is_version = 1 THEN ` + versionMonthAnchorExpr() + `
+			ELSE s.start_date
 		END AS anchor_date,
 		COALESCE(s.bymonthday, CAST(strftime('%d', s.start_date) AS INTEGER)) AS dom
-	FROM schedule s
-	WHERE s.is_active = 1
+	FROM (
+		SELECT src.id, src.ledger_id, src.name, src.kind, src.amount_cents, src.src_account_id, src.dest_account_id,
+			src.dest_amount_cents, src.description, src.category_id, g.freq, g.interval, g.start_date,
+			CASE
+				WHEN g.next_start IS NOT NULL AND (src.end_date IS NULL OR src.end_date >= g.next_start)
+				THEN date(g.next_start, '-1 day')
+				ELSE src.end_date
+			END AS end_date,
+			g.bymonthday, g.byweekday, src.rrule, src.business_day_roll, src.holiday_calendar,
+			src.escalation_kind, src.escalation_bps, src.escalation_cents, src.escalation_every,
+			src.escalation_anniversary, src.escalation_round_cents, src.max_occurrences, src.stop_when_paid_off, g.is_version
+		FROM schedule_source src
+		JOIN schedule_segment g ON g.id = src.id
+	) s
 ),
-recur AS (
+recur_freq AS (
 	SELECT
 		id AS schedule_id,
 		name,
@@ -27,14 +108,18 @@ recur AS (
 		amount_cents,
 		src_account_id,
 		dest_account_id,
+		dest_amount_cents,
 		description,
+		category_id,
 		freq,
 		interval,
 		start_date,
 		end_date,
 		anchor_date AS occ_date,
-		dom
+		dom,
+		0 AS occ_n
 	FROM schedule_anchor
+	WHERE rrule IS NULL
 
 	UNION ALL
 
@@ -45,7 +130,9 @@ recur AS (
 		r.amount_cents,
 		r.src_account_id,
 		r.dest_account_id,
+		r.dest_amount_cents,
 		r.description,
+		r.category_id,
 		r.freq,
 		r.interval,
 		r.start_date,
@@ -131,94 +218,557 @@ recur AS (
 					)
 				)
 		END AS occ_date,
-		r.dom
-	FROM recur r
-	WHERE r.occ_date < ?
+		r.dom,
+		r.occ_n + 1
+	FROM recur_freq r
+	-- a little past the range end, for occurrences rolled back into it
+	WHERE (
+			r.occ_date < date((SELECT end_date FROM occurrence_param), '+14 days')
+			-- keep going far enough to reach occurrences moved into the range
+			OR r.occ_date < (
+				SELECT MAX(x.occ_date) FROM schedule_exception x
+				WHERE x.schedule_id = r.schedule_id AND x.kind = 'move'
+			)
+		)
+		AND (r.end_date IS NULL OR r.occ_date <= r.end_date)
+),
+-- revisions and versions that set the amount; on the same date the revision wins
+amount_change AS (
+	SELECT 'r' || id AS change_key, schedule_id, effective_date, amount_cents, dest_amount_cents, 1 AS priority
+	FROM schedule_revision
+
+	UNION ALL
+
+	SELECT 'v' || id, schedule_id, effective_date, amount_cents, dest_amount_cents, 0
+	FROM schedule_version
+	WHERE amount_cents IS NOT NULL
+),
+-- the occurrences of escalating schedules in order, across recurrence changes
+escalation_occ AS (
+	SELECT
+		f.schedule_id,
+		f.occ_date,
+		f.amount_cents,
+		f.dest_amount_cents,
+		ROW_NUMBER() OVER (PARTITION BY f.schedule_id ORDER BY f.occ_date) AS seq
+	FROM recur_freq f
+	JOIN schedule_anchor a ON a.id = f.schedule_id AND a.start_date = f.start_date
+	WHERE a.escalation_kind != 'none'
+		AND (f.end_date IS NULL OR f.occ_date <= f.end_date)
+),
+escalation AS (
+	SELECT
+		f.schedule_id,
+		f.seq,
+		f.occ_date,
+		0 AS since_n,
+		COALESCE(
+			(
+				SELECT sr.amount_cents FROM amount_change sr
+				WHERE sr.schedule_id = f.schedule_id AND sr.effective_date <= f.occ_date
+				ORDER BY sr.effective_date DESC, sr.priority DESC
+				LIMIT 1
+			),
+			f.amount_cents
+		) AS amount_cents,
+		COALESCE(
+			(
+				SELECT COALESCE(sr.dest_amount_cents, sr.amount_cents) FROM amount_change sr
+				WHERE sr.schedule_id = f.schedule_id AND sr.effective_date <= f.occ_date
+				ORDER BY sr.effective_date DESC, sr.priority DESC
+				LIMIT 1
+			),
+			f.dest_amount_cents
+		) AS dest_amount_cents
+	FROM escalation_occ f
+	WHERE f.seq = 1
+
+	UNION ALL
+
+	SELECT
+		f.schedule_id,
+		f.seq,
+		f.occ_date,
+		CASE WHEN sr.change_key IS NULL THEN e.since_n + 1 ELSE 0 END,
+		` + escalatedAmountExpr() + `,
+		` + escalatedDestAmountExpr() + `
+	FROM escalation e
+	JOIN escalation_occ f ON f.schedule_id = e.schedule_id AND f.seq = e.seq + 1
+	JOIN schedule_anchor a ON a.id = e.schedule_id AND a.is_version = 0
+	-- a revision or version taking effect since the previous occurrence
+	-- rebases the amount
+	LEFT JOIN amount_change sr ON sr.change_key = (
+		SELECT c.change_key FROM amount_change c
+		WHERE c.schedule_id = e.schedule_id
+			AND c.effective_date > e.occ_date
+			AND c.effective_date <= f.occ_date
+		ORDER BY c.effective_date DESC, c.priority DESC
+		LIMIT 1
+	)
+),
+day_offset(n) AS (
+	SELECT 0
+	UNION ALL
+	SELECT n + 1 FROM day_offset WHERE n < 14
+),
+recur AS (
+	SELECT u.*, ` + businessDayRollExpr() + ` AS due_date
+	FROM (
+		SELECT f.schedule_id, f.name, f.kind, f.amount_cents,
+			CASE WHEN v.id IS NULL THEN f.src_account_id ELSE v.src_account_id END AS src_account_id,
+			CASE WHEN v.id IS NULL THEN f.dest_account_id ELSE v.dest_account_id END AS dest_account_id,
+			f.dest_amount_cents, f.description, f.category_id, f.end_date, f.occ_date,
+			a.ledger_id, a.business_day_roll, a.holiday_calendar, a.max_occurrences, a.stop_when_paid_off,
+			e.amount_cents AS escalated_amount_cents, e.dest_amount_cents AS escalated_dest_amount_cents
+		FROM recur_freq f
+		JOIN schedule_anchor a ON a.id = f.schedule_id AND a.start_date = f.start_date
+		LEFT JOIN escalation e ON e.schedule_id = f.schedule_id AND e.occ_date = f.occ_date
+		-- the accounts of the version in force
+		LEFT JOIN schedule_version v ON v.id = (
+			SELECT v2.id FROM schedule_version v2
+			WHERE v2.schedule_id = f.schedule_id AND v2.effective_date <= f.occ_date
+			ORDER BY v2.effective_date DESC
+			LIMIT 1
+		)
+
+		UNION ALL
+
+		SELECT a.id, a.name, a.kind, a.amount_cents, a.src_account_id, a.dest_account_id,
+			a.dest_amount_cents, a.description, a.category_id, a.end_date, d.occ_date,
+			a.ledger_id, a.business_day_roll, a.holiday_calendar, a.max_occurrences, a.stop_when_paid_off,
+			NULL, NULL
+		FROM schedule_anchor a
+		JOIN schedule_rrule_date d ON d.schedule_id = a.id
+		WHERE a.rrule IS NOT NULL
+	) u
+),
+occurrence_all AS (
+	SELECT
+		recur.schedule_id,
+		COALESCE(x.move_to_date, recur.due_date) AS occ_date,
+		recur.occ_date AS original_date,
+		recur.kind,
+		recur.name,
+		COALESCE(` + scenarioAmountExpr("so.amount_cents") + `, x.amount_cents, ` + occurrenceAmountExpr() + `) AS amount_cents,
+		recur.src_account_id,
+		recur.dest_account_id,
+		COALESCE(` + scenarioAmountExpr("COALESCE(so.dest_amount_cents, so.amount_cents)") + `, x.dest_amount_cents, x.amount_cents, ` + occurrenceDestAmountExpr() + `) AS dest_amount_cents,
+		recur.description,
+		recur.category_id,
+		x.id AS exception_id,
+		x.kind AS exception_kind,
+		recur.max_occurrences,
+		recur.stop_when_paid_off,
+		ROW_NUMBER() OVER (PARTITION BY recur.schedule_id ORDER BY recur.occ_date) AS occ_number,
+		` + occurrenceNotPostedCond() + ` AS unposted
+	FROM recur
+	LEFT JOIN schedule_exception x
+		ON x.schedule_id = recur.schedule_id
+		AND x.occ_date = recur.occ_date
+		AND x.kind != 'suspend'
+	WHERE (recur.end_date IS NULL OR recur.occ_date <= recur.end_date)
+		AND (x.kind IS NULL OR x.kind != 'skip')
+		AND NOT EXISTS (
+			SELECT 1 FROM schedule_exception sx
+			WHERE sx.schedule_id = recur.schedule_id
+				AND sx.kind = 'suspend'
+				AND recur.occ_date BETWEEN sx.occ_date AND sx.end_date
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM scenario_override so
+			WHERE so.scenario_id = (SELECT id FROM scenario_param)
+				AND so.schedule_id = recur.schedule_id
+				AND so.kind = 'disable'
+				AND (so.effective_date IS NULL OR so.effective_date <= recur.occ_date)
+		)
+),
+-- posted occurrences count towards max_occurrences
+occurrence_pending AS (
+	SELECT *
+	FROM occurrence_all
+	WHERE unposted AND (max_occurrences IS NULL OR occ_number <= max_occurrences)
+		AND occ_date BETWEEN (SELECT start_date FROM occurrence_param) AND (SELECT end_date FROM occurrence_param)
+),
+` + payoffCTEDefs() + `,
+occurrence AS (
+	SELECT
+		schedule_id,
+		occ_date,
+		original_date,
+		kind,
+		name,
+		CASE
+			WHEN cap_cents IS NULL THEN amount_cents
+			ELSE CAST(round(amount_cents * 1.0 * cap_cents / dest_amount_cents) AS INTEGER)
+		END AS amount_cents,
+		src_account_id,
+		dest_account_id,
+		COALESCE(cap_cents, dest_amount_cents) AS dest_amount_cents,
+		description,
+		category_id,
+		exception_id,
+		exception_kind
+	FROM payoff_cap
+	WHERE side = stop_when_paid_off
+		AND (cap_cents IS NULL OR cap_cents > 0)
 )
 `
 }
 
-func occurrenceQuery() string {
-	return "\nWITH RECURSIVE\n" + occurrenceCTEDefs() + `
-SELECT
-	schedule_id,
-	occ_date,
-	kind,
-	name,
-	COALESCE(
+// payoffCTEDefs caps the payments of schedules that stop once the liability
+// they pay is paid off. Every pending occurrence in the range is split into
+// its source and destination side and walked in date order per account,
+// entries counted up front, as a projection from the range start would; the
+// payment taking the balance to zero or above is cut to what was owed and
+// later payments are capped at zero. Within a day payments come last.
+// Loan payments only pay down their principal: the interest part of each is
+// given as a JSON list of [schedule id, original date, interest cents] (see
+// payoffInterest). A NULL list leaves the payments uncapped.
+// occurrence_pending is read once here: each reference to it is planned anew.
+func payoffCTEDefs() string {
+	return `payoff_interest AS (
+	SELECT json_extract(value, '$[0]') AS schedule_id,
+		json_extract(value, '$[1]') AS original_date,
+		json_extract(value, '$[2]') AS interest_cents
+	FROM json_each((SELECT payoff_interest FROM occurrence_param))
+),
+payoff_delta AS (
+	SELECT o.*, k.side,
+		CASE k.side WHEN 0 THEN o.src_account_id ELSE o.dest_account_id END AS account_id,
+		CASE k.side WHEN 0 THEN -o.amount_cents ELSE o.dest_amount_cents - COALESCE(i.interest_cents, 0) END AS delta_cents,
+		k.side = 1 AND o.stop_when_paid_off = 1 AS is_payoff
+	FROM occurrence_pending o
+	CROSS JOIN (SELECT 0 AS side UNION ALL SELECT 1) k
+	LEFT JOIN payoff_interest i
+		ON k.side = 1 AND i.schedule_id = o.schedule_id AND i.original_date = o.original_date
+),
+payoff_balance AS (
+	SELECT p.*,
+		CASE WHEN p.is_payoff THEN
+			(SELECT opening_balance_cents FROM account WHERE id = p.account_id)
+			+ (
+				SELECT COALESCE(SUM(d.delta_cents), 0)
+				FROM v_entry_delta d
+				JOIN account a ON a.id = d.account_id
+				WHERE d.account_id = p.account_id
+					AND d.entry_date BETWEEN a.opening_date AND p.occ_date
+			)
+			+ SUM(p.delta_cents) OVER (
+				PARTITION BY p.account_id
+				ORDER BY p.occ_date, p.is_payoff, p.schedule_id, p.original_date, p.side
+				ROWS UNBOUNDED PRECEDING
+			)
+		END AS balance_after
+	FROM payoff_delta p
+),
+payoff_cap AS (
+	SELECT b.*,
+		CASE
+			WHEN NOT b.is_payoff OR (SELECT payoff_interest FROM occurrence_param) IS NULL THEN NULL
+			WHEN COALESCE(MAX(b.balance_after >= 0) OVER (
+				PARTITION BY b.account_id, b.schedule_id, b.is_payoff
+				ORDER BY b.occ_date, b.original_date
+				ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+			), 0) THEN 0
+			WHEN b.balance_after >= 0 THEN max(0, b.dest_amount_cents - b.balance_after)
+		END AS cap_cents
+	FROM payoff_balance b
+)`
+}
+
+// scenarioAmountExpr selects col from the scenario's amount override in
+// effect on the date of a recur row; dated overrides win over undated ones.
+// It is NULL outside a scenario.
+func scenarioAmountExpr(col string) string {
+	return `(
+		SELECT ` + col + `
+		FROM scenario_override so
+		WHERE so.scenario_id = (SELECT id FROM scenario_param)
+			AND so.schedule_id = recur.schedule_id
+			AND so.kind = 'amount'
+			AND (so.effective_date IS NULL OR so.effective_date <= recur.occ_date)
+		ORDER BY so.effective_date DESC
+		LIMIT 1
+	)`
+}
+
+// businessDayRollExpr applies a schedule's business_day_roll to the nominal
+// date of a recur row (u.occ_date). Dates with no business day within two
+// weeks are left alone.
+func businessDayRollExpr() string {
+	next := businessDayExpr("+")
+	prev := businessDayExpr("-")
+	return `CASE u.business_day_roll
+		WHEN 'following' THEN COALESCE(` + next + `, u.occ_date)
+		WHEN 'preceding' THEN COALESCE(` + prev + `, u.occ_date)
+		WHEN 'modified_following' THEN COALESCE(
+			CASE WHEN strftime('%Y-%m', ` + next + `) = strftime('%Y-%m', u.occ_date) THEN ` + next + ` ELSE ` + prev + ` END,
+			u.occ_date
+		)
+		ELSE u.occ_date
+	END`
+}
+
+// businessDayExpr finds the nearest weekday that is not a holiday, searching
+// forward ("+") or backward ("-") from u.occ_date.
+func businessDayExpr(dir string) string {
+	day := `date(u.occ_date, printf('` + dir + `%d days', o.n))`
+	return `(
+		SELECT ` + day + `
+		FROM day_offset o
+		WHERE strftime('%w', ` + day + `) NOT IN ('0', '6')
+			AND NOT EXISTS (
+				SELECT 1 FROM holiday h
+				WHERE h.ledger_id = u.ledger_id
+					AND h.holiday_date = ` + day + `
+					AND (u.holiday_calendar IS NULL OR h.calendar = u.holiday_calendar)
+			)
+		ORDER BY o.n
+		LIMIT 1
+	)`
+}
+
+// categoryPathCTEDefs expands the category tree into "Parent > Child" paths.
+func categoryPathCTEDefs() string {
+	return `
+category_path AS (
+	SELECT id, name AS path, 0 AS depth
+	FROM category
+	WHERE parent_id IS NULL
+
+	UNION ALL
+
+	SELECT c.id, cp.path || ' > ' || c.name, cp.depth + 1
+	FROM category c
+	JOIN category_path cp ON cp.id = c.parent_id
+)
+`
+}
+
+// versionMonthAnchorExpr is the first date on or after a version's
+// effective date (s.start_date) that falls on its bymonthday, clamped to the
+// end of shorter months.
+func versionMonthAnchorExpr() string {
+	dayIn := func(month string) string {
+		return `min(s.bymonthday, CAST(strftime('%d', date(` + month + `, '+1 month', '-1 day')) AS INTEGER))`
+	}
+	this := `date(s.start_date, 'start of month')`
+	next := `date(s.start_date, 'start of month', '+1 month')`
+	return `CASE
+				WHEN ` + dayIn(this) + ` >= CAST(strftime('%d', s.start_date) AS INTEGER)
+				THEN date(` + this + `, printf('+%d days', ` + dayIn(this) + ` - 1))
+				ELSE date(` + next + `, printf('+%d days', ` + dayIn(next) + ` - 1))
+			END`
+}
+
+// escalationStepExpr is true when an escalation step falls between the
+// previous occurrence (e) and the next (f): every escalation_every
+// occurrences since the amount was last set, or on an anniversary after the
+// previous occurrence or the revision or version (sr) that rebased it.
+func escalationStepExpr() string {
+	anniversaries := func(date string) string {
+		return `(CAST(strftime('%Y', ` + date + `) AS INTEGER) - (strftime('%m-%d', ` + date + `) < a.escalation_anniversary))`
+	}
+	return `CASE
+		WHEN a.escalation_every IS NOT NULL THEN sr.change_key IS NULL AND (e.since_n + 1) % a.escalation_every = 0
+		ELSE ` + anniversaries("f.occ_date") + ` > ` + anniversaries("COALESCE(sr.effective_date, e.occ_date)") + `
+	END`
+}
+
+// escalationRoundExpr rounds cents to the schedule's escalation_round_cents.
+func escalationRoundExpr(cents string) string {
+	return `CAST(round((` + cents + `) * 1.0 / COALESCE(a.escalation_round_cents, 1)) AS INTEGER) * COALESCE(a.escalation_round_cents, 1)`
+}
+
+// escalatedAmountExpr steps the amount of an escalation row: the amount
+// change that rebased it (sr) or the previous amount, escalated when a step falls
+// due. Amounts do not go below zero.
+func escalatedAmountExpr() string {
+	base := `COALESCE(sr.amount_cents, e.amount_cents)`
+	return `CASE WHEN ` + escalationStepExpr() + ` THEN max(0, CASE a.escalation_kind
+			WHEN 'percent' THEN ` + escalationRoundExpr(base+` * (10000 + a.escalation_bps) / 10000.0`) + `
+			ELSE ` + escalationRoundExpr(base+` + a.escalation_cents`) + `
+		END) ELSE ` + base + ` END`
+}
+
+// escalatedDestAmountExpr steps what the destination receives alongside
+// escalatedAmountExpr. A fixed step is in the source currency, so the
+// destination moves by the same proportion.
+func escalatedDestAmountExpr() string {
+	base := `CASE WHEN sr.change_key IS NULL THEN e.dest_amount_cents ELSE COALESCE(sr.dest_amount_cents, sr.amount_cents) END`
+	amount := `COALESCE(sr.amount_cents, e.amount_cents)`
+	return `CASE WHEN ` + escalationStepExpr() + ` THEN max(0, CASE a.escalation_kind
+			WHEN 'percent' THEN ` + escalationRoundExpr(base+` * (10000 + a.escalation_bps) / 10000.0`) + `
+			ELSE ` + escalationRoundExpr(base+` * (`+escalatedAmountExpr()+`) * 1.0 / nullif(`+amount+`, 0)`) + `
+		END) ELSE ` + base + ` END`
+}
+
+// occurrenceAmountExpr resolves the amount of a recur row: the escalated
+// amount for a schedule that escalates, else the revision or version amount
+// in effect on the occurrence date, else the schedule's amount.
+func occurrenceAmountExpr() string {
+	return `COALESCE(
+		recur.escalated_amount_cents,
 		(
 			SELECT sr.amount_cents
-			FROM schedule_revision sr
+			FROM amount_change sr
 			WHERE sr.schedule_id = recur.schedule_id
 				AND sr.effective_date <= recur.occ_date
-			ORDER BY sr.effective_date DESC
+			ORDER BY sr.effective_date DESC, sr.priority DESC
 			LIMIT 1
 		),
-		amount_cents
-	) AS amount_cents,
-	src_account_id,
-	dest_account_id,
-	description
-FROM recur
-WHERE occ_date BETWEEN ? AND ?
-	AND (end_date IS NULL OR occ_date <= end_date)
-ORDER BY occ_date, name
+		recur.amount_cents
+	)`
+}
+
+// occurrenceDestAmountExpr resolves what the destination of a recur row
+// receives: the escalated amounts for a schedule that escalates, else the
+// revision or version amount in effect (its dest amount, else its amount),
+// then the schedule's dest amount, then the schedule's amount.
+func occurrenceDestAmountExpr() string {
+	return `COALESCE(
+		recur.escalated_dest_amount_cents,
+		recur.escalated_amount_cents,
+		(
+			SELECT COALESCE(sr.dest_amount_cents, sr.amount_cents)
+			FROM amount_change sr
+			WHERE sr.schedule_id = recur.schedule_id
+				AND sr.effective_date <= recur.occ_date
+			ORDER BY sr.effective_date DESC, sr.priority DESC
+			LIMIT 1
+		),
+		recur.dest_amount_cents,
+		recur.amount_cents
+	)`
+}
+
+// occurrenceNotPostedCond filters recur rows down to occurrences that have
+// not been posted. Entries posted from an occurrence record its original date
+// in occurrence_date; other entries linked to the schedule match on the date
+// the occurrence falls on.
+func occurrenceNotPostedCond() string {
+	return `NOT EXISTS (
+		SELECT 1 FROM entry e
+		WHERE e.schedule_id = recur.schedule_id
+			AND (
+				e.occurrence_date = recur.occ_date
+				OR (e.occurrence_date IS NULL AND e.entry_date = COALESCE(x.move_to_date, recur.due_date))
+			)
+	)`
+}
+
+// occurrenceQuery lists occurrences falling in a date range. Parameters are
+// those of occurrenceCTEDefs; see occurrenceArgs. Occurrences changed by a
+// schedule exception have modified = 1.
+func occurrenceQuery() string {
+	return occurrenceQueryFor(false)
+}
+
+// occurrenceParams lists the parameters of occurrenceCTEDefs for a scenario
+// (0 for none). A nil interest leaves payments that stop once paid off
+// uncapped.
+func occurrenceParams(scenarioID, ledgerID int64, from, to string, interest any) []any {
+	args := []any{ledgerID, from, to, interest}
+	if scenarioID == 0 {
+		return args
+	}
+	return append([]any{scenarioID}, args...)
+}
+
+func occurrenceQueryFor(scenario bool) string {
+	return "\nWITH RECURSIVE\n" + occurrenceCTEDefs(scenario) + "," + categoryPathCTEDefs() + `
+SELECT
+	o.schedule_id,
+	o.occ_date,
+	o.kind,
+	o.name,
+	o.amount_cents,
+	o.src_account_id,
+	o.dest_account_id,
+	o.description,
+	o.category_id,
+	cp.path AS category_path,
+	o.dest_amount_cents,
+	o.original_date,
+	o.exception_id IS NOT NULL AS modified,
+	o.exception_id,
+	o.exception_kind
+FROM occurrence o
+LEFT JOIN category_path cp ON cp.id = o.category_id
+ORDER BY o.occ_date, o.name
 `
 }
 
+// projectedBalanceQuery projects every account's balance to a date from its
+// entries plus the occurrences in a range. Parameters: those of
+// occurrenceCTEDefs for the range up to the as-of date, then the as-of date
+// and ledger id.
 func projectedBalanceQuery() string {
+	return projectedBalanceQueryFor(false)
+}
+
+// scenarioProjectedBalanceQuery is projectedBalanceQuery with a scenario's
+// overlay, including its hypothetical entries. The scenario id is the first
+// parameter.
+func scenarioProjectedBalanceQuery() string {
+	return projectedBalanceQueryFor(true)
+}
+
+// projectedBalanceQueryArgs returns the projected balance query for a
+// scenario (0 for none) with its parameters.
+func projectedBalanceQueryArgs(q querier, scenarioID, ledgerID int64, start, asOf string) (string, []any, error) {
+	args, err := occurrenceArgs(q, ledgerID, scenarioID, start, asOf)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, asOf, ledgerID)
+	if scenarioID == 0 {
+		return projectedBalanceQuery(), args, nil
+	}
+	return scenarioProjectedBalanceQuery(), args, nil
+}
+
+func projectedBalanceQueryFor(scenario bool) string {
 	return `
 WITH RECURSIVE
-` + occurrenceCTEDefs() + `,
+` + occurrenceCTEDefs(scenario) + `,
 occ AS (
-	SELECT
-		schedule_id,
-		occ_date,
-		kind,
-		name,
-		COALESCE(
-			(
-				SELECT sr.amount_cents
-				FROM schedule_revision sr
-				WHERE sr.schedule_id = recur.schedule_id
-					AND sr.effective_date <= recur.occ_date
-				ORDER BY sr.effective_date DESC
-				LIMIT 1
-			),
-			amount_cents
-		) AS amount_cents,
-		src_account_id,
-		dest_account_id
-	FROM recur
-	WHERE occ_date BETWEEN ? AND ?
-		AND (end_date IS NULL OR occ_date <= end_date)
+	SELECT schedule_id, occ_date, kind, name, amount_cents, src_account_id, dest_account_id, dest_amount_cents
+	FROM occurrence
 ),
 projected_deltas AS (
 	SELECT src_account_id AS account_id, -amount_cents AS delta_cents
 	FROM occ
 	WHERE src_account_id IS NOT NULL
-		AND NOT EXISTS (
-			SELECT 1 FROM entry e
-			WHERE e.schedule_id = occ.schedule_id
-			AND e.entry_date = occ.occ_date
-		)
 
 	UNION ALL
 
-	SELECT dest_account_id AS account_id, amount_cents AS delta_cents
+	SELECT dest_account_id AS account_id, dest_amount_cents AS delta_cents
 	FROM occ
 	WHERE dest_account_id IS NOT NULL
-		AND NOT EXISTS (
-			SELECT 1 FROM entry e
-			WHERE e.schedule_id = occ.schedule_id
-			AND e.entry_date = occ.occ_date
-		)
+),
+scenario_entry_delta AS (
+	SELECT src_account_id AS account_id, entry_date, -amount_cents AS delta_cents
+	FROM scenario_entry
+	WHERE scenario_id = (SELECT id FROM scenario_param)
+		AND src_account_id IS NOT NULL
+
+	UNION ALL
+
+	SELECT dest_account_id AS account_id, entry_date, COALESCE(dest_amount_cents, amount_cents) AS delta_cents
+	FROM scenario_entry
+	WHERE scenario_id = (SELECT id FROM scenario_param)
+		AND dest_account_id IS NOT NULL
 ),
 all_deltas AS (
 	SELECT account_id, SUM(delta_cents) AS delta_cents
 	FROM (
 		SELECT d.account_id, d.delta_cents
-		FROM v_entry_delta d
+		FROM (
+			SELECT account_id, entry_date, delta_cents FROM v_entry_delta
+			UNION ALL
+			SELECT account_id, entry_date, delta_cents FROM scenario_entry_delta
+		) d
 		JOIN account a ON a.id = d.account_id
 		WHERE d.entry_date <= ?
 			AND d.entry_date >= a.opening_date
@@ -240,10 +790,12 @@ SELECT
 	a.is_interest_bearing,
 	a.interest_apr_bps,
 	a.interest_compound,
-	a.exclude_from_dashboard
+	a.exclude_from_dashboard,
+	a.currency
 FROM account a
 LEFT JOIN all_deltas d ON d.account_id = a.id
 WHERE a.archived_at IS NULL
+	AND a.ledger_id = ?
 ORDER BY a.name
 `
 }
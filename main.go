@@ -64,6 +64,21 @@ func main() {
 		}
 	}()
 
+	// Daily posting of accrued interest, then a net worth snapshot that
+	// includes it. Both are safe to repeat on the same day.
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if err := budgie.PostAccruedInterest(db); err != nil {
+				fmt.Fprintf(os.Stderr, "interest posting failed: %v\n", err)
+			}
+			if err := budgie.SnapshotBalances(db); err != nil {
+				fmt.Fprintf(os.Stderr, "balance snapshot failed: %v\n", err)
+			}
+		}
+	}()
+
 	fmt.Printf("budgie listening on http://%s (db=%s)\n", addr, budgie.DBPath())
 	if err := http.ListenAndServe(addr, handler); err != nil {
 		fmt.Fprintf(os.Stderr, "server error: %v\n", err)
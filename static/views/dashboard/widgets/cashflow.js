@@ -306,12 +306,12 @@ export const cashflow = {
             const destId = o?.dest_account_id;
             if (accountId && Number(destId) !== accountId) continue;
             if (!cfg.showHidden && isHidden(destId)) continue;
-            addToMap(inflows, normalizeName(o?.name), amount);
+            addToMap(inflows, normalizeName(o?.category_path || o?.name), amount);
           } else {
             const srcId = o?.src_account_id;
             if (accountId && Number(srcId) !== accountId) continue;
             if (!cfg.showHidden && isHidden(srcId)) continue;
-            addToMap(outflows, normalizeName(o?.name), amount);
+            addToMap(outflows, normalizeName(o?.category_path || o?.name), amount);
           }
         }
       } else {
@@ -329,11 +329,11 @@ export const cashflow = {
           if (hasDest && !hasSrc) {
             if (accountId && Number(destId) !== accountId) continue;
             if (!cfg.showHidden && isHidden(destId)) continue;
-            addToMap(inflows, normalizeName(e?.name), amount);
+            addToMap(inflows, normalizeName(e?.category_path || e?.name), amount);
           } else if (hasSrc && !hasDest) {
             if (accountId && Number(srcId) !== accountId) continue;
             if (!cfg.showHidden && isHidden(srcId)) continue;
-            addToMap(outflows, normalizeName(e?.name), amount);
+            addToMap(outflows, normalizeName(e?.category_path || e?.name), amount);
           }
         }
       }
@@ -227,10 +227,32 @@ export async function viewSchedules() {
           </div>
           <div class="actions" style="margin-top: 10px;">
             <button class="primary" id="sm_save">${isEdit ? 'Save' : 'Create'}</button>
+            <button id="sm_preview" type="button">Preview dates</button>
           </div>
+          <div id="sm_preview_out" style="margin-top: 10px;"></div>
         `;
     };
 
+    // Schedule fields the editor does not show.
+    const keptFields = [
+        'rrule',
+        'category_id',
+        'payee_id',
+        'dest_amount_cents',
+        'business_day_roll',
+        'holiday_calendar',
+        'variance_model',
+        'variance_bps',
+        'escalation_kind',
+        'escalation_bps',
+        'escalation_cents',
+        'escalation_every',
+        'escalation_anniversary',
+        'escalation_round_cents',
+        'max_occurrences',
+        'stop_when_paid_off',
+    ];
+
     const showScheduleModal = (s) => {
         const isEdit = Boolean(s);
         const { root, close } = showModal({
@@ -267,26 +289,30 @@ export async function viewSchedules() {
         kindSel?.addEventListener('change', applyKindRules);
         applyKindRules();
 
+        const readPayload = () => {
+            const amount_cents = parseCentsFromDollarsString(modal.querySelector('#sm_amount').value);
+            if (amount_cents === null) throw new Error('Amount is required');
+
+            return {
+                name: modal.querySelector('#sm_name').value,
+                kind: modal.querySelector('#sm_kind').value,
+                amount_cents,
+                start_date: modal.querySelector('#sm_start').value,
+                end_date: modal.querySelector('#sm_end').value || null,
+                freq: modal.querySelector('#sm_freq').value,
+                interval: Number(modal.querySelector('#sm_interval').value || '1'),
+                bymonthday: modal.querySelector('#sm_dom').value ? Number(modal.querySelector('#sm_dom').value) : null,
+                byweekday: modal.querySelector('#sm_dow').value ? Number(modal.querySelector('#sm_dow').value) : null,
+                src_account_id: srcSel.value ? Number(srcSel.value) : null,
+                dest_account_id: destSel.value ? Number(destSel.value) : null,
+                description: modal.querySelector('#sm_desc').value || null,
+                is_active: Number(modal.querySelector('#sm_active').value),
+            };
+        };
+
         modal.querySelector('#sm_save').onclick = async () => {
             try {
-                const amount_cents = parseCentsFromDollarsString(modal.querySelector('#sm_amount').value);
-                if (amount_cents === null) throw new Error('Amount is required');
-
-                const payload = {
-                    name: modal.querySelector('#sm_name').value,
-                    kind: modal.querySelector('#sm_kind').value,
-                    amount_cents,
-                    start_date: modal.querySelector('#sm_start').value,
-                    end_date: modal.querySelector('#sm_end').value || null,
-                    freq: modal.querySelector('#sm_freq').value,
-                    interval: Number(modal.querySelector('#sm_interval').value || '1'),
-                    bymonthday: modal.querySelector('#sm_dom').value ? Number(modal.querySelector('#sm_dom').value) : null,
-                    byweekday: modal.querySelector('#sm_dow').value ? Number(modal.querySelector('#sm_dow').value) : null,
-                    src_account_id: srcSel.value ? Number(srcSel.value) : null,
-                    dest_account_id: destSel.value ? Number(destSel.value) : null,
-                    description: modal.querySelector('#sm_desc').value || null,
-                    is_active: Number(modal.querySelector('#sm_active').value),
-                };
+                const payload = readPayload();
 
                 if (isEdit) await api(`/api/schedules/${s.id}`, { method: 'PUT', body: JSON.stringify(payload) });
                 else await api('/api/schedules', { method: 'POST', body: JSON.stringify(payload) });
@@ -297,6 +323,46 @@ export async function viewSchedules() {
                 alert(e.message);
             }
         };
+
+        // Dates come from the server so rolls, holidays and revisions match the projection.
+        modal.querySelector('#sm_preview').onclick = async () => {
+            const out = modal.querySelector('#sm_preview_out');
+            try {
+                // The editor leaves these out and a save keeps them, so the preview
+                // takes them from the saved schedule.
+                const kept = {};
+                if (isEdit) {
+                    keptFields.forEach((f) => {
+                        kept[f] = s[f] ?? null;
+                    });
+                }
+                const payload = { ...kept, ...readPayload(), count: 12 };
+                if (isEdit) {
+                    const revisions = await api('/api/revisions');
+                    payload.revisions = revisions.data
+                        .filter((r) => r.schedule_id === s.id)
+                        .map((r) => ({
+                            effective_date: r.effective_date,
+                            amount_cents: r.amount_cents,
+                            dest_amount_cents: r.dest_amount_cents,
+                        }));
+                }
+                const res = await api('/api/schedules/preview', { method: 'POST', body: JSON.stringify(payload) });
+                out.innerHTML = res.data.length
+                    ? table(
+                          ['date', 'amount'],
+                          res.data.map((o) => ({
+                              date: { text: o.occ_date, title: o.original_date },
+                              amount: fmtDollarsFromCents(o.amount_cents),
+                          }))
+                      )
+                    : '<div class="notice">No upcoming dates.</div>';
+            } catch (e) {
+                const fields = e.details?.fields || {};
+                const lines = Object.keys(fields).map((f) => `${escapeHtml(f)}: ${escapeHtml(fields[f])}`);
+                out.innerHTML = `<div class="notice">${lines.length ? lines.join('<br />') : escapeHtml(e.message)}</div>`;
+            }
+        };
     };
 
     $('#s_add').onclick = () => showScheduleModal(null);